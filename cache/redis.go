@@ -0,0 +1,116 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Redis adalah client Redis bersama (matcher, dedup update, cache)
+var Redis *redis.Client
+
+// Redis keys
+const (
+	KeyUpdateSeen   = "update:seen:%d" // Penanda update_id yang sudah diproses
+	KeyUpdateOffset = "update:offset"  // update_id terakhir yang selesai diproses (polling)
+)
+
+// InitRedis menginisialisasi koneksi Redis dari URL
+func InitRedis(redisURL string) error {
+	opt, err := redis.ParseURL(redisURL)
+	if err != nil {
+		return fmt.Errorf("failed to parse Redis URL: %w", err)
+	}
+
+	rdb := redis.NewClient(opt)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := rdb.Ping(ctx).Err(); err != nil {
+		rdb.Close()
+		return fmt.Errorf("failed to connect to Redis: %w", err)
+	}
+
+	Redis = rdb
+	log.Println("✅ Connected to Redis successfully")
+	return nil
+}
+
+// CloseRedis menutup koneksi Redis
+func CloseRedis() {
+	if Redis != nil {
+		if err := Redis.Close(); err != nil {
+			log.Printf("Error closing Redis connection: %v", err)
+			return
+		}
+		log.Println("Redis connection closed")
+	}
+}
+
+// UpdateProcessingTTL adalah masa berlaku klaim update yang sedang diproses. Jika proses
+// crash sebelum selesai, klaim kedaluwarsa sehingga update yang dikirim ulang tetap diproses.
+const UpdateProcessingTTL = 5 * time.Minute
+
+// ClaimUpdate menandai update_id sebagai diproses. Mengembalikan false jika update
+// sudah pernah diproses (duplikat). Jika Redis error, update tetap diproses (fail open).
+func ClaimUpdate(ctx context.Context, updateID int, ttl time.Duration) bool {
+	if Redis == nil {
+		return true
+	}
+
+	ok, err := Redis.SetNX(ctx, fmt.Sprintf(KeyUpdateSeen, updateID), 1, ttl).Result()
+	if err != nil {
+		log.Printf("Error claiming update %d: %v", updateID, err)
+		return true
+	}
+	return ok
+}
+
+// CompleteUpdate memperpanjang klaim update yang selesai diproses menjadi ttl
+func CompleteUpdate(ctx context.Context, updateID int, ttl time.Duration) {
+	if Redis == nil {
+		return
+	}
+	if err := Redis.Expire(ctx, fmt.Sprintf(KeyUpdateSeen, updateID), ttl).Err(); err != nil {
+		log.Printf("Error completing update %d: %v", updateID, err)
+	}
+}
+
+// ReleaseUpdate melepas klaim update yang gagal diproses supaya bisa diproses ulang
+func ReleaseUpdate(ctx context.Context, updateID int) {
+	if Redis == nil {
+		return
+	}
+	if err := Redis.Del(ctx, fmt.Sprintf(KeyUpdateSeen, updateID)).Err(); err != nil {
+		log.Printf("Error releasing update %d: %v", updateID, err)
+	}
+}
+
+// SaveUpdateOffset menyimpan update_id terakhir yang selesai diproses
+func SaveUpdateOffset(ctx context.Context, updateID int) error {
+	if Redis == nil {
+		return nil
+	}
+	return Redis.Set(ctx, KeyUpdateOffset, updateID, 0).Err()
+}
+
+// LoadUpdateOffset mengambil update_id terakhir yang tersimpan (0 jika belum ada)
+func LoadUpdateOffset(ctx context.Context) (int, error) {
+	if Redis == nil {
+		return 0, nil
+	}
+
+	value, err := Redis.Get(ctx, KeyUpdateOffset).Result()
+	if errors.Is(err, redis.Nil) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	return strconv.Atoi(value)
+}
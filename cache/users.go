@@ -0,0 +1,290 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"time"
+
+	"tg-anon-go/config"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Redis keys untuk state dan counter user
+const (
+	KeyUserState     = "user:%d:state"    // Hash status/partner_id/session_id/is_banned/is_inactive (read-through cache)
+	KeyUserStateVer  = "user:%d:statever" // Versi state, dinaikkan setiap invalidasi
+	KeyUserCounters  = "user:%d:counters" // Hash delta counter yang belum di-flush ke Postgres
+	KeyUserAds       = "user:%d:ads"      // Jumlah pesan sejak ads terakhir (nilai absolut)
+	KeyUserActive    = "user:%d:active"   // Penanda last_active baru saja ditulis (throttle)
+	KeyDirtyCounters = "counters:dirty"   // Set user yang punya counter belum di-flush
+)
+
+// Field counter di KeyUserCounters
+const (
+	CounterTotalMessages = "total_messages"
+)
+
+// UserState adalah state chat user yang di-cache
+type UserState struct {
+	Status     string
+	PartnerID  int64
+	SessionID  int64
+	IsBanned   bool
+	IsInactive bool
+}
+
+// CounterDelta adalah counter satu user yang diambil dari Redis untuk di-flush
+type CounterDelta struct {
+	UserID        int64
+	TotalMessages int64
+	AdsCounter    int64
+	HasAds        bool // AdsCounter hanya valid jika true
+}
+
+// Enabled mengecek apakah Redis tersedia untuk cache
+func Enabled() bool {
+	return Redis != nil
+}
+
+// GetUserState mengambil state chat user dari cache
+func GetUserState(ctx context.Context, userID int64) (UserState, bool) {
+	if Redis == nil {
+		return UserState{}, false
+	}
+
+	values, err := Redis.HGetAll(ctx, fmt.Sprintf(KeyUserState, userID)).Result()
+	if err != nil || len(values) == 0 {
+		return UserState{}, false
+	}
+	// Entry lama tanpa flag dianggap belum di-cache supaya status ban tidak terlewat
+	if _, ok := values["is_banned"]; !ok {
+		return UserState{}, false
+	}
+
+	state := UserState{Status: values["status"]}
+	state.PartnerID, _ = strconv.ParseInt(values["partner_id"], 10, 64)
+	state.SessionID, _ = strconv.ParseInt(values["session_id"], 10, 64)
+	state.IsBanned = values["is_banned"] == "1"
+	state.IsInactive = values["is_inactive"] == "1"
+	return state, true
+}
+
+// UserStateVersion mengambil versi state user, dipanggil sebelum membaca user_state dari
+// database. ok false jika Redis tidak tersedia atau error (hasil baca jangan di-cache).
+func UserStateVersion(ctx context.Context, userID int64) (version string, ok bool) {
+	if Redis == nil {
+		return "", false
+	}
+	version, err := Redis.Get(ctx, fmt.Sprintf(KeyUserStateVer, userID)).Result()
+	if err != nil && !errors.Is(err, redis.Nil) {
+		return "", false
+	}
+	return version, true
+}
+
+// setUserStateScript menyimpan state hanya jika versinya belum berubah sejak dibaca,
+// supaya hasil baca lama tidak menimpa invalidasi yang terjadi di tengah jalan
+var setUserStateScript = redis.NewScript(`
+local version = redis.call("GET", KEYS[2]) or ""
+if version ~= ARGV[1] then
+	return 0
+end
+redis.call("HSET", KEYS[1], "status", ARGV[2], "partner_id", ARGV[3], "session_id", ARGV[4],
+	"is_banned", ARGV[5], "is_inactive", ARGV[6])
+redis.call("EXPIRE", KEYS[1], ARGV[7])
+return 1
+`)
+
+// SetUserState menyimpan state chat user ke cache jika versinya masih sama dengan
+// version (hasil UserStateVersion sebelum state dibaca dari database)
+func SetUserState(ctx context.Context, userID int64, state UserState, version string) {
+	if Redis == nil {
+		return
+	}
+
+	keys := []string{fmt.Sprintf(KeyUserState, userID), fmt.Sprintf(KeyUserStateVer, userID)}
+	ttl := time.Duration(config.C.UserCacheTTL) * time.Minute
+	err := setUserStateScript.Run(ctx, Redis, keys, version, state.Status, state.PartnerID, state.SessionID,
+		flagValue(state.IsBanned), flagValue(state.IsInactive), int64(ttl.Seconds())).Err()
+	if err != nil {
+		log.Printf("Error caching state for user %d: %v", userID, err)
+	}
+}
+
+// flagValue mengubah flag boolean ke nilai field hash ("1" / "0")
+func flagValue(flag bool) string {
+	if flag {
+		return "1"
+	}
+	return "0"
+}
+
+// InvalidateUserState menghapus state chat user dari cache dan menaikkan versinya
+// (dipanggil setelah write ke database)
+func InvalidateUserState(ctx context.Context, userIDs ...int64) {
+	if Redis == nil || len(userIDs) == 0 {
+		return
+	}
+
+	// Versi disimpan lebih lama dari state supaya tidak kedaluwarsa di tengah pengisian cache
+	ttl := 2 * time.Duration(config.C.UserCacheTTL) * time.Minute
+	_, err := Redis.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		for _, userID := range userIDs {
+			verKey := fmt.Sprintf(KeyUserStateVer, userID)
+			pipe.Incr(ctx, verKey)
+			pipe.Expire(ctx, verKey, ttl)
+			pipe.Del(ctx, fmt.Sprintf(KeyUserState, userID))
+		}
+		return nil
+	})
+	if err != nil {
+		log.Printf("Error invalidating user state cache: %v", err)
+	}
+}
+
+// ClearUserStates menghapus semua state user di cache (misalnya setelah reset database)
+func ClearUserStates(ctx context.Context) error {
+	if Redis == nil {
+		return nil
+	}
+
+	iter := Redis.Scan(ctx, 0, "user:*:state", 500).Iterator()
+	for iter.Next(ctx) {
+		if err := Redis.Del(ctx, iter.Val()).Err(); err != nil {
+			return err
+		}
+	}
+	return iter.Err()
+}
+
+// IncrCounter menambah delta counter user dan menandainya untuk di-flush
+func IncrCounter(ctx context.Context, userID int64, field string) error {
+	_, err := Redis.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.HIncrBy(ctx, fmt.Sprintf(KeyUserCounters, userID), field, 1)
+		pipe.SAdd(ctx, KeyDirtyCounters, userID)
+		return nil
+	})
+	return err
+}
+
+// incrAdsScript menambah counter ads secara atomik. Jika key belum ada, counter diisi
+// dengan ARGV[1] + 1 (nilai dari Postgres); jika ARGV[1] kosong, mengembalikan -1.
+var incrAdsScript = redis.NewScript(`
+local by = 1
+if redis.call("EXISTS", KEYS[1]) == 0 then
+	if ARGV[1] == "" then
+		return -1
+	end
+	by = tonumber(ARGV[1]) + 1
+end
+redis.call("SADD", KEYS[2], ARGV[2])
+return redis.call("INCRBY", KEYS[1], by)
+`)
+
+// IncrAdsCounter menambah counter ads user dan mengembalikan nilai barunya.
+// ok false jika counter belum ada di Redis dan perlu diisi lewat SeedAdsCounter.
+func IncrAdsCounter(ctx context.Context, userID int64) (count int64, ok bool, err error) {
+	count, err = incrAdsScript.Run(ctx, Redis, []string{fmt.Sprintf(KeyUserAds, userID), KeyDirtyCounters}, "", userID).Int64()
+	if err != nil {
+		return 0, false, err
+	}
+	if count < 0 {
+		return 0, false, nil
+	}
+	return count, true, nil
+}
+
+// SeedAdsCounter menambah counter ads user, melanjutkan dari seed jika counter belum ada
+// di Redis. Jika counter sudah ada (diisi request lain), seed diabaikan.
+func SeedAdsCounter(ctx context.Context, userID int64, seed int64) (int64, error) {
+	return incrAdsScript.Run(ctx, Redis, []string{fmt.Sprintf(KeyUserAds, userID), KeyDirtyCounters}, seed, userID).Int64()
+}
+
+// ResetAdsCounter mereset counter ads user
+func ResetAdsCounter(ctx context.Context, userID int64) error {
+	_, err := Redis.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Set(ctx, fmt.Sprintf(KeyUserAds, userID), 0, 0)
+		pipe.SAdd(ctx, KeyDirtyCounters, userID)
+		return nil
+	})
+	return err
+}
+
+// TouchActive mengembalikan true jika last_active user perlu ditulis ke Postgres,
+// yaitu paling banyak sekali per interval. Jika Redis error, selalu true.
+func TouchActive(ctx context.Context, userID int64, every time.Duration) bool {
+	if Redis == nil {
+		return true
+	}
+	ok, err := Redis.SetNX(ctx, fmt.Sprintf(KeyUserActive, userID), 1, every).Result()
+	if err != nil {
+		return true
+	}
+	return ok
+}
+
+// PopDirtyCounters mengambil dan mereset counter hingga limit user yang belum di-flush.
+// Delta total pesan diambil dan dihapus secara atomik; counter ads dibaca apa adanya.
+func PopDirtyCounters(ctx context.Context, limit int64) ([]CounterDelta, error) {
+	if Redis == nil {
+		return nil, nil
+	}
+
+	members, err := Redis.SPopN(ctx, KeyDirtyCounters, limit).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	deltas := make([]CounterDelta, 0, len(members))
+	for i, member := range members {
+		userID, err := strconv.ParseInt(member, 10, 64)
+		if err != nil {
+			continue
+		}
+
+		counterKey := fmt.Sprintf(KeyUserCounters, userID)
+		var counters *redis.MapStringStringCmd
+		var ads *redis.StringCmd
+		_, err = Redis.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			counters = pipe.HGetAll(ctx, counterKey)
+			pipe.Del(ctx, counterKey)
+			ads = pipe.Get(ctx, fmt.Sprintf(KeyUserAds, userID))
+			return nil
+		})
+		if err != nil && !errors.Is(err, redis.Nil) {
+			// Kembalikan user ini dan semua sisa yang belum diproses ke antrian
+			// supaya dicoba lagi di flush berikutnya
+			pending := make([]interface{}, 0, len(members)-i)
+			for _, m := range members[i:] {
+				pending = append(pending, m)
+			}
+			Redis.SAdd(ctx, KeyDirtyCounters, pending...)
+			return deltas, err
+		}
+
+		delta := CounterDelta{UserID: userID}
+		delta.TotalMessages, _ = strconv.ParseInt(counters.Val()[CounterTotalMessages], 10, 64)
+		if value, err := ads.Int64(); err == nil {
+			delta.AdsCounter, delta.HasAds = value, true
+		}
+		deltas = append(deltas, delta)
+	}
+	return deltas, nil
+}
+
+// RestoreCounters mengembalikan delta yang gagal di-flush ke Redis
+func RestoreCounters(ctx context.Context, deltas []CounterDelta) {
+	if Redis == nil {
+		return
+	}
+	for _, delta := range deltas {
+		if delta.TotalMessages > 0 {
+			Redis.HIncrBy(ctx, fmt.Sprintf(KeyUserCounters, delta.UserID), CounterTotalMessages, delta.TotalMessages)
+		}
+		Redis.SAdd(ctx, KeyDirtyCounters, delta.UserID)
+	}
+}
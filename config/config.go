@@ -0,0 +1,415 @@
+package config
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"net/url"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"tg-anon-go/constants"
+
+	"github.com/joho/godotenv"
+)
+
+// Config adalah seluruh konfigurasi bot. Setiap field dibaca dari environment variable
+// sesuai tag `env`, bisa juga dari file (-config / CONFIG_FILE) atau flag command line.
+//
+// Tag:
+//   - env: nama environment variable (flag-nya: huruf kecil dengan "-", misalnya -bot-token)
+//   - default: nilai default; field required tidak punya default
+//   - required: wajib diisi, bot tidak start tanpanya
+//   - secret: disembunyikan saat ditampilkan (/env, log)
+type Config struct {
+	// Telegram
+	BotToken   string  `env:"BOT_TOKEN" required:"true" secret:"true" usage:"Token bot dari @BotFather"`
+	BotDebug   bool    `env:"BOT_DEBUG" default:"false" usage:"Debug mode Telegram API"`
+	OwnerIDs   []int64 `env:"OWNER_IDS" required:"true" usage:"User ID owner, pisahkan dengan koma"`
+	LogGroupID int64   `env:"LOG_GROUP_ID" default:"0" usage:"Group ID untuk log media dan laporan (0 = nonaktif)"`
+
+	// Bot Info URLs
+	BotOwnerURL   string `env:"BOT_OWNER_URL" default:"https://t.me/ursweetbae" usage:"URL Telegram owner bot"`
+	BotChannelURL string `env:"BOT_CHANNEL_URL" default:"https://t.me/baecorner" usage:"URL channel bot"`
+	BotSupportURL string `env:"BOT_SUPPORT_URL" default:"https://t.me/tgnolimitchat" usage:"URL grup support"`
+
+	// Storage
+	DatabaseDriver string `env:"DATABASE_DRIVER" default:"postgres" usage:"postgres atau sqlite"`
+	DatabaseURL    string `env:"DATABASE_URL" secret:"true" usage:"Connection URL Postgres (wajib jika DATABASE_DRIVER=postgres)"`
+	SQLitePath     string `env:"SQLITE_PATH" default:"tg-anon.db" usage:"File database jika DATABASE_DRIVER=sqlite"`
+	RedisURL       string `env:"REDIS_URL" required:"true" secret:"true" usage:"Connection URL Redis"`
+
+	// Postgres Connection Settings
+	DBForceIPv4        bool   `env:"DB_FORCE_IPV4" default:"false" usage:"Hanya resolve & connect lewat IPv4"`
+	DBDNSServer        string `env:"DB_DNS_SERVER" usage:"DNS server host:port untuk resolve host DB (kosong = resolver sistem)"`
+	DBMaxConns         int    `env:"DB_MAX_CONNS" default:"5" usage:"Ukuran maksimal pool"`
+	DBMinConns         int    `env:"DB_MIN_CONNS" default:"1" usage:"Koneksi idle minimal"`
+	DBConnectTimeout   int    `env:"DB_CONNECT_TIMEOUT" default:"10" usage:"Detik per percobaan connect (0 = tanpa batas)"`
+	DBStatementTimeout int    `env:"DB_STATEMENT_TIMEOUT" default:"30" usage:"Detik maksimal satu query (0 = tanpa batas)"`
+	DBConnectRetries   int    `env:"DB_CONNECT_RETRIES" default:"5" usage:"Percobaan ulang saat start sebelum menyerah"`
+	MigrationsDryRun   bool   `env:"MIGRATIONS_DRY_RUN" default:"false" usage:"Tampilkan migrasi yang akan dijalankan lalu keluar"`
+
+	// Run Mode
+	Port           string `env:"PORT" usage:"Port HTTP (health check / webhook)"`
+	UsePolling     bool   `env:"USE_POLLING" default:"false" usage:"Pakai long polling walaupun PORT diisi"`
+	WebhookURL     string `env:"WEBHOOK_URL" usage:"URL publik app untuk webhook"`
+	AppURL         string `env:"APP_URL" usage:"URL app untuk self-ping"`
+	EnableSelfPing bool   `env:"ENABLE_SELF_PING" default:"false" usage:"Ping APP_URL berkala supaya dyno tidak tidur"`
+
+	// Webhook Settings
+	WebhookPath         string `env:"WEBHOOK_PATH" default:"/webhook" usage:"Path webhook (tanpa bot token)"`
+	WebhookSecret       string `env:"WEBHOOK_SECRET" secret:"true" usage:"Secret token header (default diturunkan dari BOT_TOKEN)"`
+	WebhookMaxBodyBytes int64  `env:"WEBHOOK_MAX_BODY_BYTES" default:"1048576" usage:"Batas ukuran body request webhook"`
+	CallbackSecret      string `env:"CALLBACK_SECRET" secret:"true" usage:"Kunci HMAC callback data (default turunan BOT_TOKEN)"`
+
+	// Moderation
+	MaxWarnings            int  `env:"MAX_WARNINGS" default:"3" usage:"Jumlah warning sebelum auto-ban"`
+	AdsIntervalMessages    int  `env:"ADS_INTERVAL_MESSAGES" default:"30" usage:"Kirim ads setiap N pesan"`
+	LowReputationThreshold int  `env:"LOW_REPUTATION_THRESHOLD" default:"30" usage:"Skor reputasi (0-100) di bawah ini dianggap rendah"`
+	MinRatingsForFlag      int  `env:"MIN_RATINGS_FOR_FLAG" default:"10" usage:"Minimal jumlah rating sebelum user bisa diflag"`
+	RestrictLowReputation  bool `env:"RESTRICT_LOW_REPUTATION" default:"true" usage:"User reputasi rendah hanya dimatch dengan sesamanya"`
+
+	// Matching
+	NearbyRadius int `env:"NEARBY_RADIUS" default:"100" usage:"Jarak maksimal (km) pencarian nearby"`
+
+	// Sessions & Flows (menit)
+	SessionIdleTimeout int `env:"SESSION_IDLE_TIMEOUT" default:"30" usage:"Menit tanpa pesan sebelum sesi ditutup (0 = nonaktif)"`
+	SessionIdleWarning int `env:"SESSION_IDLE_WARNING" default:"5" usage:"Menit sebelum ditutup peringatan dikirim"`
+	FlowTimeout        int `env:"FLOW_TIMEOUT" default:"30" usage:"Menit sebelum flow multi-step kedaluwarsa"`
+
+	// Throughput
+	RateLimitMessages int `env:"RATE_LIMIT_MESSAGES" default:"20" usage:"Maksimal update per window per user (0 = nonaktif)"`
+	RateLimitWindow   int `env:"RATE_LIMIT_WINDOW" default:"10" usage:"Panjang window rate limit dalam detik"`
+	UpdateWorkers     int `env:"UPDATE_WORKERS" default:"16" usage:"Jumlah worker (shard) pemroses update"`
+	UpdateQueueSize   int `env:"UPDATE_QUEUE_SIZE" default:"100" usage:"Kapasitas antrian per worker"`
+
+	// Background Jobs
+	ErrorReportThreshold int `env:"ERROR_REPORT_THRESHOLD" default:"5" usage:"Error yang sama sebanyak ini baru dilaporkan"`
+	ErrorReportWindow    int `env:"ERROR_REPORT_WINDOW" default:"10" usage:"Window hitungan error dalam menit"`
+	UserCacheTTL         int `env:"USER_CACHE_TTL" default:"10" usage:"Menit state chat user disimpan di cache Redis"`
+	CounterFlushInterval int `env:"COUNTER_FLUSH_INTERVAL" default:"30" usage:"Detik antar flush counter Redis ke database"`
+	ReconcileInterval    int `env:"RECONCILE_INTERVAL" default:"5" usage:"Menit antar reconcile state (0 = nonaktif)"`
+	UpdateDedupTTL       int `env:"UPDATE_DEDUP_TTL" default:"24" usage:"Jam update_id yang sudah diproses diingat"`
+	ShutdownTimeout      int `env:"SHUTDOWN_TIMEOUT" default:"25" usage:"Detik untuk menyelesaikan pekerjaan saat SIGTERM"`
+}
+
+// C adalah konfigurasi aktif, diganti oleh Load saat start.
+// Sebelum Load berisi nilai default supaya package lain aman dipakai (misalnya di tool/test).
+var C = defaults()
+
+// Entry adalah satu setting yang sudah diformat untuk ditampilkan
+type Entry struct {
+	Key   string
+	Value string
+}
+
+const redacted = "****"
+
+// Load membaca konfigurasi dengan urutan prioritas: default < file < environment < flag.
+// File (format .env) diambil dari flag -config atau CONFIG_FILE dan boleh tidak ada jika tidak diminta.
+// Hasilnya divalidasi; jika valid dipasang sebagai C.
+func Load(args []string) (*Config, error) {
+	cfg := defaults()
+	fields := cfg.fields()
+
+	fs := flag.NewFlagSet("tg-anon-go", flag.ContinueOnError)
+	configFile := fs.String("config", os.Getenv("CONFIG_FILE"), "File konfigurasi format .env (CONFIG_FILE)")
+	flagValues := make(map[string]*string, len(fields))
+	for _, f := range fields {
+		flagValues[f.key] = fs.String(f.flagName(), "", f.usage+" ("+f.key+")")
+	}
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+
+	values := make(map[string]string)
+	if *configFile != "" {
+		fileValues, err := godotenv.Read(*configFile)
+		if err != nil {
+			return nil, fmt.Errorf("read config file %s: %v", *configFile, err)
+		}
+		for key, value := range fileValues {
+			values[key] = value
+		}
+	}
+	for _, f := range fields {
+		if value, ok := os.LookupEnv(f.key); ok && value != "" {
+			values[f.key] = value
+		}
+	}
+	fs.Visit(func(fl *flag.Flag) {
+		for _, f := range fields {
+			if f.flagName() == fl.Name {
+				values[f.key] = *flagValues[f.key]
+			}
+		}
+	})
+
+	var errs []error
+	for _, f := range fields {
+		value, ok := values[f.key]
+		if !ok {
+			continue
+		}
+		if err := f.set(value); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %v", f.key, err))
+		}
+	}
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+
+	C = cfg
+	return cfg, nil
+}
+
+// Validate mengecek nilai wajib dan batas tiap setting, semua kesalahan dikembalikan sekaligus
+func (c *Config) Validate() error {
+	var errs []error
+	fail := func(format string, args ...interface{}) {
+		errs = append(errs, fmt.Errorf(format, args...))
+	}
+
+	for _, f := range c.fields() {
+		if f.required && f.value.IsZero() {
+			fail("%s is required", f.key)
+		}
+	}
+
+	switch c.DatabaseDriver {
+	case constants.DatabaseDriverPostgres:
+		if c.DatabaseURL == "" {
+			fail("DATABASE_URL is required when DATABASE_DRIVER=%s", constants.DatabaseDriverPostgres)
+		}
+	case constants.DatabaseDriverSQLite:
+		if c.SQLitePath == "" {
+			fail("SQLITE_PATH is required when DATABASE_DRIVER=%s", constants.DatabaseDriverSQLite)
+		}
+	default:
+		fail("DATABASE_DRIVER must be %s or %s, got %q",
+			constants.DatabaseDriverPostgres, constants.DatabaseDriverSQLite, c.DatabaseDriver)
+	}
+
+	for _, owner := range c.OwnerIDs {
+		if owner <= 0 {
+			fail("OWNER_IDS must contain positive user IDs, got %d", owner)
+		}
+	}
+	if c.WebhookURL != "" {
+		if u, err := url.Parse(c.WebhookURL); err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+			fail("WEBHOOK_URL must be an http(s) URL, got %q", c.WebhookURL)
+		}
+	}
+	if c.EnableSelfPing && c.AppURL == "" {
+		fail("APP_URL is required when ENABLE_SELF_PING=true")
+	}
+	if !strings.HasPrefix(c.WebhookPath, "/") {
+		fail("WEBHOOK_PATH must start with /, got %q", c.WebhookPath)
+	}
+	// Path ini sudah dipakai oleh server HTTP bot (root, health check, metrics)
+	switch c.WebhookPath {
+	case "/", "/health", "/metrics":
+		fail("WEBHOOK_PATH %q is reserved, choose another path", c.WebhookPath)
+	}
+	if c.WebhookMaxBodyBytes <= 0 {
+		fail("WEBHOOK_MAX_BODY_BYTES must be positive")
+	}
+
+	if c.DBMaxConns < 1 {
+		fail("DB_MAX_CONNS must be at least 1")
+	}
+	if c.DBMinConns < 0 || c.DBMinConns > c.DBMaxConns {
+		fail("DB_MIN_CONNS must be between 0 and DB_MAX_CONNS (%d)", c.DBMaxConns)
+	}
+	if c.LowReputationThreshold < 0 || c.LowReputationThreshold > 100 {
+		fail("LOW_REPUTATION_THRESHOLD must be between 0 and 100")
+	}
+	if c.SessionIdleTimeout > 0 && (c.SessionIdleWarning < 0 || c.SessionIdleWarning >= c.SessionIdleTimeout) {
+		fail("SESSION_IDLE_WARNING must be between 0 and SESSION_IDLE_TIMEOUT (%d)", c.SessionIdleTimeout)
+	}
+	if c.RateLimitMessages > 0 && c.RateLimitWindow < 1 {
+		fail("RATE_LIMIT_WINDOW must be at least 1 when RATE_LIMIT_MESSAGES is set")
+	}
+
+	// Setting yang harus bernilai positif
+	for _, p := range []struct {
+		key   string
+		value int
+	}{
+		{"MAX_WARNINGS", c.MaxWarnings},
+		{"ADS_INTERVAL_MESSAGES", c.AdsIntervalMessages},
+		{"NEARBY_RADIUS", c.NearbyRadius},
+		{"FLOW_TIMEOUT", c.FlowTimeout},
+		{"UPDATE_WORKERS", c.UpdateWorkers},
+		{"UPDATE_QUEUE_SIZE", c.UpdateQueueSize},
+		{"ERROR_REPORT_THRESHOLD", c.ErrorReportThreshold},
+		{"ERROR_REPORT_WINDOW", c.ErrorReportWindow},
+		{"USER_CACHE_TTL", c.UserCacheTTL},
+		{"COUNTER_FLUSH_INTERVAL", c.CounterFlushInterval},
+		{"UPDATE_DEDUP_TTL", c.UpdateDedupTTL},
+		{"SHUTDOWN_TIMEOUT", c.ShutdownTimeout},
+	} {
+		if p.value < 1 {
+			fail("%s must be at least 1, got %d", p.key, p.value)
+		}
+	}
+
+	// Setting yang boleh 0 (nonaktif / tanpa batas) tapi tidak negatif
+	for _, p := range []struct {
+		key   string
+		value int
+	}{
+		{"DB_CONNECT_TIMEOUT", c.DBConnectTimeout},
+		{"DB_STATEMENT_TIMEOUT", c.DBStatementTimeout},
+		{"DB_CONNECT_RETRIES", c.DBConnectRetries},
+		{"MIN_RATINGS_FOR_FLAG", c.MinRatingsForFlag},
+		{"SESSION_IDLE_TIMEOUT", c.SessionIdleTimeout},
+		{"RATE_LIMIT_MESSAGES", c.RateLimitMessages},
+		{"RECONCILE_INTERVAL", c.ReconcileInterval},
+	} {
+		if p.value < 0 {
+			fail("%s must not be negative, got %d", p.key, p.value)
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// Entries mengembalikan semua setting dengan urutan deklarasi; nilai secret disamarkan
+func (c *Config) Entries() []Entry {
+	fields := c.fields()
+	entries := make([]Entry, 0, len(fields))
+	for _, f := range fields {
+		value := f.String()
+		if f.secret && value != "" {
+			value = redacted
+		}
+		entries = append(entries, Entry{Key: f.key, Value: value})
+	}
+	return entries
+}
+
+// String menampilkan konfigurasi sebagai KEY=value per baris dengan secret disamarkan
+func (c *Config) String() string {
+	var sb strings.Builder
+	for _, e := range c.Entries() {
+		fmt.Fprintf(&sb, "%s=%s\n", e.Key, e.Value)
+	}
+	return sb.String()
+}
+
+// IsOwner mengecek apakah user termasuk OWNER_IDS
+func (c *Config) IsOwner(userID int64) bool {
+	for _, ownerID := range c.OwnerIDs {
+		if userID == ownerID {
+			return true
+		}
+	}
+	return false
+}
+
+// field adalah satu field Config beserta metadata tag-nya
+type field struct {
+	key      string
+	usage    string
+	def      string
+	required bool
+	secret   bool
+	value    reflect.Value
+}
+
+func (c *Config) fields() []field {
+	v := reflect.ValueOf(c).Elem()
+	t := v.Type()
+	fields := make([]field, 0, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		fields = append(fields, field{
+			key:      sf.Tag.Get("env"),
+			usage:    sf.Tag.Get("usage"),
+			def:      sf.Tag.Get("default"),
+			required: sf.Tag.Get("required") == "true",
+			secret:   sf.Tag.Get("secret") == "true",
+			value:    v.Field(i),
+		})
+	}
+	return fields
+}
+
+// defaults membuat Config berisi nilai tag `default`
+func defaults() *Config {
+	cfg := &Config{}
+	for _, f := range cfg.fields() {
+		if f.def == "" {
+			continue
+		}
+		if err := f.set(f.def); err != nil {
+			panic(fmt.Sprintf("config: invalid default for %s: %v", f.key, err))
+		}
+	}
+	return cfg
+}
+
+// flagName mengubah BOT_TOKEN menjadi bot-token
+func (f field) flagName() string {
+	return strings.ReplaceAll(strings.ToLower(f.key), "_", "-")
+}
+
+// set mem-parse value sesuai tipe field; nilai yang tidak valid menjadi error, bukan diabaikan
+func (f field) set(value string) error {
+	value = strings.TrimSpace(value)
+	switch f.value.Kind() {
+	case reflect.String:
+		f.value.SetString(value)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("invalid boolean %q", value)
+		}
+		f.value.SetBool(b)
+	case reflect.Int, reflect.Int64:
+		n, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid integer %q", value)
+		}
+		f.value.SetInt(n)
+	case reflect.Slice:
+		var ids []int64
+		for _, part := range strings.Split(value, ",") {
+			part = strings.TrimSpace(part)
+			if part == "" {
+				continue
+			}
+			n, err := strconv.ParseInt(part, 10, 64)
+			if err != nil {
+				return fmt.Errorf("invalid integer %q in list", part)
+			}
+			ids = append(ids, n)
+		}
+		f.value.Set(reflect.ValueOf(ids))
+	default:
+		return fmt.Errorf("unsupported type %s", f.value.Type())
+	}
+	return nil
+}
+
+// String memformat nilai field seperti saat ditulis di environment
+func (f field) String() string {
+	switch f.value.Kind() {
+	case reflect.Slice:
+		ids := f.value.Interface().([]int64)
+		parts := make([]string, len(ids))
+		for i, id := range ids {
+			parts[i] = strconv.FormatInt(id, 10)
+		}
+		return strings.Join(parts, ",")
+	default:
+		return fmt.Sprint(f.value.Interface())
+	}
+}
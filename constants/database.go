@@ -1,63 +1,47 @@
 package constants
 
+// Database drivers (DATABASE_DRIVER)
+const (
+	DatabaseDriverPostgres = "postgres"
+	DatabaseDriverSQLite   = "sqlite"
+)
+
 // Database Tables
 const (
 	TableUsers    = "users"
 	TableSessions = "chat_sessions"
 	TableMessages = "messages"
 	TableVars     = "vars"
+	TableRatings  = "session_ratings"
+
+	TableUserProfiles = "user_profiles" // Profil & preferensi user
+	TableUserState    = "user_state"    // Status chat, partner, sesi & flag moderasi
+	TableUserStats    = "user_stats"    // Counter & reputasi
+
+	TableDailyStats       = "daily_stats"
+	TableDailyActiveUsers = "daily_active_users"
 )
 
-// SQL Queries
-const (
-	QueryCreateUsersTable = `
-		CREATE TABLE IF NOT EXISTS users (
-			id SERIAL PRIMARY KEY,
-			telegram_id BIGINT UNIQUE NOT NULL,
-			username VARCHAR(255),
-			first_name VARCHAR(255),
-			status VARCHAR(50) DEFAULT 'idle',
-			partner_id BIGINT DEFAULT NULL,
-			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-			updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
-		);
-		CREATE INDEX IF NOT EXISTS idx_users_telegram_id ON users(telegram_id);
-		CREATE INDEX IF NOT EXISTS idx_users_status ON users(status);
-	`
+// Tabel pencatat versi migrasi (lihat databases/migrations)
+const TableSchemaMigrations = "schema_migrations"
 
-	QueryCreateSessionsTable = `
-		CREATE TABLE IF NOT EXISTS chat_sessions (
-			id SERIAL PRIMARY KEY,
-			user1_id BIGINT NOT NULL,
-			user2_id BIGINT NOT NULL,
-			started_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-			ended_at TIMESTAMP DEFAULT NULL,
-			is_active BOOLEAN DEFAULT TRUE
-		);
-		CREATE INDEX IF NOT EXISTS idx_sessions_active ON chat_sessions(is_active);
-	`
-	QueryCreateMessagesTable = `
-		CREATE TABLE IF NOT EXISTS messages (
-			id SERIAL PRIMARY KEY,
-			session_id INT REFERENCES chat_sessions(id),
-			sender_id BIGINT NOT NULL,
-			receiver_id BIGINT NOT NULL,
-			message_type VARCHAR(50) DEFAULT 'text',
-			content TEXT,
-			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
-		);
-	`
+// MigrationLockID adalah key pg_advisory_lock supaya hanya satu instance yang menjalankan migrasi
+const MigrationLockID int64 = 7246119
 
-	QueryCreateVarsTable = `
-		CREATE TABLE IF NOT EXISTS vars (
-			id SERIAL PRIMARY KEY,
-			user_id BIGINT NOT NULL,
-			var_key VARCHAR(255) NOT NULL,
-			var_value TEXT,
-			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-			updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-			UNIQUE(user_id, var_key)
+// SQL Queries untuk migrasi
+const (
+	QueryCreateSchemaMigrationsTable = `
+		CREATE TABLE IF NOT EXISTS schema_migrations (
+			version INT PRIMARY KEY,
+			name VARCHAR(255) NOT NULL,
+			applied_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
 		);
-		CREATE INDEX IF NOT EXISTS idx_vars_user_key ON vars(user_id, var_key);
 	`
+	QuerySelectMigrationVersions = `SELECT version FROM schema_migrations ORDER BY version`
+	QueryInsertMigration         = `INSERT INTO schema_migrations (version, name) VALUES ($1, $2)`
+	QueryTableExists             = `SELECT to_regclass($1) IS NOT NULL`
+	QueryAdvisoryLock            = `SELECT pg_advisory_lock($1)`
+	QueryAdvisoryUnlock          = `SELECT pg_advisory_unlock($1)`
+	QueryDisableStatementTimeout = `SET statement_timeout = 0`
+	QueryResetStatementTimeout   = `RESET statement_timeout`
 )
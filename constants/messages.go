@@ -10,25 +10,31 @@ const (
 	CmdShare       = "share"
 	CmdProfile     = "profile"
 	CmdEditProfile = "editprofile"
+	CmdPrivacy     = "privacy"
+	CmdMyStats     = "mystats"
+	CmdCancel      = "cancel"
 )
 
 // Admin Commands
 const (
-	CmdAdmin      = "admin"
-	CmdBroadcast  = "broadcast"
-	CmdResetDB    = "resetdb"
-	CmdAddAds     = "addads"
-	CmdDelAds     = "delads"
-	CmdListAds    = "listads"
-	CmdToggleAds  = "toggleads"
-	CmdStats      = "stats"
-	CmdBan        = "ban"
-	CmdUnban      = "unban"
-	CmdEnv        = "env"
-	CmdUpdate     = "update"
-	CmdSetFsub    = "setfsub"
-	CmdRemoveFsub = "removefsub"
-	CmdFsubInfo   = "fsubinfo"
+	CmdAdmin       = "admin"
+	CmdBroadcast   = "broadcast"
+	CmdResetDB     = "resetdb"
+	CmdAddAds      = "addads"
+	CmdDelAds      = "delads"
+	CmdListAds     = "listads"
+	CmdToggleAds   = "toggleads"
+	CmdStats       = "stats"
+	CmdBan         = "ban"
+	CmdUnban       = "unban"
+	CmdEnv         = "env"
+	CmdUpdate      = "update"
+	CmdSetFsub     = "setfsub"
+	CmdRemoveFsub  = "removefsub"
+	CmdFsubInfo    = "fsubinfo"
+	CmdReport      = "report"
+	CmdConfig      = "config"
+	CmdMaintenance = "maintenance"
 )
 
 // User Status
@@ -63,6 +69,9 @@ Ketik /search untuk mulai mencari partner!`
 /stop - Mengakhiri percakapan saat ini
 /profile - Lihat profil kamu
 /editprofile - Edit profil kamu
+/privacy - Atur data yang dilihat partner
+/mystats - Statistik chat kamu
+/cancel - Batalkan proses yang sedang berjalan
 /help - Menampilkan pesan bantuan ini
 
 💡 *Tips:*
@@ -80,10 +89,13 @@ Ketik /search untuk mulai mencari partner!`
 	MsgSearchCancelled    = "❎ Pencarian dibatalkan."
 	MsgCannotSendToSelf   = "❌ Tidak bisa mengirim pesan ke diri sendiri."
 	MsgPartnerDisconnect  = "⚠️ Partner terputus dari chat."
+	MsgPartnerGone        = "😔 Partner kamu sudah tidak tersedia (memblokir bot atau akunnya dihapus). Chat telah diakhiri.\n\nKetik /search untuk mencari partner baru."
 	MsgError              = "❌ Terjadi kesalahan. Silakan coba lagi."
+	MsgNotSupported       = "ℹ️ Fitur ini belum tersedia untuk database yang sedang dipakai."
 	MsgRegistered         = "✅ Kamu telah terdaftar!"
 	MsgNotRegistered      = "❌ Kamu belum terdaftar. Silakan ketik /start untuk mendaftar."
-	MsgAutoClosedInactive = "⏰ Chat kamu telah otomatis ditutup karena sudah lebih dari 2 hari.\n\nKetik /search untuk mencari partner baru!"
+	MsgAutoClosedInactive = "⏰ Chat kamu telah otomatis ditutup karena tidak ada pesan selama %d menit.\n\nKetik /search untuk mencari partner baru!"
+	MsgIdleWarning        = "⏳ Chat ini sepi nih. Chat akan ditutup otomatis dalam *%d menit*, kirim pesan apa saja untuk tetap lanjut."
 
 	// Share Messages
 	MsgShareSent     = "✅ Kontak kamu telah dikirim ke partner!"
@@ -103,6 +115,21 @@ Ketik /search untuk mulai mencari partner!`
 	MsgShareNotChatting = "❌ Kamu harus sedang dalam chat untuk membagikan kontak."
 )
 
+// Rating Messages
+const (
+	MsgRatePrompt       = "⭐ Bagaimana partner chat kamu tadi?"
+	MsgRateThanks       = "🙏 Terima kasih atas penilaian kamu!"
+	MsgRateAlreadyRated = "Kamu sudah menilai chat ini."
+	MsgRateInvalid      = "❌ Penilaian tidak valid."
+	MsgLowReputationLog = `⚠️ *Reputasi Rendah*
+
+👤 User: [User %d](tg://user?id=%d)
+⭐ Skor: *%.0f/100*
+👍 %d / 👎 %d
+
+User ini sekarang hanya dimatch dengan user reputasi rendah lainnya.`
+)
+
 // Registration Messages
 const (
 	MsgRegWelcome = `🎭 *Selamat datang di Anonymous Chat Bot!*
@@ -146,11 +173,14 @@ Ketik /search untuk memulai.`
 📅 Umur: *%s tahun*
 👥 Gender: *%s*
 📍 Lokasi: *%s*
+🎯 Minat: *%s*
 📊 Total Chat: *%d*
 💬 Total Pesan: *%d*
+⭐ Reputasi: *%.0f/100* (%d rating)
 
 Gunakan /search untuk mencari partner!
-Gunakan /editprofile untuk edit profil.`
+Gunakan /editprofile untuk edit profil.
+Gunakan /privacy untuk atur data yang dilihat partner.`
 
 	MsgEditProfile = `✏️ *Edit Profil*
 
@@ -180,10 +210,70 @@ Lokasi saat ini: *%s*
 
 Bagikan lokasi baru kamu:`
 
+	MsgEditInterests = `✏️ *Edit Minat*
+
+Minat saat ini: *%s*
+
+Kirim minat/hobi kamu (contoh: musik, game, kopi). Maksimal 100 karakter:`
+
+	MsgInterestsTooLong = "⚠️ Minat terlalu panjang. Maksimal 100 karakter."
+
 	MsgProfileUpdated = "✅ Profil berhasil diupdate!"
 	MsgEditCancelled  = "❌ Edit profil dibatalkan."
 )
 
+// Flow Messages
+const (
+	MsgFlowCancelHint      = "\n\n_Ketik /cancel untuk membatalkan._"
+	MsgFlowCancelled       = "❎ Dibatalkan."
+	MsgFlowNothingToCancel = "❌ Tidak ada proses yang bisa dibatalkan."
+	MsgFlowExpired         = "⌛ Waktu pengisian sudah habis. Silakan mulai lagi."
+	MsgFlowStaleButton     = "⚠️ Tombol ini sudah tidak berlaku."
+	MsgFlowUseButtons      = "⚠️ Silakan pilih menggunakan tombol di bawah."
+	MsgRegCancelled        = "❎ Registrasi dibatalkan. Ketik /start untuk mulai lagi."
+	MsgNameTooShort        = "⚠️ Nama terlalu pendek. Silakan masukkan nama yang valid."
+	MsgNameTooLong         = "⚠️ Nama terlalu panjang. Maksimal 50 karakter."
+	MsgInterestsEmpty      = "⚠️ Minat tidak boleh kosong."
+)
+
+// User Stats Messages
+const (
+	MsgMyStats = `📊 *Statistik Chat Kamu*
+
+💬 Total Sesi: *%d*
+⏱️ Rata-rata Durasi: *%s*
+🏆 Sesi Terlama: *%s*
+⏳ Rata-rata Waktu Tunggu: *%s*
+📤 Pesan Dikirim: *%d*
+📥 Pesan Diterima: *%d*
+👋 Kamu Akhiri: *%d*
+🚪 Partner Akhiri: *%d*`
+
+	MsgMyStatsEmpty = "📊 Kamu belum pernah chat. Ketik /search untuk mulai!"
+)
+
+// Privacy & Partner Card Messages
+const (
+	MsgPrivacyMenu = `🔒 *Privasi Profil*
+
+Pilih data yang boleh dilihat partner saat match.
+Nama dan ID Telegram tidak pernah ditampilkan kecuali kamu aktifkan.
+
+*Preview kartu kamu:*
+%s`
+
+	MsgPrivacySaved        = "✅ Pengaturan privasi disimpan."
+	MsgPrivacyHidden       = "🕶️ Semua data disembunyikan, kamu full anonim."
+	MsgPartnerCard         = "👤 *Tentang partner kamu:*\n%s"
+	MsgPartnerAnonim       = "🕶️ Partner memilih untuk tetap anonim."
+	MsgCardFieldAge        = "📅 Umur: *%s tahun*"
+	MsgCardFieldGender     = "👥 Gender: *%s*"
+	MsgCardFieldCity       = "📍 Kota: *%s*"
+	MsgCardFieldInterests  = "🎯 Minat: *%s*"
+	MsgCardFieldName       = "🙋 Nama: *%s*"
+	MsgCardFieldTelegramID = "🆔 ID Telegram: `%d`"
+)
+
 // Search Messages
 const (
 	MsgSearchNearbyNoLocation = "⚠️ Kamu belum menyimpan lokasi. Silakan update lokasi dengan /updatelocation"
@@ -202,8 +292,12 @@ const (
 📨 Total Messages: *%d*
 
 🛠 *Commands:*
-/stats - Lihat statistik
+/stats - Lihat statistik & tren
+/stats csv - Download statistik harian (CSV)
+/report [hari] - Laporan sesi chat (default 7 hari)
 /env - Lihat environment variables
+/config list|get|set - Lihat & ubah setting runtime
+/maintenance on|off [pesan] - Mode maintenance
 /broadcast <pesan> - Broadcast ke semua user
 /update - Update bot ke versi terbaru
 /resetdb - Reset database (⚠️ BAHAYA!)
@@ -237,18 +331,41 @@ const (
 📢 Ads Enabled: *%s*
 📝 Total Ads: *%d*`
 
+	MsgStatsTrend = `
+
+📈 *Tren %d Hari:*
+👤 User Baru: *%d*
+👥 Rata-rata DAU: *%.0f*
+🤝 Match: *%d*
+⏳ Median Tunggu: *%s*
+⏱️ Rata-rata Sesi: *%s*
+📨 Pesan: *%d*`
+
+	MsgStatsDaily      = "\n\n📅 *Harian:*\n`Tanggal  Baru   DAU Match`\n%s"
+	MsgStatsNoRollup   = "\n\n📈 Belum ada data tren (rollup harian belum berjalan)."
+	MsgStatsCSVEmpty   = "❌ Belum ada data statistik harian."
+	MsgStatsCSVCaption = "📊 Statistik harian %d hari terakhir"
+
+	MsgSessionReport = `📈 *Laporan Sesi (%d hari terakhir)*
+
+💬 Total Sesi: *%d* (aktif: *%d*)
+⏱️ Rata-rata Durasi: *%s*
+⏳ Rata-rata Waktu Tunggu: *%s*
+📨 Rata-rata Pesan/Sesi: *%.1f*
+🤐 Sesi Tanpa Pesan: *%d*
+
+🎲 Random Match: *%d*
+📍 Nearby Match: *%d* (rata-rata jarak *%.1f km*)
+
+🏁 *Alasan Berakhir:*
+%s`
+
 	MsgEnvInfo = `⚙️ *Environment Variables*
 
-🔗 *Bot URLs:*
-• Owner: %s
-• Channel: %s
-• Support: %s
+Nilai secret ditampilkan sebagai ****.
 
-📋 *Settings:*
-• Log Group ID: ` + "`%d`" + `
-• Owner IDs: ` + "`%s`" + `
-• Max Warnings: ` + "`%d`" + `
-• Ads Interval: ` + "`%d`" + ` messages
+` + "```" + `
+%s` + "```" + `
 
 💡 *Heroku Commands:*
 ` + "```" + `
@@ -264,6 +381,37 @@ heroku config:set LOG_GROUP_ID=-100123456 -a app-name
 heroku config:set OWNER_IDS=123,456,789 -a app-name
 ` + "```"
 
+	MsgMaintenanceUsage = `🛠️ *Maintenance Mode*
+
+Status: %s
+
+Gunakan:
+/maintenance on [pesan] - Hentikan pencarian baru
+/maintenance on -end [pesan] - Sekaligus akhiri semua sesi chat aktif
+/maintenance off - Kembali normal
+
+Owner tetap bisa memakai bot selama maintenance.`
+	MsgMaintenanceOn  = "🛠️ Maintenance mode *aktif*.\n\n🔍 Pencarian dibatalkan: *%d*\n💬 Sesi chat diakhiri: *%d*"
+	MsgMaintenanceOff = "✅ Maintenance mode *nonaktif*. Bot kembali normal."
+
+	MsgConfigUsage = `⚙️ *Runtime Settings*
+
+Gunakan:
+/config list - Semua setting
+/config get <key> - Detail satu setting
+/config set <key> <value> - Ubah setting (langsung berlaku di semua instance)`
+	MsgConfigList = `⚙️ *Runtime Settings*
+
+%s
+✏️ = diubah lewat /config (bukan default environment)
+💡 Ubah dengan /config set <key> <value>`
+	MsgConfigListItem = "• `%s` = `%s`%s\n   %s\n"
+	MsgConfigGet      = "⚙️ `%s` = `%s`\n\n%s\n\n📏 Rentang: `%s`\n🔧 Default: `%s`\n📍 Sumber: %s"
+	MsgConfigSet      = "✅ `%s` diubah dari `%s` menjadi `%s`.\nPerubahan langsung berlaku di semua instance."
+	MsgConfigUnknown  = "❌ Setting `%s` tidak dikenal. Lihat /config list"
+	MsgConfigInvalid  = "❌ Nilai tidak valid: `%v`"
+	MsgConfigFailed   = "❌ Gagal menyimpan setting: `%v`"
+
 	MsgUpdateStart    = "🔄 *Memulai update bot...*"
 	MsgUpdatePulling  = "📥 Pulling latest code dari git..."
 	MsgUpdateBuilding = "🔨 Building binary baru..."
@@ -292,9 +440,41 @@ const (
 	MsgMediaDeleted = "🗑️ Media dari user yang mendapat peringatan telah dihapus."
 )
 
-// Callback Prefixes
+// Callback Namespaces
+// Format callback data: {namespace}:{action}[:{arg}...]:{signature}
+const (
+	CallbackWarn    = "warn"    // warn:user:{senderID}:{partnerID}:{messageID} (owner only)
+	CallbackRate    = "rate"    // rate:{up|down}:{sessionID}
+	CallbackPrivacy = "privacy" // privacy:{toggle|hide_all|done}[:{field}]
+	CallbackProfile = "profile" // profile:{edit|cancel|close}[:{field}]
+	CallbackFsub    = "fsub"    // fsub:verify
+	CallbackFlow    = "flow"    // flow:{step}:{value}
+)
+
+// Middleware Messages
+const (
+	MsgBanned                  = "❌ Kamu telah dibanned dari bot ini."
+	MsgMaintenance             = "🛠️ *Bot sedang maintenance.*\n\nSilakan coba lagi beberapa saat lagi."
+	MsgMaintenanceCustom       = "🛠️ *Bot sedang maintenance.*\n\n%s"
+	MsgMaintenanceSessionEnded = "💬 Chat kamu diakhiri oleh admin karena maintenance."
+	MsgRateLimited             = "⏳ Terlalu cepat! Tunggu sebentar sebelum mengirim lagi."
+)
+
+// Error Report Messages (dikirim tanpa Markdown ke log group)
+const (
+	MsgReportPanic      = "🔥 PANIC\n\nPlugin: %s\nUpdate: %s\nUser: %d\nError: %v\n\n%s"
+	MsgReportError      = "⚠️ ERROR BERULANG\n\nPlugin: %s\nUpdate: %s\nUser terakhir: %d\nJumlah: %dx dalam %d menit\nError terakhir: %v"
+	MsgReportSuppressed = "\n\n(%d laporan lain ditahan karena rate limit)"
+)
+
+// MsgReconcileReport adalah laporan reconciler state ke log group (tanpa Markdown)
+const MsgReconcileReport = "🩹 RECONCILE STATE\n\nPartner satu arah direset: %d\nSesi yatim ditutup: %d\nSearcher basi dihapus dari Redis: %d\nSearcher hilang dipublish ulang: %d"
+
+// Callback Messages
 const (
-	CallbackWarnUser = "warn_user_" // Format: warn_user_{userID}_{messageID}
+	MsgCallbackInvalid   = "⚠️ Tombol tidak valid atau sudah kedaluwarsa."
+	MsgCallbackOwnerOnly = "⛔ Tombol ini hanya untuk admin."
+	MsgCallbackFailed    = "❌ Terjadi kesalahan, coba lagi."
 )
 
 // FSub Messages
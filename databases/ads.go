@@ -0,0 +1,80 @@
+package databases
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+	"time"
+
+	"tg-anon-go/constants"
+)
+
+// varAds menyimpan iklan sebagai JSON di variabel global, sehingga bisa dipakai
+// di atas VarRepository dari driver mana pun
+type varAds struct {
+	vars VarRepository
+}
+
+func (r varAds) List(ctx context.Context) ([]Ad, error) {
+	value, err := r.vars.Get(ctx, 0, constants.VarGlobalAds)
+	if err != nil || value == "" {
+		return []Ad{}, err
+	}
+
+	var ads []Ad
+	if err := json.Unmarshal([]byte(value), &ads); err != nil {
+		return []Ad{}, nil
+	}
+	return ads, nil
+}
+
+func (r varAds) save(ctx context.Context, ads []Ad) error {
+	value, err := json.Marshal(ads)
+	if err != nil {
+		return err
+	}
+	return r.vars.Set(ctx, 0, constants.VarGlobalAds, string(value))
+}
+
+func (r varAds) Add(ctx context.Context, message string) (Ad, error) {
+	ads, err := r.List(ctx)
+	if err != nil {
+		return Ad{}, err
+	}
+
+	ad := Ad{ID: 1, Message: message, CreatedAt: time.Now()}
+	if len(ads) > 0 {
+		ad.ID = ads[len(ads)-1].ID + 1
+	}
+	return ad, r.save(ctx, append(ads, ad))
+}
+
+func (r varAds) Delete(ctx context.Context, id int) (bool, error) {
+	ads, err := r.List(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	kept := make([]Ad, 0, len(ads))
+	for _, ad := range ads {
+		if ad.ID != id {
+			kept = append(kept, ad)
+		}
+	}
+	if len(kept) == len(ads) {
+		return false, nil
+	}
+	return true, r.save(ctx, kept)
+}
+
+func (r varAds) Enabled(ctx context.Context) (bool, error) {
+	value, err := r.vars.Get(ctx, 0, constants.VarGlobalAdsEnabled)
+	if err != nil || value == "" {
+		return false, err
+	}
+	return strconv.ParseBool(value)
+}
+
+func (r varAds) SetEnabled(ctx context.Context, enabled bool) error {
+	return r.vars.Set(ctx, 0, constants.VarGlobalAdsEnabled, strconv.FormatBool(enabled))
+}
@@ -0,0 +1,112 @@
+package databases
+
+import (
+	"context"
+	"time"
+)
+
+// UserSessionStats berisi ringkasan sesi chat milik satu user
+type UserSessionStats struct {
+	TotalSessions    int64
+	AvgDuration      float64 // detik
+	LongestDuration  float64 // detik
+	AvgWait          float64 // detik
+	MessagesSent     int64
+	MessagesReceived int64
+	EndedByMe        int64
+	EndedByPartner   int64
+}
+
+// GetUserSessionStats menghitung statistik sesi chat user dari chat_sessions (hanya Postgres)
+func GetUserSessionStats(ctx context.Context, userID int64) (UserSessionStats, error) {
+	if DB == nil {
+		return UserSessionStats{}, ErrNotSupported
+	}
+	query := `
+		SELECT
+			COUNT(*),
+			COALESCE(AVG(EXTRACT(EPOCH FROM (ended_at - started_at))), 0)::float8,
+			COALESCE(MAX(EXTRACT(EPOCH FROM (ended_at - started_at))), 0)::float8,
+			COALESCE(AVG(CASE WHEN user1_id = $1 THEN user1_wait_seconds ELSE user2_wait_seconds END), 0)::float8,
+			COALESCE(SUM(CASE WHEN user1_id = $1 THEN user1_messages ELSE user2_messages END), 0),
+			COALESCE(SUM(CASE WHEN user1_id = $1 THEN user2_messages ELSE user1_messages END), 0),
+			COUNT(*) FILTER (WHERE ended_by = $1),
+			COUNT(*) FILTER (WHERE ended_by IS NOT NULL AND ended_by <> $1)
+		FROM chat_sessions
+		WHERE user1_id = $1 OR user2_id = $1
+	`
+	var stats UserSessionStats
+	err := DB.QueryRow(ctx, query, userID).Scan(
+		&stats.TotalSessions, &stats.AvgDuration, &stats.LongestDuration, &stats.AvgWait,
+		&stats.MessagesSent, &stats.MessagesReceived, &stats.EndedByMe, &stats.EndedByPartner,
+	)
+	return stats, err
+}
+
+// SessionReport berisi ringkasan sesi chat untuk admin
+type SessionReport struct {
+	TotalSessions  int64
+	ActiveSessions int64
+	AvgDuration    float64 // detik
+	AvgWait        float64 // detik
+	AvgMessages    float64
+	SilentSessions int64 // Sesi tanpa satu pesan pun
+	RandomMatches  int64
+	NearbyMatches  int64
+	AvgDistance    float64 // km, hanya match nearby
+	EndReasons     map[string]int64
+}
+
+// GetSessionReport menghitung ringkasan sesi yang dimulai sejak waktu tertentu (hanya Postgres)
+func GetSessionReport(ctx context.Context, since time.Time) (SessionReport, error) {
+	if DB == nil {
+		return SessionReport{}, ErrNotSupported
+	}
+	query := `
+		SELECT
+			COUNT(*),
+			COUNT(*) FILTER (WHERE is_active = true),
+			COALESCE(AVG(EXTRACT(EPOCH FROM (ended_at - started_at))), 0)::float8,
+			COALESCE(AVG((COALESCE(user1_wait_seconds, 0) + COALESCE(user2_wait_seconds, 0)) / 2.0), 0)::float8,
+			COALESCE(AVG(user1_messages + user2_messages), 0)::float8,
+			COUNT(*) FILTER (WHERE is_active = false AND user1_messages + user2_messages = 0),
+			COUNT(*) FILTER (WHERE match_mode = 'random'),
+			COUNT(*) FILTER (WHERE match_mode = 'nearby'),
+			COALESCE(AVG(distance_km) FILTER (WHERE match_mode = 'nearby'), 0)::float8
+		FROM chat_sessions
+		WHERE started_at >= $1
+	`
+	report := SessionReport{EndReasons: make(map[string]int64)}
+	err := DB.QueryRow(ctx, query, since).Scan(
+		&report.TotalSessions, &report.ActiveSessions, &report.AvgDuration, &report.AvgWait,
+		&report.AvgMessages, &report.SilentSessions, &report.RandomMatches, &report.NearbyMatches,
+		&report.AvgDistance,
+	)
+	if err != nil {
+		return report, err
+	}
+
+	// Breakdown alasan sesi berakhir
+	reasonQuery := `
+		SELECT COALESCE(end_reason, 'unknown'), COUNT(*)
+		FROM chat_sessions
+		WHERE started_at >= $1 AND is_active = false
+		GROUP BY 1
+		ORDER BY 2 DESC
+	`
+	rows, err := DB.Query(ctx, reasonQuery, since)
+	if err != nil {
+		return report, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var reason string
+		var count int64
+		if err := rows.Scan(&reason, &count); err != nil {
+			continue
+		}
+		report.EndReasons[reason] = count
+	}
+	return report, nil
+}
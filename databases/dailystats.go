@@ -0,0 +1,148 @@
+package databases
+
+import (
+	"context"
+	"time"
+)
+
+// DailyStats berisi agregat harian dari tabel daily_stats.
+// Tabel harian hanya ada di Postgres; di SQLite semua fungsi di file ini mengembalikan ErrNotSupported.
+type DailyStats struct {
+	Day               time.Time
+	NewUsers          int
+	DAU               int
+	Matches           int
+	MedianWaitSeconds float64
+	AvgSessionSeconds float64
+	Messages          int64
+}
+
+// DailyActiveUsersRetention adalah berapa hari data daily_active_users disimpan
+const DailyActiveUsersRetention = 90
+
+// MarkDailyActive mencatat user aktif hari ini (untuk hitung DAU)
+func MarkDailyActive(ctx context.Context, userID int64) error {
+	if DB == nil {
+		return ErrNotSupported
+	}
+	query := `
+		INSERT INTO daily_active_users (day, user_id)
+		VALUES ($1::date, $2)
+		ON CONFLICT (day, user_id) DO NOTHING
+	`
+	_, err := DB.Exec(ctx, query, today(), userID)
+	return err
+}
+
+// RollupDailyStats menghitung ulang agregat untuk satu hari dan menyimpannya ke daily_stats.
+// Aman dijalankan berkali-kali untuk hari yang sama (upsert).
+func RollupDailyStats(ctx context.Context, day time.Time) error {
+	if DB == nil {
+		return ErrNotSupported
+	}
+	query := `
+		WITH bounds AS (
+			SELECT $1::date AS day, $1::date + INTERVAL '1 day' AS next_day
+		),
+		registrations AS (
+			SELECT COUNT(*) AS new_users
+			FROM user_profiles, bounds
+			WHERE registered_at >= bounds.day AND registered_at < bounds.next_day
+		),
+		active AS (
+			SELECT COUNT(*) AS dau
+			FROM daily_active_users, bounds
+			WHERE daily_active_users.day = bounds.day
+		),
+		started AS (
+			SELECT
+				COUNT(*) AS matches,
+				COALESCE(SUM(user1_messages + user2_messages), 0) AS messages
+			FROM chat_sessions, bounds
+			WHERE started_at >= bounds.day AND started_at < bounds.next_day
+		),
+		waits AS (
+			SELECT COALESCE(percentile_cont(0.5) WITHIN GROUP (ORDER BY wait), 0)::float8 AS median_wait
+			FROM (
+				SELECT user1_wait_seconds AS wait FROM chat_sessions, bounds
+				WHERE started_at >= bounds.day AND started_at < bounds.next_day AND user1_wait_seconds IS NOT NULL
+				UNION ALL
+				SELECT user2_wait_seconds FROM chat_sessions, bounds
+				WHERE started_at >= bounds.day AND started_at < bounds.next_day AND user2_wait_seconds IS NOT NULL
+			) w
+		),
+		ended AS (
+			SELECT COALESCE(AVG(EXTRACT(EPOCH FROM (ended_at - started_at))), 0)::float8 AS avg_session
+			FROM chat_sessions, bounds
+			WHERE ended_at >= bounds.day AND ended_at < bounds.next_day
+		)
+		INSERT INTO daily_stats (day, new_users, dau, matches, median_wait_seconds, avg_session_seconds, messages, updated_at)
+		SELECT $1::date, registrations.new_users, active.dau, started.matches,
+			waits.median_wait, ended.avg_session, started.messages, $2
+		FROM registrations, active, started, waits, ended
+		ON CONFLICT (day) DO UPDATE SET
+			new_users = EXCLUDED.new_users,
+			dau = EXCLUDED.dau,
+			matches = EXCLUDED.matches,
+			median_wait_seconds = EXCLUDED.median_wait_seconds,
+			avg_session_seconds = EXCLUDED.avg_session_seconds,
+			messages = EXCLUDED.messages,
+			updated_at = EXCLUDED.updated_at
+	`
+	_, err := DB.Exec(ctx, query, day.Format("2006-01-02"), time.Now())
+	return err
+}
+
+// GetDailyStats mengambil agregat harian untuk N hari terakhir (terlama dulu)
+func GetDailyStats(ctx context.Context, days int) ([]DailyStats, error) {
+	if DB == nil {
+		return nil, ErrNotSupported
+	}
+	query := `
+		SELECT day, new_users, dau, matches, median_wait_seconds, avg_session_seconds, messages
+		FROM daily_stats
+		WHERE day > $1::date - $2::int
+		ORDER BY day ASC
+	`
+	rows, err := DB.Query(ctx, query, today(), days)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var stats []DailyStats
+	for rows.Next() {
+		var s DailyStats
+		if err := rows.Scan(&s.Day, &s.NewUsers, &s.DAU, &s.Matches,
+			&s.MedianWaitSeconds, &s.AvgSessionSeconds, &s.Messages); err != nil {
+			continue
+		}
+		stats = append(stats, s)
+	}
+	return stats, nil
+}
+
+// CountDailyStats menghitung jumlah hari yang sudah di-rollup
+func CountDailyStats(ctx context.Context) (int, error) {
+	if DB == nil {
+		return 0, ErrNotSupported
+	}
+	var count int
+	err := DB.QueryRow(ctx, `SELECT COUNT(*) FROM daily_stats`).Scan(&count)
+	return count, err
+}
+
+// PruneDailyActiveUsers menghapus data daily_active_users yang lebih lama dari retensi
+func PruneDailyActiveUsers(ctx context.Context) error {
+	if DB == nil {
+		return ErrNotSupported
+	}
+	query := `DELETE FROM daily_active_users WHERE day < $1::date - $2::int`
+	_, err := DB.Exec(ctx, query, today(), DailyActiveUsersRetention)
+	return err
+}
+
+// today mengembalikan tanggal hari ini (zona waktu bot, bukan database)
+func today() string {
+	return time.Now().Format("2006-01-02")
+}
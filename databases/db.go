@@ -5,136 +5,169 @@ import (
 	"fmt"
 	"log"
 	"net"
-	"os"
+	"strconv"
+	"strings"
 	"time"
 
+	"tg-anon-go/config"
 	"tg-anon-go/constants"
 
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
+// DB adalah pool Postgres jika DATABASE_DRIVER=postgres (nil jika memakai SQLite).
+// Plugin sebaiknya memakai repository (Users, Vars, Sessions, ...) daripada DB langsung.
 var DB *pgxpool.Pool
 
-// resolveIPv4 resolves hostname to IPv4 address
-func resolveIPv4(host string) (string, error) {
-	// Use Google DNS to resolve IPv4 only
-	resolver := &net.Resolver{
-		PreferGo: true,
-		Dial: func(ctx context.Context, network, address string) (net.Conn, error) {
-			d := net.Dialer{Timeout: 10 * time.Second}
-			return d.DialContext(ctx, "udp4", "8.8.8.8:53")
-		},
-	}
-
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-	defer cancel()
-
-	ips, err := resolver.LookupIP(ctx, "ip4", host)
-	if err != nil {
-		return "", fmt.Errorf("failed to resolve %s to IPv4: %v", host, err)
-	}
-
-	if len(ips) == 0 {
-		return "", fmt.Errorf("no IPv4 addresses found for %s", host)
-	}
-
-	log.Printf("🔍 Resolved %s to IPv4: %s", host, ips[0].String())
-	return ips[0].String(), nil
+// querier adalah method query yang dimiliki *pgxpool.Pool maupun pgx.Tx,
+// supaya helper yang sama bisa dipakai di dalam dan di luar transaksi
+type querier interface {
+	Exec(ctx context.Context, sql string, arguments ...any) (pgconn.CommandTag, error)
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
 }
 
-// InitDatabase menginisialisasi koneksi ke NeonDB
+// InitDatabase menginisialisasi database sesuai DATABASE_DRIVER dan memasang repository-nya
 func InitDatabase() error {
-	databaseURL := os.Getenv("DATABASE_URL")
-	if databaseURL == "" {
-		log.Fatal("DATABASE_URL environment variable is not set")
+	switch config.C.DatabaseDriver {
+	case constants.DatabaseDriverPostgres:
+		return initPostgres()
+	case constants.DatabaseDriverSQLite:
+		return initSQLite(config.C.SQLitePath)
+	default:
+		return fmt.Errorf("unknown DATABASE_DRIVER %q (expected %s or %s)",
+			config.C.DatabaseDriver, constants.DatabaseDriverPostgres, constants.DatabaseDriverSQLite)
 	}
+}
 
-	config, err := pgxpool.ParseConfig(databaseURL)
+// initPostgres menginisialisasi koneksi ke Postgres. Setiap error menyebut langkah yang gagal
+// (parse config, resolve host, connect, migrations) supaya mudah didiagnosis.
+func initPostgres() error {
+	poolConfig, err := postgresConfig(config.C.DatabaseURL)
 	if err != nil {
-		return err
+		return fmt.Errorf("parse DATABASE_URL: %v", err)
 	}
 
-	// Set pool configuration
-	config.MaxConns = 5
-	config.MinConns = 1
+	host := poolConfig.ConnConfig.Host
+	log.Printf("🔌 Connecting to Postgres at %s:%d (pool %d-%d, force IPv4: %v)...",
+		host, poolConfig.ConnConfig.Port, poolConfig.MinConns, poolConfig.MaxConns, config.C.DBForceIPv4)
 
-	// Pre-resolve the host to IPv4
-	originalHost := config.ConnConfig.Host
-	ipv4Addr, err := resolveIPv4(originalHost)
+	pool, err := connectWithRetry(poolConfig)
 	if err != nil {
-		return fmt.Errorf("failed to resolve host: %v", err)
+		return err
 	}
 
-	// Replace hostname with IPv4 address in config
-	config.ConnConfig.Host = ipv4Addr
+	DB = pool
+	usePostgres(pool)
+	log.Println("✅ Connected to Postgres successfully!")
 
-	// Force IPv4 for all connections
-	config.ConnConfig.DialFunc = func(ctx context.Context, network, addr string) (net.Conn, error) {
-		d := net.Dialer{Timeout: 30 * time.Second}
-		conn, err := d.DialContext(ctx, "tcp4", addr)
-		if err != nil {
-			log.Printf("❌ Dial error: %v", err)
-			return nil, err
-		}
-		log.Printf("✅ Connected via IPv4: %s", addr)
-		return conn, nil
+	// Run migrations
+	if err := runMigrations(context.Background(), config.C.MigrationsDryRun); err != nil {
+		return fmt.Errorf("run migrations: %v", err)
 	}
 
-	log.Printf("🔌 Connecting to NeonDB at %s...", ipv4Addr)
+	return nil
+}
 
-	pool, err := pgxpool.NewWithConfig(context.Background(), config)
+// postgresConfig membuat konfigurasi pool dari DATABASE_URL dan setting DB_*
+func postgresConfig(databaseURL string) (*pgxpool.Config, error) {
+	poolConfig, err := pgxpool.ParseConfig(databaseURL)
 	if err != nil {
-		return fmt.Errorf("failed to create pool: %v", err)
+		return nil, err
 	}
 
-	// Test connection
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
-	defer cancel()
+	poolConfig.MaxConns = int32(config.C.DBMaxConns)
+	poolConfig.MinConns = int32(config.C.DBMinConns)
+	if config.C.DBConnectTimeout > 0 {
+		poolConfig.ConnConfig.ConnectTimeout = time.Duration(config.C.DBConnectTimeout) * time.Second
+	}
+	if _, ok := poolConfig.ConnConfig.RuntimeParams["statement_timeout"]; !ok && config.C.DBStatementTimeout > 0 {
+		// statement_timeout dalam milidetik, dikirim saat koneksi dibuka (kecuali sudah diatur di DATABASE_URL)
+		poolConfig.ConnConfig.RuntimeParams["statement_timeout"] = strconv.Itoa(config.C.DBStatementTimeout * 1000)
+	}
 
-	if err := pool.Ping(ctx); err != nil {
-		return fmt.Errorf("failed to ping database: %v", err)
+	// Unix socket (host berupa path) tidak butuh DNS maupun pemilihan IPv4/IPv6
+	if strings.HasPrefix(poolConfig.ConnConfig.Host, "/") {
+		return poolConfig, nil
 	}
 
-	DB = pool
-	log.Println("✅ Connected to NeonDB successfully!")
+	resolver := net.DefaultResolver
+	if config.C.DBDNSServer != "" {
+		resolver = &net.Resolver{
+			PreferGo: true,
+			Dial: func(ctx context.Context, network, address string) (net.Conn, error) {
+				var d net.Dialer
+				return d.DialContext(ctx, network, config.C.DBDNSServer)
+			},
+		}
+	}
 
-	// Run migrations
-	if err := runMigrations(); err != nil {
-		return err
+	ipNetwork, dialNetwork := "ip", "tcp"
+	if config.C.DBForceIPv4 {
+		ipNetwork, dialNetwork = "ip4", "tcp4"
 	}
 
-	return nil
+	poolConfig.ConnConfig.LookupFunc = func(ctx context.Context, host string) ([]string, error) {
+		if net.ParseIP(host) != nil {
+			return []string{host}, nil
+		}
+		ips, err := resolver.LookupIP(ctx, ipNetwork, host)
+		if err != nil {
+			return nil, fmt.Errorf("resolve host %s: %w", host, err)
+		}
+		addrs := make([]string, len(ips))
+		for i, ip := range ips {
+			addrs[i] = ip.String()
+		}
+		return addrs, nil
+	}
+
+	dialer := &net.Dialer{KeepAlive: 5 * time.Minute}
+	poolConfig.ConnConfig.DialFunc = func(ctx context.Context, network, addr string) (net.Conn, error) {
+		if network == "tcp" {
+			network = dialNetwork
+		}
+		return dialer.DialContext(ctx, network, addr)
+	}
+
+	return poolConfig, nil
 }
 
-// runMigrations menjalankan migrasi database
-func runMigrations() error {
-	ctx := context.Background()
+// connectWithRetry membuat pool dan melakukan ping, diulang dengan backoff eksponensial
+// (1s, 2s, 4s, ... maksimal 30s) hingga DB_CONNECT_RETRIES kali
+func connectWithRetry(poolConfig *pgxpool.Config) (*pgxpool.Pool, error) {
+	attempts := config.C.DBConnectRetries + 1
+	backoff := time.Second
 
-	// Create users table
-	if _, err := DB.Exec(ctx, constants.QueryCreateUsersTable); err != nil {
-		log.Printf("Error creating users table: %v", err)
-		return err
-	}
+	var lastErr error
+	for attempt := 1; attempt <= attempts; attempt++ {
+		pool, err := pgxpool.NewWithConfig(context.Background(), poolConfig)
+		if err != nil {
+			// Config tidak valid, tidak ada gunanya diulang
+			return nil, fmt.Errorf("create pool: %v", err)
+		}
 
-	// Create sessions table
-	if _, err := DB.Exec(ctx, constants.QueryCreateSessionsTable); err != nil {
-		log.Printf("Error creating sessions table: %v", err)
-		return err
-	}
-	// Create messages table
-	if _, err := DB.Exec(ctx, constants.QueryCreateMessagesTable); err != nil {
-		log.Printf("Error creating messages table: %v", err)
-		return err
-	}
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		err = pool.Ping(ctx)
+		cancel()
+		if err == nil {
+			return pool, nil
+		}
+		pool.Close()
 
-	// Create vars table
-	if _, err := DB.Exec(ctx, constants.QueryCreateVarsTable); err != nil {
-		log.Printf("Error creating vars table: %v", err)
-		return err
+		lastErr = fmt.Errorf("connect to %s (attempt %d/%d): %v", poolConfig.ConnConfig.Host, attempt, attempts, err)
+		if attempt == attempts {
+			break
+		}
+		log.Printf("⚠️ %v, retrying in %s", lastErr, backoff)
+		time.Sleep(backoff)
+		if backoff *= 2; backoff > 30*time.Second {
+			backoff = 30 * time.Second
+		}
 	}
-
-	log.Println("✅ Database migrations completed")
-	return nil
+	return nil, lastErr
 }
 
 // CloseDatabase menutup koneksi database
@@ -143,4 +176,8 @@ func CloseDatabase() {
 		DB.Close()
 		log.Println("Database connection closed")
 	}
+	if SQLite != nil {
+		SQLite.Close()
+		log.Println("SQLite database closed")
+	}
 }
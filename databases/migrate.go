@@ -0,0 +1,204 @@
+package databases
+
+import (
+	"context"
+	"embed"
+	"fmt"
+	"io/fs"
+	"log"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+
+	"tg-anon-go/constants"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// File migrasi bernama NNNN_nama.sql dan dijalankan berurutan berdasarkan NNNN
+//
+//go:embed migrations/*.sql
+var migrationFiles embed.FS
+
+// baselineVersion adalah versi yang mewakili skema lama (sebelum schema_migrations ada)
+const baselineVersion = 1
+
+// Migration adalah satu file migrasi yang sudah di-parse
+type Migration struct {
+	Version int
+	Name    string
+	SQL     string
+}
+
+// loadMigrations membaca semua file migrasi yang di-embed, urut berdasarkan versi
+func loadMigrations() ([]Migration, error) {
+	return readMigrations(migrationFiles)
+}
+
+// readMigrations membaca file migrasi dari direktori migrations di fsys, urut berdasarkan versi
+func readMigrations(fsys fs.FS) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, "migrations")
+	if err != nil {
+		return nil, err
+	}
+
+	var migrations []Migration
+	seen := make(map[int]string)
+	for _, entry := range entries {
+		if entry.IsDir() || path.Ext(entry.Name()) != ".sql" {
+			continue
+		}
+
+		name := strings.TrimSuffix(entry.Name(), ".sql")
+		prefix, _, _ := strings.Cut(name, "_")
+		version, err := strconv.Atoi(prefix)
+		if err != nil || version < 1 {
+			return nil, fmt.Errorf("invalid migration file name %q (expected NNNN_name.sql)", entry.Name())
+		}
+		if other, exists := seen[version]; exists {
+			return nil, fmt.Errorf("duplicate migration version %d: %s and %s", version, other, entry.Name())
+		}
+		seen[version] = entry.Name()
+
+		content, err := fs.ReadFile(fsys, path.Join("migrations", entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+		migrations = append(migrations, Migration{Version: version, Name: name, SQL: string(content)})
+	}
+
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+	return migrations, nil
+}
+
+// runMigrations menjalankan migrasi yang belum tercatat di schema_migrations.
+// Advisory lock memastikan instance lain menunggu sampai migrasi selesai.
+// Jika dryRun true, migrasi hanya ditampilkan tanpa dijalankan.
+func runMigrations(ctx context.Context, dryRun bool) error {
+	migrations, err := loadMigrations()
+	if err != nil {
+		return err
+	}
+
+	conn, err := DB.Acquire(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to acquire connection for migrations: %w", err)
+	}
+	defer conn.Release()
+
+	// Menunggu lock dan migrasi besar bisa lebih lama dari DB_STATEMENT_TIMEOUT, jadi batasnya
+	// dimatikan di koneksi ini lalu dikembalikan sebelum koneksi kembali ke pool
+	if _, err := conn.Exec(ctx, constants.QueryDisableStatementTimeout); err != nil {
+		return fmt.Errorf("failed to disable statement timeout for migrations: %w", err)
+	}
+	defer func() {
+		if _, err := conn.Exec(context.Background(), constants.QueryResetStatementTimeout); err != nil {
+			log.Printf("Error resetting statement timeout: %v", err)
+		}
+	}()
+
+	// Advisory lock bersifat per sesi, jadi semua query harus lewat koneksi yang sama
+	if _, err := conn.Exec(ctx, constants.QueryAdvisoryLock, constants.MigrationLockID); err != nil {
+		return fmt.Errorf("failed to acquire migration lock: %w", err)
+	}
+	defer func() {
+		if _, err := conn.Exec(context.Background(), constants.QueryAdvisoryUnlock, constants.MigrationLockID); err != nil {
+			log.Printf("Error releasing migration lock: %v", err)
+		}
+	}()
+
+	applied, err := appliedMigrations(ctx, conn, dryRun)
+	if err != nil {
+		return err
+	}
+
+	// Deployment lama sudah punya tabel users tapi belum punya schema_migrations
+	if len(applied) == 0 {
+		var legacy bool
+		if err := conn.QueryRow(ctx, constants.QueryTableExists, constants.TableUsers).Scan(&legacy); err != nil {
+			return fmt.Errorf("failed to detect existing schema: %w", err)
+		}
+		if legacy {
+			log.Printf("🗄️ Existing schema detected, treating it as baseline version %d", baselineVersion)
+		}
+	}
+
+	pending := 0
+	for _, m := range migrations {
+		if applied[m.Version] {
+			continue
+		}
+		pending++
+
+		if dryRun {
+			log.Printf("📝 [dry-run] Would apply migration %s", m.Name)
+			continue
+		}
+		if err := applyMigration(ctx, conn, m); err != nil {
+			return err
+		}
+		log.Printf("✅ Applied migration %s", m.Name)
+	}
+
+	switch {
+	case dryRun:
+		log.Printf("📝 [dry-run] %d pending migration(s), database not modified", pending)
+	case pending == 0:
+		log.Println("✅ Database schema is up to date")
+	default:
+		log.Printf("✅ Database migrations completed (%d applied)", pending)
+	}
+	return nil
+}
+
+// appliedMigrations mengambil versi migrasi yang sudah dijalankan
+func appliedMigrations(ctx context.Context, conn *pgxpool.Conn, dryRun bool) (map[int]bool, error) {
+	applied := make(map[int]bool)
+
+	if dryRun {
+		// Jangan membuat tabel apapun saat dry-run
+		var exists bool
+		if err := conn.QueryRow(ctx, constants.QueryTableExists, constants.TableSchemaMigrations).Scan(&exists); err != nil {
+			return nil, err
+		}
+		if !exists {
+			return applied, nil
+		}
+	} else if _, err := conn.Exec(ctx, constants.QueryCreateSchemaMigrationsTable); err != nil {
+		return nil, fmt.Errorf("failed to create %s table: %w", constants.TableSchemaMigrations, err)
+	}
+
+	rows, err := conn.Query(ctx, constants.QuerySelectMigrationVersions)
+	if err != nil {
+		return nil, err
+	}
+	versions, err := pgx.CollectRows(rows, pgx.RowTo[int])
+	if err != nil {
+		return nil, err
+	}
+	for _, v := range versions {
+		applied[v] = true
+	}
+	return applied, nil
+}
+
+// applyMigration menjalankan satu migrasi dan mencatat versinya dalam satu transaksi
+func applyMigration(ctx context.Context, conn *pgxpool.Conn, m Migration) error {
+	tx, err := conn.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, m.SQL); err != nil {
+		return fmt.Errorf("migration %s failed: %w", m.Name, err)
+	}
+	if _, err := tx.Exec(ctx, constants.QueryInsertMigration, m.Version, m.Name); err != nil {
+		return fmt.Errorf("failed to record migration %s: %w", m.Name, err)
+	}
+	return tx.Commit(ctx)
+}
@@ -0,0 +1,84 @@
+package databases
+
+import (
+	"io/fs"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestReadMigrations(t *testing.T) {
+	tests := []struct {
+		name         string
+		files        []string
+		wantVersions []int
+		wantErr      string // "" = tidak error
+	}{
+		{
+			name:         "sorted by version, not by name",
+			files:        []string{"0010_ten.sql", "0002_two.sql", "0001_one.sql"},
+			wantVersions: []int{1, 2, 10},
+		},
+		{
+			name:         "non-sql files ignored",
+			files:        []string{"0001_one.sql", "README.md", "0002_two.sql.bak"},
+			wantVersions: []int{1},
+		},
+		{
+			name:         "name without description",
+			files:        []string{"0003.sql"},
+			wantVersions: []int{3},
+		},
+		{name: "empty directory", files: nil, wantVersions: nil},
+		{name: "non-numeric prefix", files: []string{"init_schema.sql"}, wantErr: "invalid migration file name"},
+		{name: "version zero", files: []string{"0000_zero.sql"}, wantErr: "invalid migration file name"},
+		{name: "duplicate version", files: []string{"0001_a.sql", "1_b.sql"}, wantErr: "duplicate migration version 1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fsys := fstest.MapFS{"migrations": &fstest.MapFile{Mode: fs.ModeDir | 0o755}}
+			for _, name := range tt.files {
+				fsys["migrations/"+name] = &fstest.MapFile{Data: []byte("-- " + name)}
+			}
+
+			migrations, err := readMigrations(fsys)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("readMigrations() = %v, want error containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("readMigrations() = %v", err)
+			}
+
+			var versions []int
+			for _, m := range migrations {
+				versions = append(versions, m.Version)
+				if want := "-- " + m.Name + ".sql"; m.SQL != want {
+					t.Errorf("migration %d SQL = %q, want %q", m.Version, m.SQL, want)
+				}
+			}
+			if !reflect.DeepEqual(versions, tt.wantVersions) {
+				t.Errorf("versions = %v, want %v", versions, tt.wantVersions)
+			}
+		})
+	}
+}
+
+func TestEmbeddedMigrations(t *testing.T) {
+	migrations, err := loadMigrations()
+	if err != nil {
+		t.Fatalf("loadMigrations() = %v", err)
+	}
+	if len(migrations) == 0 || migrations[0].Version != baselineVersion {
+		t.Fatalf("first embedded migration must be the baseline (version %d)", baselineVersion)
+	}
+	for i := 1; i < len(migrations); i++ {
+		if migrations[i].Version <= migrations[i-1].Version {
+			t.Errorf("migration %s out of order after %s", migrations[i].Name, migrations[i-1].Name)
+		}
+	}
+}
@@ -0,0 +1,92 @@
+-- Baseline: skema yang sebelumnya dibuat oleh runMigrations (CREATE ... IF NOT EXISTS).
+-- Semua statement idempotent sehingga aman dijalankan ulang pada deployment lama.
+
+CREATE TABLE IF NOT EXISTS users (
+	id SERIAL PRIMARY KEY,
+	telegram_id BIGINT UNIQUE NOT NULL,
+	username VARCHAR(255),
+	first_name VARCHAR(255),
+	status VARCHAR(50) DEFAULT 'idle',
+	partner_id BIGINT DEFAULT NULL,
+	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS idx_users_telegram_id ON users(telegram_id);
+CREATE INDEX IF NOT EXISTS idx_users_status ON users(status);
+
+CREATE TABLE IF NOT EXISTS chat_sessions (
+	id SERIAL PRIMARY KEY,
+	user1_id BIGINT NOT NULL,
+	user2_id BIGINT NOT NULL,
+	started_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	ended_at TIMESTAMP DEFAULT NULL,
+	is_active BOOLEAN DEFAULT TRUE
+);
+CREATE INDEX IF NOT EXISTS idx_sessions_active ON chat_sessions(is_active);
+
+CREATE TABLE IF NOT EXISTS messages (
+	id SERIAL PRIMARY KEY,
+	session_id INT REFERENCES chat_sessions(id),
+	sender_id BIGINT NOT NULL,
+	receiver_id BIGINT NOT NULL,
+	message_type VARCHAR(50) DEFAULT 'text',
+	content TEXT,
+	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS vars (
+	id SERIAL PRIMARY KEY,
+	user_id BIGINT NOT NULL,
+	var_key VARCHAR(255) NOT NULL,
+	var_value TEXT,
+	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	UNIQUE(user_id, var_key)
+);
+CREATE INDEX IF NOT EXISTS idx_vars_user_key ON vars(user_id, var_key);
+
+CREATE TABLE IF NOT EXISTS session_ratings (
+	id SERIAL PRIMARY KEY,
+	session_id INT NOT NULL REFERENCES chat_sessions(id),
+	rater_id BIGINT NOT NULL,
+	ratee_id BIGINT NOT NULL,
+	score SMALLINT NOT NULL,
+	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	UNIQUE(session_id, rater_id)
+);
+CREATE INDEX IF NOT EXISTS idx_ratings_ratee ON session_ratings(ratee_id);
+
+-- Kolom aktivitas sesi untuk auto-close berdasarkan pesan terakhir
+ALTER TABLE chat_sessions ADD COLUMN IF NOT EXISTS last_message_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP;
+ALTER TABLE chat_sessions ADD COLUMN IF NOT EXISTS idle_warned_at TIMESTAMP DEFAULT NULL;
+ALTER TABLE chat_sessions ADD COLUMN IF NOT EXISTS end_reason VARCHAR(50) DEFAULT NULL;
+CREATE INDEX IF NOT EXISTS idx_sessions_last_message ON chat_sessions(is_active, last_message_at);
+
+-- Kolom analytics sesi: waktu tunggu, mode match, jumlah pesan dan siapa yang mengakhiri
+ALTER TABLE chat_sessions ADD COLUMN IF NOT EXISTS match_mode VARCHAR(20) DEFAULT NULL;
+ALTER TABLE chat_sessions ADD COLUMN IF NOT EXISTS distance_km DOUBLE PRECISION DEFAULT NULL;
+ALTER TABLE chat_sessions ADD COLUMN IF NOT EXISTS user1_wait_seconds INT DEFAULT NULL;
+ALTER TABLE chat_sessions ADD COLUMN IF NOT EXISTS user2_wait_seconds INT DEFAULT NULL;
+ALTER TABLE chat_sessions ADD COLUMN IF NOT EXISTS user1_messages INT DEFAULT 0;
+ALTER TABLE chat_sessions ADD COLUMN IF NOT EXISTS user2_messages INT DEFAULT 0;
+ALTER TABLE chat_sessions ADD COLUMN IF NOT EXISTS ended_by BIGINT DEFAULT NULL;
+CREATE INDEX IF NOT EXISTS idx_sessions_user1 ON chat_sessions(user1_id);
+CREATE INDEX IF NOT EXISTS idx_sessions_user2 ON chat_sessions(user2_id);
+CREATE INDEX IF NOT EXISTS idx_sessions_started ON chat_sessions(started_at);
+
+CREATE TABLE IF NOT EXISTS daily_active_users (
+	day DATE NOT NULL,
+	user_id BIGINT NOT NULL,
+	PRIMARY KEY (day, user_id)
+);
+
+CREATE TABLE IF NOT EXISTS daily_stats (
+	day DATE PRIMARY KEY,
+	new_users INT DEFAULT 0,
+	dau INT DEFAULT 0,
+	matches INT DEFAULT 0,
+	median_wait_seconds DOUBLE PRECISION DEFAULT 0,
+	avg_session_seconds DOUBLE PRECISION DEFAULT 0,
+	messages BIGINT DEFAULT 0,
+	updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
@@ -0,0 +1,111 @@
+-- Data user dipindah dari tabel EAV vars ke tabel bertipe supaya bisa di-index dan di-query langsung.
+-- vars hanya dipakai untuk key ad-hoc (variabel global, state flow, dll).
+
+CREATE TABLE IF NOT EXISTS user_profiles (
+	user_id BIGINT PRIMARY KEY,
+	name VARCHAR(255),
+	age SMALLINT,
+	gender VARCHAR(20),
+	location VARCHAR(255),
+	latitude DOUBLE PRECISION,
+	longitude DOUBLE PRECISION,
+	interests TEXT,
+	card_fields VARCHAR(255),
+	search_mode VARCHAR(20),
+	is_registered BOOLEAN NOT NULL DEFAULT FALSE,
+	registered_at TIMESTAMP DEFAULT NULL,
+	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS idx_user_profiles_gender_age ON user_profiles(gender, age);
+CREATE INDEX IF NOT EXISTS idx_user_profiles_age ON user_profiles(age);
+CREATE INDEX IF NOT EXISTS idx_user_profiles_registered_at ON user_profiles(registered_at);
+
+CREATE TABLE IF NOT EXISTS user_state (
+	user_id BIGINT PRIMARY KEY,
+	status VARCHAR(20) NOT NULL DEFAULT 'idle',
+	partner_id BIGINT DEFAULT NULL,
+	session_id BIGINT DEFAULT NULL,
+	search_started_at TIMESTAMP DEFAULT NULL,
+	warn_count INT NOT NULL DEFAULT 0,
+	is_banned BOOLEAN NOT NULL DEFAULT FALSE,
+	is_inactive BOOLEAN NOT NULL DEFAULT FALSE,
+	low_rep_flag BOOLEAN NOT NULL DEFAULT FALSE,
+	last_active TIMESTAMP DEFAULT NULL,
+	updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS idx_user_state_status ON user_state(status);
+CREATE INDEX IF NOT EXISTS idx_user_state_searching ON user_state(search_started_at) WHERE status = 'searching';
+
+CREATE TABLE IF NOT EXISTS user_stats (
+	user_id BIGINT PRIMARY KEY,
+	total_chats INT NOT NULL DEFAULT 0,
+	total_messages INT NOT NULL DEFAULT 0,
+	reputation DOUBLE PRECISION DEFAULT NULL,
+	rating_count INT NOT NULL DEFAULT 0,
+	ads_counter INT NOT NULL DEFAULT 0,
+	updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Salin data dari vars. Nilai yang tidak valid untuk tipe kolom diabaikan (NULL/default).
+INSERT INTO user_profiles (user_id, name, age, gender, location, latitude, longitude,
+	interests, card_fields, search_mode, is_registered, registered_at)
+SELECT
+	user_id,
+	MAX(var_value) FILTER (WHERE var_key = 'name'),
+	MAX(CASE WHEN var_key = 'age' AND var_value ~ '^[0-9]{1,3}$' THEN var_value::SMALLINT END),
+	MAX(var_value) FILTER (WHERE var_key = 'gender'),
+	MAX(var_value) FILTER (WHERE var_key = 'location'),
+	MAX(CASE WHEN var_key = 'latitude' AND var_value ~ '^-?[0-9]+(\.[0-9]+)?$' THEN var_value::DOUBLE PRECISION END),
+	MAX(CASE WHEN var_key = 'longitude' AND var_value ~ '^-?[0-9]+(\.[0-9]+)?$' THEN var_value::DOUBLE PRECISION END),
+	MAX(var_value) FILTER (WHERE var_key = 'interests'),
+	MAX(var_value) FILTER (WHERE var_key = 'card_fields'),
+	MAX(var_value) FILTER (WHERE var_key = 'search_mode'),
+	COALESCE(BOOL_OR(var_key = 'is_registered' AND var_value = 'true'), FALSE),
+	MIN(created_at) FILTER (WHERE var_key = 'is_registered' AND var_value = 'true')
+FROM vars
+WHERE user_id <> 0 AND var_key IN ('name', 'age', 'gender', 'location', 'latitude', 'longitude',
+	'interests', 'card_fields', 'search_mode', 'is_registered')
+GROUP BY user_id
+ON CONFLICT (user_id) DO NOTHING;
+
+INSERT INTO user_state (user_id, status, partner_id, session_id, search_started_at,
+	warn_count, is_banned, is_inactive, low_rep_flag, last_active)
+SELECT
+	user_id,
+	COALESCE(MAX(var_value) FILTER (WHERE var_key = 'status'), 'idle'),
+	MAX(CASE WHEN var_key = 'partner_id' AND var_value ~ '^-?[0-9]+$' THEN var_value::BIGINT END),
+	MAX(CASE WHEN var_key = 'session_id' AND var_value ~ '^[0-9]+$' THEN var_value::BIGINT END),
+	MAX(CASE WHEN var_key = 'search_started_at' AND var_value ~ '^[0-9]+$' THEN to_timestamp(var_value::BIGINT)::TIMESTAMP END),
+	COALESCE(MAX(CASE WHEN var_key = 'warn_count' AND var_value ~ '^[0-9]+$' THEN var_value::INT END), 0),
+	COALESCE(BOOL_OR(var_key = 'is_banned' AND var_value = 'true'), FALSE),
+	COALESCE(BOOL_OR(var_key = 'is_inactive' AND var_value = 'true'), FALSE),
+	COALESCE(BOOL_OR(var_key = 'low_rep_flag' AND var_value = 'true'), FALSE),
+	MAX(CASE WHEN var_key = 'last_active' AND var_value ~ '^[0-9]+$' THEN to_timestamp(var_value::BIGINT)::TIMESTAMP END)
+FROM vars
+WHERE user_id <> 0 AND var_key IN ('status', 'partner_id', 'session_id', 'search_started_at',
+	'warn_count', 'is_banned', 'is_inactive', 'low_rep_flag', 'last_active')
+GROUP BY user_id
+ON CONFLICT (user_id) DO NOTHING;
+
+INSERT INTO user_stats (user_id, total_chats, total_messages, reputation, rating_count, ads_counter)
+SELECT
+	user_id,
+	COALESCE(MAX(CASE WHEN var_key = 'total_chats' AND var_value ~ '^[0-9]+$' THEN var_value::INT END), 0),
+	COALESCE(MAX(CASE WHEN var_key = 'total_messages' AND var_value ~ '^[0-9]+$' THEN var_value::INT END), 0),
+	MAX(CASE WHEN var_key = 'reputation' AND var_value ~ '^-?[0-9]+(\.[0-9]+)?$' THEN var_value::DOUBLE PRECISION END),
+	COALESCE(MAX(CASE WHEN var_key = 'rating_count' AND var_value ~ '^[0-9]+$' THEN var_value::INT END), 0),
+	COALESCE(MAX(CASE WHEN var_key = 'msg_count_ads' AND var_value ~ '^[0-9]+$' THEN var_value::INT END), 0)
+FROM vars
+WHERE user_id <> 0 AND var_key IN ('total_chats', 'total_messages', 'reputation', 'rating_count', 'msg_count_ads')
+GROUP BY user_id
+ON CONFLICT (user_id) DO NOTHING;
+
+-- Key yang sudah pindah ke tabel bertipe tidak dipakai lagi
+DELETE FROM vars
+WHERE user_id <> 0 AND var_key IN (
+	'name', 'age', 'gender', 'location', 'latitude', 'longitude', 'interests', 'card_fields',
+	'search_mode', 'is_registered', 'status', 'partner_id', 'session_id', 'search_started_at',
+	'warn_count', 'is_banned', 'is_inactive', 'low_rep_flag', 'last_active', 'total_chats',
+	'total_messages', 'reputation', 'rating_count', 'msg_count_ads'
+);
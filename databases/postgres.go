@@ -0,0 +1,666 @@
+package databases
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"tg-anon-go/cache"
+	"tg-anon-go/constants"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// usePostgres memasang repository Postgres di atas pool
+func usePostgres(pool *pgxpool.Pool) {
+	Users = pgUsers{pool}
+	Vars = pgVars{pool}
+	Sessions = pgSessions{pool}
+	Messages = pgMessages{pool}
+	Ads = varAds{Vars}
+}
+
+// ============================================================
+// USERS
+// ============================================================
+
+type pgUsers struct {
+	db *pgxpool.Pool
+}
+
+func (r pgUsers) Save(ctx context.Context, telegramID int64, username, firstName string) error {
+	query := `
+		INSERT INTO users (telegram_id, username, first_name, status, updated_at)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (telegram_id)
+		DO UPDATE SET username = $2, first_name = $3, updated_at = $5
+	`
+	_, err := r.db.Exec(ctx, query, telegramID, username, firstName, constants.StatusIdle, time.Now())
+	return err
+}
+
+func (r pgUsers) Count(ctx context.Context) (int64, error) {
+	var count int64
+	err := r.db.QueryRow(ctx, "SELECT COUNT(*) FROM users").Scan(&count)
+	return count, err
+}
+
+func (r pgUsers) ActiveIDs(ctx context.Context) ([]int64, error) {
+	query := `
+		SELECT u.telegram_id FROM users u
+		LEFT JOIN user_state s ON s.user_id = u.telegram_id
+		WHERE s.is_inactive IS NOT TRUE
+	`
+	rows, err := r.db.Query(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, pgx.RowTo[int64])
+}
+
+func (r pgUsers) CountByStatus(ctx context.Context, status string) (int, error) {
+	var count int
+	err := r.db.QueryRow(ctx, `SELECT COUNT(*) FROM user_state WHERE status = $1`, status).Scan(&count)
+	return count, err
+}
+
+func (r pgUsers) SearchingIDs(ctx context.Context, olderThan time.Duration) ([]int64, error) {
+	query := `
+		SELECT user_id FROM user_state
+		WHERE status = $1 AND (search_started_at IS NULL OR search_started_at < $2)
+	`
+	rows, err := r.db.Query(ctx, query, constants.StatusSearching, time.Now().Add(-olderThan))
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, pgx.RowTo[int64])
+}
+
+func (r pgUsers) BrokenPartnerLinks(ctx context.Context) ([]PartnerLink, error) {
+	query := `
+		SELECT a.user_id, COALESCE(a.partner_id, 0)
+		FROM user_state a
+		LEFT JOIN user_state b ON b.user_id = a.partner_id
+		WHERE a.status = $1
+		AND (a.partner_id IS NULL OR b.user_id IS NULL
+			OR b.status <> $1 OR b.partner_id IS DISTINCT FROM a.user_id)
+	`
+	rows, err := r.db.Query(ctx, query, constants.StatusChatting)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var links []PartnerLink
+	for rows.Next() {
+		var link PartnerLink
+		if err := rows.Scan(&link.UserID, &link.PartnerID); err != nil {
+			return nil, err
+		}
+		links = append(links, link)
+	}
+	return links, rows.Err()
+}
+
+func (r pgUsers) Profile(ctx context.Context, userID int64) (*UserProfile, error) {
+	query := `
+		SELECT COALESCE(name, ''), COALESCE(age, 0), COALESCE(gender, ''), COALESCE(location, ''),
+			COALESCE(latitude, 0), COALESCE(longitude, 0), COALESCE(interests, ''),
+			COALESCE(card_fields, ''), COALESCE(search_mode, ''), is_registered, registered_at
+		FROM user_profiles WHERE user_id = $1
+	`
+	profile := &UserProfile{UserID: userID}
+	err := r.db.QueryRow(ctx, query, userID).Scan(
+		&profile.Name, &profile.Age, &profile.Gender, &profile.Location,
+		&profile.Latitude, &profile.Longitude, &profile.Interests,
+		&profile.CardFields, &profile.SearchMode, &profile.IsRegistered, &profile.RegisteredAt,
+	)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return profile, nil
+	}
+	return profile, err
+}
+
+func (r pgUsers) State(ctx context.Context, userID int64) (*UserState, error) {
+	query := `
+		SELECT status, COALESCE(partner_id, 0), COALESCE(session_id, 0), search_started_at,
+			warn_count, is_banned, is_inactive, low_rep_flag, last_active
+		FROM user_state WHERE user_id = $1
+	`
+	state := &UserState{UserID: userID, Status: constants.StatusIdle}
+	err := r.db.QueryRow(ctx, query, userID).Scan(
+		&state.Status, &state.PartnerID, &state.SessionID, &state.SearchStartedAt,
+		&state.WarnCount, &state.IsBanned, &state.IsInactive, &state.LowRepFlag, &state.LastActive,
+	)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return state, nil
+	}
+	return state, err
+}
+
+func (r pgUsers) Stats(ctx context.Context, userID int64) (*UserStats, error) {
+	query := `
+		SELECT total_chats, total_messages, COALESCE(reputation, $2), rating_count, ads_counter
+		FROM user_stats WHERE user_id = $1
+	`
+	stats := &UserStats{UserID: userID, Reputation: DefaultReputation}
+	err := r.db.QueryRow(ctx, query, userID, DefaultReputation).Scan(
+		&stats.TotalChats, &stats.TotalMessages, &stats.Reputation, &stats.RatingCount, &stats.AdsCounter,
+	)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return stats, nil
+	}
+	return stats, err
+}
+
+// Update menyimpan beberapa kolom user (upsert).
+// Nama tabel dan kolom hanya berasal dari package ini, bukan dari input user.
+func (r pgUsers) Update(ctx context.Context, table string, userID int64, columns []string, values ...interface{}) error {
+	placeholders := make([]string, len(columns))
+	updates := make([]string, len(columns))
+	for i, column := range columns {
+		placeholders[i] = fmt.Sprintf("$%d", i+2)
+		updates[i] = fmt.Sprintf("%s = EXCLUDED.%s", column, column)
+	}
+
+	query := fmt.Sprintf(`
+		INSERT INTO %s (user_id, %s, updated_at)
+		VALUES ($1, %s, CURRENT_TIMESTAMP)
+		ON CONFLICT (user_id)
+		DO UPDATE SET %s, updated_at = CURRENT_TIMESTAMP
+	`, table, strings.Join(columns, ", "), strings.Join(placeholders, ", "), strings.Join(updates, ", "))
+
+	args := append([]interface{}{userID}, values...)
+	_, err := r.db.Exec(ctx, query, args...)
+	return err
+}
+
+func (r pgUsers) Increment(ctx context.Context, table string, userID int64, column string) (int, error) {
+	return pgIncrement(ctx, r.db, table, userID, column)
+}
+
+// pgIncrement menambah satu counter user dan mengembalikan nilai barunya
+func pgIncrement(ctx context.Context, q querier, table string, userID int64, column string) (int, error) {
+	query := fmt.Sprintf(`
+		INSERT INTO %[1]s (user_id, %[2]s, updated_at)
+		VALUES ($1, 1, CURRENT_TIMESTAMP)
+		ON CONFLICT (user_id)
+		DO UPDATE SET %[2]s = %[1]s.%[2]s + 1, updated_at = CURRENT_TIMESTAMP
+		RETURNING %[2]s
+	`, table, column)
+
+	var value int
+	err := q.QueryRow(ctx, query, userID).Scan(&value)
+	return value, err
+}
+
+func (r pgUsers) MarkRegistered(ctx context.Context, userID int64) error {
+	query := `
+		INSERT INTO user_profiles (user_id, is_registered, registered_at, updated_at)
+		VALUES ($1, TRUE, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP)
+		ON CONFLICT (user_id)
+		DO UPDATE SET is_registered = TRUE,
+			registered_at = COALESCE(user_profiles.registered_at, CURRENT_TIMESTAMP),
+			updated_at = CURRENT_TIMESTAMP
+	`
+	_, err := r.db.Exec(ctx, query, userID)
+	return err
+}
+
+func (r pgUsers) ApplyCounters(ctx context.Context, deltas []cache.CounterDelta) error {
+	var msgUsers, adsUsers, messages, ads []int64
+	for _, delta := range deltas {
+		if delta.TotalMessages > 0 {
+			msgUsers = append(msgUsers, delta.UserID)
+			messages = append(messages, delta.TotalMessages)
+		}
+		if delta.HasAds {
+			adsUsers = append(adsUsers, delta.UserID)
+			ads = append(ads, delta.AdsCounter)
+		}
+	}
+
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if len(msgUsers) > 0 {
+		query := `
+			INSERT INTO user_stats (user_id, total_messages, updated_at)
+			SELECT t.user_id, t.messages, CURRENT_TIMESTAMP
+			FROM unnest($1::BIGINT[], $2::BIGINT[]) AS t(user_id, messages)
+			ON CONFLICT (user_id)
+			DO UPDATE SET total_messages = user_stats.total_messages + EXCLUDED.total_messages,
+				updated_at = CURRENT_TIMESTAMP
+		`
+		if _, err := tx.Exec(ctx, query, msgUsers, messages); err != nil {
+			return err
+		}
+	}
+
+	if len(adsUsers) > 0 {
+		query := `
+			INSERT INTO user_stats (user_id, ads_counter, updated_at)
+			SELECT t.user_id, t.ads, CURRENT_TIMESTAMP
+			FROM unnest($1::BIGINT[], $2::BIGINT[]) AS t(user_id, ads)
+			ON CONFLICT (user_id)
+			DO UPDATE SET ads_counter = EXCLUDED.ads_counter, updated_at = CURRENT_TIMESTAMP
+		`
+		if _, err := tx.Exec(ctx, query, adsUsers, ads); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit(ctx)
+}
+
+func (r pgUsers) Reset(ctx context.Context) error {
+	if _, err := r.db.Exec(ctx, "TRUNCATE TABLE user_profiles, user_state, user_stats"); err != nil {
+		return err
+	}
+	_, err := r.db.Exec(ctx, "UPDATE users SET status = 'idle', partner_id = NULL")
+	return err
+}
+
+// ============================================================
+// VARS
+// ============================================================
+
+type pgVars struct {
+	db *pgxpool.Pool
+}
+
+func (r pgVars) Set(ctx context.Context, userID int64, key, value string) error {
+	query := `
+		INSERT INTO vars (user_id, var_key, var_value, updated_at)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (user_id, var_key)
+		DO UPDATE SET var_value = $3, updated_at = $4
+	`
+	_, err := r.db.Exec(ctx, query, userID, key, value, time.Now())
+	return err
+}
+
+func (r pgVars) Get(ctx context.Context, userID int64, key string) (string, error) {
+	var value string
+	err := r.db.QueryRow(ctx, `SELECT var_value FROM vars WHERE user_id = $1 AND var_key = $2`, userID, key).Scan(&value)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return "", nil
+	}
+	return value, err
+}
+
+func (r pgVars) Delete(ctx context.Context, userID int64, key string) error {
+	_, err := r.db.Exec(ctx, `DELETE FROM vars WHERE user_id = $1 AND var_key = $2`, userID, key)
+	return err
+}
+
+func (r pgVars) DeleteAll(ctx context.Context, userID int64) error {
+	_, err := r.db.Exec(ctx, `DELETE FROM vars WHERE user_id = $1`, userID)
+	return err
+}
+
+func (r pgVars) All(ctx context.Context, userID int64) (map[string]string, error) {
+	rows, err := r.db.Query(ctx, `SELECT var_key, var_value FROM vars WHERE user_id = $1`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	vars := make(map[string]string)
+	for rows.Next() {
+		var key, value string
+		if err := rows.Scan(&key, &value); err != nil {
+			return nil, err
+		}
+		vars[key] = value
+	}
+	return vars, rows.Err()
+}
+
+func (r pgVars) Reset(ctx context.Context) error {
+	_, err := r.db.Exec(ctx, "TRUNCATE TABLE vars CASCADE")
+	return err
+}
+
+// ============================================================
+// SESSIONS
+// ============================================================
+
+type pgSessions struct {
+	db *pgxpool.Pool
+}
+
+// Connect mengunci baris user_state kedua user (FOR UPDATE) sehingga dua matcher
+// tidak bisa memasangkan user yang sama ke dua partner
+func (r pgSessions) Connect(ctx context.Context, user1ID, user2ID int64, matchMode string, distanceKm float64) (int64, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback(ctx)
+
+	states, err := lockUserStates(ctx, tx, user1ID, user2ID)
+	if err != nil {
+		return 0, err
+	}
+	for _, id := range []int64{user1ID, user2ID} {
+		if states[id].Status != constants.StatusSearching {
+			return 0, fmt.Errorf("user %d: %w", id, ErrUserUnavailable)
+		}
+	}
+
+	// Create session
+	sessionID, err := pgCreateSession(ctx, tx, user1ID, user2ID, matchMode, distanceKm,
+		states[user1ID].waitSeconds(), states[user2ID].waitSeconds())
+	if err != nil {
+		return 0, err
+	}
+
+	// Set status, partner dan sesi untuk kedua user
+	query := `
+		UPDATE user_state
+		SET status = $2, partner_id = $3, session_id = $4, search_started_at = NULL, updated_at = CURRENT_TIMESTAMP
+		WHERE user_id = $1
+	`
+	if _, err := tx.Exec(ctx, query, user1ID, constants.StatusChatting, user2ID, sessionID); err != nil {
+		return 0, err
+	}
+	if _, err := tx.Exec(ctx, query, user2ID, constants.StatusChatting, user1ID, sessionID); err != nil {
+		return 0, err
+	}
+
+	// Increment total chats
+	if _, err := pgIncrement(ctx, tx, constants.TableUserStats, user1ID, "total_chats"); err != nil {
+		return 0, err
+	}
+	if _, err := pgIncrement(ctx, tx, constants.TableUserStats, user2ID, "total_chats"); err != nil {
+		return 0, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return 0, err
+	}
+	return sessionID, nil
+}
+
+func (r pgSessions) Disconnect(ctx context.Context, user1ID, user2ID, endedBy int64, reason string) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := lockUserStates(ctx, tx, user1ID, user2ID); err != nil {
+		return err
+	}
+
+	// End session di database
+	if err := pgEndSession(ctx, tx, user1ID, user2ID, endedBy, reason); err != nil {
+		return err
+	}
+
+	// Reset status, partner dan sesi untuk kedua user
+	query := `
+		UPDATE user_state
+		SET status = $3, partner_id = NULL, session_id = NULL, updated_at = CURRENT_TIMESTAMP
+		WHERE user_id = $1 AND (partner_id = $2 OR (partner_id IS NULL AND status = $4))
+	`
+	if _, err := tx.Exec(ctx, query, user1ID, user2ID, constants.StatusIdle, constants.StatusChatting); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(ctx, query, user2ID, user1ID, constants.StatusIdle, constants.StatusChatting); err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+// lockedState adalah baris user_state yang sedang dikunci di dalam transaksi
+type lockedState struct {
+	Status          string
+	SearchStartedAt *time.Time
+}
+
+// waitSeconds menghitung berapa detik user sudah menunggu sejak mulai searching
+func (s lockedState) waitSeconds() int {
+	if s.SearchStartedAt == nil {
+		return 0
+	}
+	wait := time.Since(*s.SearchStartedAt)
+	if wait < 0 {
+		return 0
+	}
+	return int(wait.Seconds())
+}
+
+// lockUserStates membuat baris user_state jika belum ada lalu menguncinya (FOR UPDATE).
+// Baris dikunci berurutan berdasarkan user_id untuk menghindari deadlock.
+func lockUserStates(ctx context.Context, tx pgx.Tx, userIDs ...int64) (map[int64]lockedState, error) {
+	ensure := `
+		INSERT INTO user_state (user_id) SELECT unnest($1::BIGINT[])
+		ON CONFLICT (user_id) DO NOTHING
+	`
+	if _, err := tx.Exec(ctx, ensure, userIDs); err != nil {
+		return nil, err
+	}
+
+	query := `
+		SELECT user_id, status, search_started_at FROM user_state
+		WHERE user_id = ANY($1)
+		ORDER BY user_id
+		FOR UPDATE
+	`
+	rows, err := tx.Query(ctx, query, userIDs)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	states := make(map[int64]lockedState, len(userIDs))
+	for rows.Next() {
+		var userID int64
+		var state lockedState
+		if err := rows.Scan(&userID, &state.Status, &state.SearchStartedAt); err != nil {
+			return nil, err
+		}
+		states[userID] = state
+	}
+	return states, rows.Err()
+}
+
+func (r pgSessions) Create(ctx context.Context, user1ID, user2ID int64, matchMode string, distanceKm float64, user1Wait, user2Wait int) (int64, error) {
+	return pgCreateSession(ctx, r.db, user1ID, user2ID, matchMode, distanceKm, user1Wait, user2Wait)
+}
+
+func pgCreateSession(ctx context.Context, q querier, user1ID, user2ID int64, matchMode string, distanceKm float64, user1Wait, user2Wait int) (int64, error) {
+	query := `
+		INSERT INTO chat_sessions (user1_id, user2_id, started_at, is_active, last_message_at,
+			match_mode, distance_km, user1_wait_seconds, user2_wait_seconds)
+		VALUES ($1, $2, $3, true, $3, $4, $5, $6, $7)
+		RETURNING id
+	`
+	var sessionID int64
+	err := q.QueryRow(ctx, query, user1ID, user2ID, time.Now(), matchMode,
+		matchDistance(matchMode, distanceKm), user1Wait, user2Wait).Scan(&sessionID)
+	return sessionID, err
+}
+
+func (r pgSessions) End(ctx context.Context, user1ID, user2ID, endedBy int64, reason string) error {
+	return pgEndSession(ctx, r.db, user1ID, user2ID, endedBy, reason)
+}
+
+func pgEndSession(ctx context.Context, q querier, user1ID, user2ID, endedBy int64, reason string) error {
+	query := `
+		UPDATE chat_sessions
+		SET is_active = false, ended_at = $1, end_reason = $4, ended_by = $5
+		WHERE ((user1_id = $2 AND user2_id = $3) OR (user1_id = $3 AND user2_id = $2))
+		AND is_active = true
+	`
+	_, err := q.Exec(ctx, query, time.Now(), user1ID, user2ID, reason, endedByValue(endedBy))
+	return err
+}
+
+func (r pgSessions) ActiveID(ctx context.Context, userID int64) (int64, error) {
+	query := `
+		SELECT id FROM chat_sessions
+		WHERE (user1_id = $1 OR user2_id = $1) AND is_active = true
+		ORDER BY started_at DESC
+		LIMIT 1
+	`
+	var sessionID int64
+	err := r.db.QueryRow(ctx, query, userID).Scan(&sessionID)
+	return sessionID, err
+}
+
+func (r pgSessions) CountActive(ctx context.Context) (int64, error) {
+	var count int64
+	err := r.db.QueryRow(ctx, "SELECT COUNT(*) FROM chat_sessions WHERE is_active = true").Scan(&count)
+	return count, err
+}
+
+func (r pgSessions) Active(ctx context.Context) ([]ChatSession, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT id, user1_id, user2_id, started_at, ended_at, is_active, last_message_at
+		FROM chat_sessions
+		WHERE is_active = true
+		ORDER BY id ASC
+	`)
+	if err != nil {
+		return nil, err
+	}
+	return pgScanSessions(rows)
+}
+
+func (r pgSessions) Idle(ctx context.Context, idleFor time.Duration, onlyUnwarned bool) ([]ChatSession, error) {
+	query := `
+		SELECT id, user1_id, user2_id, started_at, ended_at, is_active, last_message_at
+		FROM chat_sessions
+		WHERE is_active = true AND last_message_at < $1
+	`
+	if onlyUnwarned {
+		query += ` AND idle_warned_at IS NULL`
+	}
+	query += ` ORDER BY last_message_at ASC`
+
+	rows, err := r.db.Query(ctx, query, time.Now().Add(-idleFor))
+	if err != nil {
+		return nil, err
+	}
+	return pgScanSessions(rows)
+}
+
+func (r pgSessions) Orphaned(ctx context.Context) ([]ChatSession, error) {
+	query := `
+		SELECT s.id, s.user1_id, s.user2_id, s.started_at, s.ended_at, s.is_active, s.last_message_at
+		FROM chat_sessions s
+		LEFT JOIN user_state a ON a.user_id = s.user1_id
+		LEFT JOIN user_state b ON b.user_id = s.user2_id
+		WHERE s.is_active = true
+		AND NOT (a.status IS NOT DISTINCT FROM $1 AND a.partner_id IS NOT DISTINCT FROM s.user2_id
+			AND b.status IS NOT DISTINCT FROM $1 AND b.partner_id IS NOT DISTINCT FROM s.user1_id)
+	`
+	rows, err := r.db.Query(ctx, query, constants.StatusChatting)
+	if err != nil {
+		return nil, err
+	}
+	return pgScanSessions(rows)
+}
+
+// pgScanSessions membaca baris chat_sessions lalu menutup rows
+func pgScanSessions(rows pgx.Rows) ([]ChatSession, error) {
+	defer rows.Close()
+
+	var sessions []ChatSession
+	for rows.Next() {
+		var session ChatSession
+		if err := rows.Scan(&session.ID, &session.User1ID, &session.User2ID,
+			&session.StartedAt, &session.EndedAt, &session.IsActive, &session.LastMessageAt); err != nil {
+			return nil, err
+		}
+		sessions = append(sessions, session)
+	}
+	return sessions, rows.Err()
+}
+
+func (r pgSessions) Touch(ctx context.Context, sessionID, senderID int64) error {
+	query := `
+		UPDATE chat_sessions SET
+			last_message_at = $1,
+			idle_warned_at = NULL,
+			user1_messages = user1_messages + CASE WHEN user1_id = $3 THEN 1 ELSE 0 END,
+			user2_messages = user2_messages + CASE WHEN user2_id = $3 THEN 1 ELSE 0 END
+		WHERE id = $2 AND is_active = true
+	`
+	_, err := r.db.Exec(ctx, query, time.Now(), sessionID, senderID)
+	return err
+}
+
+func (r pgSessions) MarkIdleWarned(ctx context.Context, sessionID int64) error {
+	_, err := r.db.Exec(ctx, `UPDATE chat_sessions SET idle_warned_at = $1 WHERE id = $2`, time.Now(), sessionID)
+	return err
+}
+
+func (r pgSessions) Participants(ctx context.Context, sessionID int64) (user1ID, user2ID int64, err error) {
+	err = r.db.QueryRow(ctx, `SELECT user1_id, user2_id FROM chat_sessions WHERE id = $1`, sessionID).Scan(&user1ID, &user2ID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		err = ErrNotFound
+	}
+	return
+}
+
+func (r pgSessions) Rate(ctx context.Context, sessionID, raterID, rateeID int64, score int) (bool, error) {
+	query := `
+		INSERT INTO session_ratings (session_id, rater_id, ratee_id, score, created_at)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (session_id, rater_id) DO NOTHING
+	`
+	tag, err := r.db.Exec(ctx, query, sessionID, raterID, rateeID, score, time.Now())
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+func (r pgSessions) RatingCounts(ctx context.Context, userID int64) (up, down int, err error) {
+	query := `
+		SELECT
+			COUNT(*) FILTER (WHERE score > 0),
+			COUNT(*) FILTER (WHERE score < 0)
+		FROM session_ratings WHERE ratee_id = $1
+	`
+	err = r.db.QueryRow(ctx, query, userID).Scan(&up, &down)
+	return
+}
+
+func (r pgSessions) Reset(ctx context.Context) error {
+	_, err := r.db.Exec(ctx, "TRUNCATE TABLE chat_sessions CASCADE")
+	return err
+}
+
+// ============================================================
+// MESSAGES
+// ============================================================
+
+type pgMessages struct {
+	db *pgxpool.Pool
+}
+
+func (r pgMessages) Save(ctx context.Context, sessionID, senderID, receiverID int64, msgType, content string) error {
+	query := `
+		INSERT INTO messages (session_id, sender_id, receiver_id, message_type, content, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+	`
+	_, err := r.db.Exec(ctx, query, sessionID, senderID, receiverID, msgType, content, time.Now())
+	return err
+}
+
+func (r pgMessages) Reset(ctx context.Context) error {
+	_, err := r.db.Exec(ctx, "TRUNCATE TABLE messages CASCADE")
+	return err
+}
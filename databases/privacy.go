@@ -0,0 +1,53 @@
+package databases
+
+import (
+	"context"
+	"strings"
+
+	"tg-anon-go/constants"
+)
+
+// GetCardFields mengambil field profil yang boleh dilihat partner.
+// User yang belum pernah mengatur privasi memakai DefaultCardFields.
+func GetCardFields(ctx context.Context, userID int64) map[string]bool {
+	var value string
+	if profile, err := Users.Profile(ctx, userID); err == nil {
+		value = profile.CardFields
+	}
+	if value == "" {
+		value = constants.DefaultCardFields
+	}
+
+	fields := make(map[string]bool)
+	if value == constants.CardFieldsNone {
+		return fields
+	}
+	for _, field := range strings.Split(value, ",") {
+		if field = strings.TrimSpace(field); field != "" {
+			fields[field] = true
+		}
+	}
+	return fields
+}
+
+// SetCardFields menyimpan field profil yang boleh dilihat partner
+func SetCardFields(ctx context.Context, userID int64, fields map[string]bool) error {
+	var visible []string
+	for _, field := range constants.CardFields {
+		if fields[field] {
+			visible = append(visible, field)
+		}
+	}
+	value := strings.Join(visible, ",")
+	if len(visible) == 0 {
+		value = constants.CardFieldsNone
+	}
+	return Users.Update(ctx, constants.TableUserProfiles, userID, []string{"card_fields"}, value)
+}
+
+// ToggleCardField menampilkan/menyembunyikan satu field profil dari partner
+func ToggleCardField(ctx context.Context, userID int64, field string) (map[string]bool, error) {
+	fields := GetCardFields(ctx, userID)
+	fields[field] = !fields[field]
+	return fields, SetCardFields(ctx, userID, fields)
+}
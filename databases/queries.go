@@ -2,9 +2,11 @@ package databases
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"time"
 
+	"tg-anon-go/cache"
 	"tg-anon-go/constants"
 )
 
@@ -22,28 +24,25 @@ type User struct {
 
 // ChatSession represents a chat session
 type ChatSession struct {
-	ID        int64
-	User1ID   int64
-	User2ID   int64
-	StartedAt time.Time
-	EndedAt   *time.Time
-	IsActive  bool
+	ID            int64
+	User1ID       int64
+	User2ID       int64
+	StartedAt     time.Time
+	EndedAt       *time.Time
+	IsActive      bool
+	LastMessageAt time.Time
 }
 
 // CreateOrUpdateUser membuat atau memperbarui user
 func CreateOrUpdateUser(ctx context.Context, telegramID int64, username, firstName string) error {
-	query := `
-		INSERT INTO users (telegram_id, username, first_name, status, updated_at)
-		VALUES ($1, $2, $3, $4, $5)
-		ON CONFLICT (telegram_id)
-		DO UPDATE SET username = $2, first_name = $3, updated_at = $5
-	`
-	_, err := DB.Exec(ctx, query, telegramID, username, firstName, constants.StatusIdle, time.Now())
-	return err
+	return Users.Save(ctx, telegramID, username, firstName)
 }
 
-// GetUserByTelegramID mengambil user berdasarkan telegram ID
+// GetUserByTelegramID mengambil user berdasarkan telegram ID (hanya Postgres)
 func GetUserByTelegramID(ctx context.Context, telegramID int64) (*User, error) {
+	if DB == nil {
+		return nil, ErrNotSupported
+	}
 	query := `
 		SELECT id, telegram_id, username, first_name, status, partner_id, created_at, updated_at
 		FROM users WHERE telegram_id = $1
@@ -59,8 +58,11 @@ func GetUserByTelegramID(ctx context.Context, telegramID int64) (*User, error) {
 	return user, nil
 }
 
-// UpdateUserStatus memperbarui status user
+// UpdateUserStatus memperbarui status user di tabel users lama (hanya Postgres)
 func UpdateUserStatus(ctx context.Context, telegramID int64, status string, partnerID *int64) error {
+	if DB == nil {
+		return ErrNotSupported
+	}
 	query := `
 		UPDATE users SET status = $1, partner_id = $2, updated_at = $3
 		WHERE telegram_id = $4
@@ -69,21 +71,23 @@ func UpdateUserStatus(ctx context.Context, telegramID int64, status string, part
 	return err
 }
 
-// FindSearchingUser mencari user yang sedang mencari partner (selain diri sendiri)
+// FindSearchingUser mencari user yang sedang mencari partner (selain diri sendiri, hanya Postgres)
 func FindSearchingUser(ctx context.Context, excludeTelegramID int64) (*User, error) {
-	// Cari dari tabel vars dimana status = searching
+	if DB == nil {
+		return nil, ErrNotSupported
+	}
+	// Cari dari tabel user_state dimana status = searching, yang paling lama menunggu dulu
 	query := `
-		SELECT u.id, u.telegram_id, u.username, u.first_name, 
-			   COALESCE(v.var_value, 'idle') as status, 
-			   u.partner_id, u.created_at, u.updated_at
-		FROM users u
-		LEFT JOIN vars v ON u.telegram_id = v.user_id AND v.var_key = $1
-		WHERE v.var_value = $2 AND u.telegram_id != $3
-		ORDER BY v.updated_at ASC
+		SELECT u.id, u.telegram_id, u.username, u.first_name,
+			   s.status, u.partner_id, u.created_at, u.updated_at
+		FROM user_state s
+		JOIN users u ON u.telegram_id = s.user_id
+		WHERE s.status = $1 AND s.user_id != $2
+		ORDER BY s.search_started_at ASC NULLS LAST
 		LIMIT 1
 	`
 	user := &User{}
-	err := DB.QueryRow(ctx, query, constants.VarStatus, constants.StatusSearching, excludeTelegramID).Scan(
+	err := DB.QueryRow(ctx, query, constants.StatusSearching, excludeTelegramID).Scan(
 		&user.ID, &user.TelegramID, &user.Username, &user.FirstName,
 		&user.Status, &user.PartnerID, &user.CreatedAt, &user.UpdatedAt,
 	)
@@ -93,221 +97,264 @@ func FindSearchingUser(ctx context.Context, excludeTelegramID int64) (*User, err
 	return user, nil
 }
 
-// CreateChatSession membuat sesi chat baru
-func CreateChatSession(ctx context.Context, user1ID, user2ID int64) (int64, error) {
-	query := `
-		INSERT INTO chat_sessions (user1_id, user2_id, started_at, is_active)
-		VALUES ($1, $2, $3, true)
-		RETURNING id
-	`
-	var sessionID int64
-	err := DB.QueryRow(ctx, query, user1ID, user2ID, time.Now()).Scan(&sessionID)
-	return sessionID, err
+// ErrUserUnavailable dikembalikan ConnectUsers jika salah satu user sudah tidak searching
+// (misalnya sudah dipasangkan oleh matcher lain atau membatalkan pencarian)
+var ErrUserUnavailable = errors.New("user is no longer searching")
+
+// CreateChatSession membuat sesi chat baru beserta data analytics match-nya
+func CreateChatSession(ctx context.Context, user1ID, user2ID int64, matchMode string, distanceKm float64, user1Wait, user2Wait int) (int64, error) {
+	return Sessions.Create(ctx, user1ID, user2ID, matchMode, distanceKm, user1Wait, user2Wait)
 }
 
-// EndChatSession mengakhiri sesi chat
-func EndChatSession(ctx context.Context, user1ID, user2ID int64) error {
-	query := `
-		UPDATE chat_sessions 
-		SET is_active = false, ended_at = $1
-		WHERE ((user1_id = $2 AND user2_id = $3) OR (user1_id = $3 AND user2_id = $2))
-		AND is_active = true
-	`
-	_, err := DB.Exec(ctx, query, time.Now(), user1ID, user2ID)
-	return err
+// matchDistance mengembalikan jarak yang dicatat di sesi (hanya untuk match nearby)
+func matchDistance(matchMode string, distanceKm float64) *float64 {
+	if matchMode != constants.SearchModeNearby {
+		return nil
+	}
+	return &distanceKm
+}
+
+// EndChatSession mengakhiri sesi chat dan mencatat siapa yang mengakhiri serta alasannya.
+// endedBy 0 berarti diakhiri oleh sistem (auto-close, ban, dll).
+func EndChatSession(ctx context.Context, user1ID, user2ID, endedBy int64, reason string) error {
+	return Sessions.End(ctx, user1ID, user2ID, endedBy, reason)
+}
+
+// endedByValue mengubah endedBy 0 (sistem) menjadi NULL
+func endedByValue(endedBy int64) *int64 {
+	if endedBy == 0 {
+		return nil
+	}
+	return &endedBy
 }
 
 // SaveMessage menyimpan pesan ke database
 func SaveMessage(ctx context.Context, sessionID int64, senderID, receiverID int64, msgType, content string) error {
-	query := `
-		INSERT INTO messages (session_id, sender_id, receiver_id, message_type, content, created_at)
-		VALUES ($1, $2, $3, $4, $5, $6)
-	`
-	_, err := DB.Exec(ctx, query, sessionID, senderID, receiverID, msgType, content, time.Now())
-	return err
+	return Messages.Save(ctx, sessionID, senderID, receiverID, msgType, content)
 }
 
 // GetActiveSessionID mengambil ID sesi aktif untuk user
 func GetActiveSessionID(ctx context.Context, userID int64) (int64, error) {
-	query := `
-		SELECT id FROM chat_sessions
-		WHERE (user1_id = $1 OR user2_id = $1) AND is_active = true
-		ORDER BY started_at DESC
-		LIMIT 1
-	`
-	var sessionID int64
-	err := DB.QueryRow(ctx, query, userID).Scan(&sessionID)
-	return sessionID, err
+	return Sessions.ActiveID(ctx, userID)
 }
 
 // GetUserStats mengambil statistik user
 func GetUserStats(ctx context.Context) (totalUsers int64, activeChats int64, err error) {
-	err = DB.QueryRow(ctx, "SELECT COUNT(*) FROM users").Scan(&totalUsers)
-	if err != nil {
+	if totalUsers, err = Users.Count(ctx); err != nil {
 		return
 	}
-	err = DB.QueryRow(ctx, "SELECT COUNT(*) FROM chat_sessions WHERE is_active = true").Scan(&activeChats)
+	activeChats, err = Sessions.CountActive(ctx)
 	return
 }
 
-// GetOldActiveSessions mengambil sesi yang sudah aktif lebih lama dari durasi tertentu
-func GetOldActiveSessions(ctx context.Context, maxAge time.Duration) ([]ChatSession, error) {
-	query := `
-		SELECT id, user1_id, user2_id, started_at, ended_at, is_active
-		FROM chat_sessions
-		WHERE is_active = true AND started_at < $1
-		ORDER BY started_at ASC
-	`
-	cutoffTime := time.Now().Add(-maxAge)
-	rows, err := DB.Query(ctx, query, cutoffTime)
+// GetActiveSessions mengambil semua sesi chat yang masih aktif
+func GetActiveSessions(ctx context.Context) ([]ChatSession, error) {
+	return Sessions.Active(ctx)
+}
+
+// GetIdleSessions mengambil sesi aktif yang tidak ada pesan sejak idleFor.
+// Jika onlyUnwarned true, hanya sesi yang belum dikirimi peringatan.
+func GetIdleSessions(ctx context.Context, idleFor time.Duration, onlyUnwarned bool) ([]ChatSession, error) {
+	return Sessions.Idle(ctx, idleFor, onlyUnwarned)
+}
+
+// TouchSession mencatat pesan yang di-relay oleh senderID: waktu pesan terakhir,
+// jumlah pesan per sisi, dan mereset peringatan idle
+func TouchSession(ctx context.Context, sessionID, senderID int64) error {
+	return Sessions.Touch(ctx, sessionID, senderID)
+}
+
+// MarkSessionIdleWarned menandai sesi sudah dikirimi peringatan idle
+func MarkSessionIdleWarned(ctx context.Context, sessionID int64) error {
+	return Sessions.MarkIdleWarned(ctx, sessionID)
+}
+
+// ============================================================
+// HELPER FUNCTIONS USER STATE (tabel user_state)
+// ============================================================
+
+// getChatState mengambil status, partner, sesi, dan flag ban/inactive user dari cache Redis,
+// atau dari user_state jika belum ada di cache (read-through)
+func getChatState(ctx context.Context, userID int64) (cache.UserState, error) {
+	if state, ok := cache.GetUserState(ctx, userID); ok {
+		return state, nil
+	}
+
+	// Versi dibaca sebelum query supaya invalidasi yang terjadi selama query terdeteksi
+	version, cacheable := cache.UserStateVersion(ctx, userID)
+
+	userState, err := Users.State(ctx, userID)
 	if err != nil {
-		return nil, err
+		return cache.UserState{Status: constants.StatusIdle}, err
+	}
+	state := cache.UserState{
+		Status:     userState.Status,
+		PartnerID:  userState.PartnerID,
+		SessionID:  userState.SessionID,
+		IsBanned:   userState.IsBanned,
+		IsInactive: userState.IsInactive,
+	}
+	if state.Status == "" {
+		state.Status = constants.StatusIdle
 	}
-	defer rows.Close()
 
-	var sessions []ChatSession
-	for rows.Next() {
-		var session ChatSession
-		if err := rows.Scan(&session.ID, &session.User1ID, &session.User2ID,
-			&session.StartedAt, &session.EndedAt, &session.IsActive); err != nil {
-			continue
-		}
-		sessions = append(sessions, session)
+	if cacheable {
+		cache.SetUserState(ctx, userID, state, version)
 	}
-	return sessions, nil
+	return state, nil
 }
 
-// ============================================================
-// HELPER FUNCTIONS MENGGUNAKAN VAR SYSTEM
-// ============================================================
+// setChatStateColumn mengubah satu kolom user_state yang di-cache lalu menghapus cache-nya
+func setChatStateColumn(ctx context.Context, userID int64, column string, value interface{}) error {
+	err := Users.Update(ctx, constants.TableUserState, userID, []string{column}, value)
+	cache.InvalidateUserState(ctx, userID)
+	return err
+}
 
-// SetUserStatus mengatur status user menggunakan var system
+// SetUserStatus mengatur status user
 func SetUserStatus(ctx context.Context, userID int64, status string) error {
-	return SetVar(ctx, userID, constants.VarStatus, status)
+	return setChatStateColumn(ctx, userID, "status", status)
 }
 
-// GetUserStatus mengambil status user menggunakan var system
+// GetUserStatus mengambil status user (default idle)
 func GetUserStatus(ctx context.Context, userID int64) (string, error) {
-	status, err := GetVar(ctx, userID, constants.VarStatus)
+	state, err := getChatState(ctx, userID)
 	if err != nil {
 		return "", err
 	}
-	if status == "" {
-		return constants.StatusIdle, nil
-	}
-	return status, nil
+	return state.Status, nil
 }
 
 // SetUserPartner mengatur partner ID untuk user
 func SetUserPartner(ctx context.Context, userID int64, partnerID int64) error {
-	return SetVar(ctx, userID, constants.VarPartnerID, partnerID)
+	return setChatStateColumn(ctx, userID, "partner_id", partnerID)
 }
 
-// GetUserPartner mengambil partner ID user
+// GetUserPartner mengambil partner ID user (0 jika tidak ada)
 func GetUserPartner(ctx context.Context, userID int64) (int64, error) {
-	return GetVarInt64(ctx, userID, constants.VarPartnerID)
+	state, err := getChatState(ctx, userID)
+	return state.PartnerID, err
 }
 
 // ClearUserPartner menghapus partner ID user
 func ClearUserPartner(ctx context.Context, userID int64) error {
-	return DeleteVar(ctx, userID, constants.VarPartnerID)
+	return setChatStateColumn(ctx, userID, "partner_id", nil)
 }
 
 // SetUserSessionID mengatur session ID untuk user
 func SetUserSessionID(ctx context.Context, userID int64, sessionID int64) error {
-	return SetVar(ctx, userID, constants.VarSessionID, sessionID)
+	return setChatStateColumn(ctx, userID, "session_id", sessionID)
 }
 
-// GetUserSessionID mengambil session ID user
+// GetUserSessionID mengambil session ID user (0 jika tidak ada)
 func GetUserSessionID(ctx context.Context, userID int64) (int64, error) {
-	return GetVarInt64(ctx, userID, constants.VarSessionID)
+	state, err := getChatState(ctx, userID)
+	return state.SessionID, err
 }
 
-// IncrementUserTotalChats menambah total chat user
-func IncrementUserTotalChats(ctx context.Context, userID int64) error {
-	current, _ := GetVarInt(ctx, userID, constants.VarTotalChats)
-	return SetVar(ctx, userID, constants.VarTotalChats, current+1)
+// SetSearchStartedAt mencatat waktu user mulai mencari partner
+func SetSearchStartedAt(ctx context.Context, userID int64) error {
+	return Users.Update(ctx, constants.TableUserState, userID, []string{"search_started_at"}, time.Now())
 }
 
-// IncrementUserTotalMessages menambah total pesan user
-func IncrementUserTotalMessages(ctx context.Context, userID int64) error {
-	current, _ := GetVarInt(ctx, userID, constants.VarTotalMessages)
-	return SetVar(ctx, userID, constants.VarTotalMessages, current+1)
+// GetSearchWaitSeconds menghitung berapa detik user sudah menunggu sejak mulai searching
+func GetSearchWaitSeconds(ctx context.Context, userID int64) int {
+	state, err := Users.State(ctx, userID)
+	if err != nil {
+		return 0
+	}
+	return lockedState{SearchStartedAt: state.SearchStartedAt}.waitSeconds()
 }
 
-// UpdateLastActive mengupdate waktu terakhir aktif user
+// lastActiveThrottle membatasi penulisan last_active (dan daily active) per user
+const lastActiveThrottle = time.Minute
+
+// UpdateLastActive mengupdate waktu terakhir aktif user, paling banyak sekali per menit
 func UpdateLastActive(ctx context.Context, userID int64) error {
-	return SetVar(ctx, userID, constants.VarLastActive, time.Now().Unix())
+	if !cache.TouchActive(ctx, userID, lastActiveThrottle) {
+		return nil
+	}
+	MarkDailyActive(ctx, userID)
+	return Users.Update(ctx, constants.TableUserState, userID, []string{"last_active"}, time.Now())
 }
 
 // IsUserBanned mengecek apakah user dibanned
 func IsUserBanned(ctx context.Context, userID int64) (bool, error) {
-	return GetVarBool(ctx, userID, constants.VarIsBanned)
+	state, err := getChatState(ctx, userID)
+	return state.IsBanned, err
 }
 
 // BanUser mem-ban user
 func BanUser(ctx context.Context, userID int64) error {
-	return SetVar(ctx, userID, constants.VarIsBanned, true)
+	return setChatStateColumn(ctx, userID, "is_banned", true)
 }
 
 // UnbanUser meng-unban user
 func UnbanUser(ctx context.Context, userID int64) error {
-	return SetVar(ctx, userID, constants.VarIsBanned, false)
+	return setChatStateColumn(ctx, userID, "is_banned", false)
 }
 
-// ConnectUsers menghubungkan dua user untuk chat
-func ConnectUsers(ctx context.Context, user1ID, user2ID int64) (int64, error) {
-	// Create session
-	sessionID, err := CreateChatSession(ctx, user1ID, user2ID)
+// IncrementWarnCount menambah jumlah warn user dan mengembalikan nilai barunya
+func IncrementWarnCount(ctx context.Context, userID int64) (int, error) {
+	return Users.Increment(ctx, constants.TableUserState, userID, "warn_count")
+}
+
+// IsUserInactive mengecek apakah user memblokir bot atau akunnya sudah dihapus
+func IsUserInactive(ctx context.Context, userID int64) (bool, error) {
+	state, err := getChatState(ctx, userID)
+	return state.IsInactive, err
+}
+
+// SetUserInactive menandai user tidak aktif (memblokir bot) atau aktif kembali
+func SetUserInactive(ctx context.Context, userID int64, inactive bool) error {
+	return setChatStateColumn(ctx, userID, "is_inactive", inactive)
+}
+
+// getUserStateFlag mengambil satu flag dari user_state tanpa cache (false jika belum ada)
+func getUserStateFlag(ctx context.Context, userID int64, flag func(*UserState) bool) (bool, error) {
+	state, err := Users.State(ctx, userID)
 	if err != nil {
-		return 0, err
+		return false, err
 	}
+	return flag(state), nil
+}
 
-	// Set status dan partner untuk kedua user
-	if err := SetUserStatus(ctx, user1ID, constants.StatusChatting); err != nil {
-		return 0, err
-	}
-	if err := SetUserStatus(ctx, user2ID, constants.StatusChatting); err != nil {
-		return 0, err
-	}
-	if err := SetUserPartner(ctx, user1ID, user2ID); err != nil {
-		return 0, err
-	}
-	if err := SetUserPartner(ctx, user2ID, user1ID); err != nil {
-		return 0, err
-	}
-	if err := SetUserSessionID(ctx, user1ID, sessionID); err != nil {
-		return 0, err
+// GetActiveUserIDs mengambil semua user ID yang tidak memblokir bot
+func GetActiveUserIDs(ctx context.Context) ([]int64, error) {
+	return Users.ActiveIDs(ctx)
+}
+
+// CountUsersByStatus menghitung user dengan status tertentu (searching, chatting, ...)
+func CountUsersByStatus(ctx context.Context, status string) (int, error) {
+	return Users.CountByStatus(ctx, status)
+}
+
+// ConnectUsers menghubungkan dua user untuk chat dalam satu transaksi.
+// Baris state kedua user dikunci sehingga dua matcher tidak bisa memasangkan
+// user yang sama ke dua partner. Mengembalikan ErrUserUnavailable jika
+// salah satu user sudah tidak searching. matchMode dan distanceKm dicatat di sesi untuk analytics.
+func ConnectUsers(ctx context.Context, user1ID, user2ID int64, matchMode string, distanceKm float64) (int64, error) {
+	if user1ID == user2ID {
+		return 0, ErrUserUnavailable
 	}
-	if err := SetUserSessionID(ctx, user2ID, sessionID); err != nil {
+
+	sessionID, err := Sessions.Connect(ctx, user1ID, user2ID, matchMode, distanceKm)
+	if err != nil {
 		return 0, err
 	}
-
-	// Increment total chats
-	IncrementUserTotalChats(ctx, user1ID)
-	IncrementUserTotalChats(ctx, user2ID)
-
+	cache.InvalidateUserState(ctx, user1ID, user2ID)
 	return sessionID, nil
 }
 
-// DisconnectUsers memutuskan koneksi chat antara dua user
-func DisconnectUsers(ctx context.Context, user1ID, user2ID int64) error {
-	// Get session ID
-	sessionID, _ := GetUserSessionID(ctx, user1ID)
-
-	// End session di database
-	if err := EndChatSession(ctx, user1ID, user2ID); err != nil {
+// DisconnectUsers memutuskan koneksi chat antara dua user dalam satu transaksi.
+// endedBy adalah user yang mengakhiri chat (0 jika oleh sistem).
+// State user hanya direset jika masih terhubung ke user lainnya, supaya partner
+// yang sudah dipasangkan ulang tidak ikut terputus.
+func DisconnectUsers(ctx context.Context, user1ID, user2ID, endedBy int64, reason string) error {
+	if err := Sessions.Disconnect(ctx, user1ID, user2ID, endedBy, reason); err != nil {
 		return err
 	}
-
-	// Reset status dan partner untuk kedua user
-	SetUserStatus(ctx, user1ID, constants.StatusIdle)
-	SetUserStatus(ctx, user2ID, constants.StatusIdle)
-	ClearUserPartner(ctx, user1ID)
-	ClearUserPartner(ctx, user2ID)
-	DeleteVar(ctx, user1ID, constants.VarSessionID)
-	DeleteVar(ctx, user2ID, constants.VarSessionID)
-	_ = sessionID // untuk menghindari unused variable warning
+	cache.InvalidateUserState(ctx, user1ID, user2ID)
 	return nil
 }
 
@@ -320,7 +367,7 @@ func FindAndConnectPartner(ctx context.Context, userID int64) (*User, int64, err
 	}
 
 	// Hubungkan kedua user
-	sessionID, err := ConnectUsers(ctx, userID, partner.TelegramID)
+	sessionID, err := ConnectUsers(ctx, userID, partner.TelegramID, constants.SearchModeRandom, 0)
 	if err != nil {
 		return nil, 0, err
 	}
@@ -334,21 +381,26 @@ type SearchingUserWithLocation struct {
 	Distance float64
 }
 
-// FindNearbySearchingUser mencari partner terdekat yang sedang searching
+// FindNearbySearchingUser mencari partner terdekat yang sedang searching (hanya Postgres)
 func FindNearbySearchingUser(ctx context.Context, userID int64, maxDistanceKm float64) (*User, float64, error) {
+	if DB == nil {
+		return nil, 0, ErrNotSupported
+	}
 	// Get user's location
 	userLat, userLon, err := GetUserLocation(ctx, userID)
 	if err != nil || (userLat == 0 && userLon == 0) {
 		return nil, 0, err
 	}
 
-	// Get all searching users
+	// Get all searching users beserta lokasinya dalam satu query
 	query := `
-		SELECT DISTINCT v.user_id 
-		FROM vars v 
-		WHERE v.var_key = $1 AND v.var_value = $2 AND v.user_id != $3
+		SELECT s.user_id, p.latitude, p.longitude
+		FROM user_state s
+		JOIN user_profiles p ON p.user_id = s.user_id
+		WHERE s.status = $1 AND s.user_id != $2
+		AND p.latitude IS NOT NULL AND p.longitude IS NOT NULL
 	`
-	rows, err := DB.Query(ctx, query, constants.VarStatus, constants.StatusSearching, userID)
+	rows, err := DB.Query(ctx, query, constants.StatusSearching, userID)
 	if err != nil {
 		return nil, 0, err
 	}
@@ -359,13 +411,11 @@ func FindNearbySearchingUser(ctx context.Context, userID int64, maxDistanceKm fl
 
 	for rows.Next() {
 		var partnerID int64
-		if err := rows.Scan(&partnerID); err != nil {
+		var partnerLat, partnerLon float64
+		if err := rows.Scan(&partnerID, &partnerLat, &partnerLon); err != nil {
 			continue
 		}
-
-		// Get partner's location
-		partnerLat, partnerLon, err := GetUserLocation(ctx, partnerID)
-		if err != nil || (partnerLat == 0 && partnerLon == 0) {
+		if partnerLat == 0 && partnerLon == 0 {
 			continue
 		}
 
@@ -398,7 +448,7 @@ func FindAndConnectNearbyPartner(ctx context.Context, userID int64, maxDistanceK
 	}
 
 	// Hubungkan kedua user
-	sessionID, err := ConnectUsers(ctx, userID, partner.TelegramID)
+	sessionID, err := ConnectUsers(ctx, userID, partner.TelegramID, constants.SearchModeNearby, distance)
 	if err != nil {
 		return nil, 0, 0, err
 	}
@@ -0,0 +1,114 @@
+package databases
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"tg-anon-go/config"
+	"tg-anon-go/constants"
+)
+
+// Rating scores
+const (
+	RatingUp   = 1
+	RatingDown = -1
+)
+
+// DefaultReputation adalah skor reputasi user yang belum pernah dinilai
+const DefaultReputation = 50.0
+
+// Reputation berisi ringkasan rating yang diterima user
+type Reputation struct {
+	Up    int
+	Down  int
+	Score float64 // 0-100
+}
+
+// Count mengembalikan total rating yang diterima
+func (r Reputation) Count() int {
+	return r.Up + r.Down
+}
+
+// IsLow mengecek apakah reputasi di bawah threshold dan sudah cukup rating
+func (r Reputation) IsLow() bool {
+	return r.Count() >= config.C.MinRatingsForFlag && r.Score < float64(config.C.LowReputationThreshold)
+}
+
+// CalculateReputation menghitung skor 0-100 dengan prior netral (1 up + 1 down)
+// supaya user dengan sedikit rating tidak langsung ekstrem
+func CalculateReputation(up, down int) float64 {
+	return float64(up+1) / float64(up+down+2) * 100
+}
+
+// GetSessionParticipants mengambil kedua user di sebuah sesi
+func GetSessionParticipants(ctx context.Context, sessionID int64) (user1ID, user2ID int64, err error) {
+	return Sessions.Participants(ctx, sessionID)
+}
+
+// SaveRating menyimpan rating dari rater untuk partner-nya di sesi tertentu.
+// Mengembalikan false jika rater sudah pernah menilai sesi ini.
+func SaveRating(ctx context.Context, sessionID, raterID int64, score int) (rateeID int64, saved bool, err error) {
+	user1ID, user2ID, err := GetSessionParticipants(ctx, sessionID)
+	if err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return 0, false, fmt.Errorf("session %d not found", sessionID)
+		}
+		return 0, false, err
+	}
+
+	switch raterID {
+	case user1ID:
+		rateeID = user2ID
+	case user2ID:
+		rateeID = user1ID
+	default:
+		return 0, false, fmt.Errorf("user %d is not part of session %d", raterID, sessionID)
+	}
+
+	saved, err = Sessions.Rate(ctx, sessionID, raterID, rateeID, score)
+	return rateeID, saved, err
+}
+
+// GetReputation menghitung reputasi user dari tabel rating
+func GetReputation(ctx context.Context, userID int64) (Reputation, error) {
+	up, down, err := Sessions.RatingCounts(ctx, userID)
+	if err != nil {
+		return Reputation{Score: DefaultReputation}, err
+	}
+	return Reputation{Up: up, Down: down, Score: CalculateReputation(up, down)}, nil
+}
+
+// RefreshReputation menghitung ulang reputasi user dan menyimpannya ke user_stats
+// supaya matcher bisa membacanya tanpa agregasi
+func RefreshReputation(ctx context.Context, userID int64) (Reputation, error) {
+	rep, err := GetReputation(ctx, userID)
+	if err != nil {
+		return rep, err
+	}
+	if err := Users.Update(ctx, constants.TableUserStats, userID,
+		[]string{"reputation", "rating_count"}, rep.Score, rep.Count()); err != nil {
+		return rep, err
+	}
+	return rep, nil
+}
+
+// GetUserReputationScore mengambil skor reputasi tersimpan (default 50)
+func GetUserReputationScore(ctx context.Context, userID int64) float64 {
+	stats, err := Users.Stats(ctx, userID)
+	if err != nil {
+		return DefaultReputation
+	}
+	return stats.Reputation
+}
+
+// IsLowReputationFlagged mengecek apakah user sedang diflag reputasi rendah
+func IsLowReputationFlagged(ctx context.Context, userID int64) bool {
+	flagged, _ := getUserStateFlag(ctx, userID, func(s *UserState) bool { return s.LowRepFlag })
+	return flagged
+}
+
+// SetLowReputationFlag mengatur flag reputasi rendah user
+func SetLowReputationFlag(ctx context.Context, userID int64, flagged bool) error {
+	return Users.Update(ctx, constants.TableUserState, userID, []string{"low_rep_flag"}, flagged)
+}
@@ -0,0 +1,57 @@
+package databases
+
+import (
+	"math"
+	"testing"
+
+	"tg-anon-go/config"
+)
+
+func TestCalculateReputation(t *testing.T) {
+	tests := []struct {
+		up, down int
+		want     float64
+	}{
+		{0, 0, DefaultReputation},
+		{1, 1, 50},
+		{1, 0, 200.0 / 3},
+		{0, 1, 100.0 / 3},
+		{8, 0, 90},
+		{0, 8, 10},
+		{98, 0, 99},
+	}
+
+	for _, tt := range tests {
+		got := CalculateReputation(tt.up, tt.down)
+		if math.Abs(got-tt.want) > 1e-9 {
+			t.Errorf("CalculateReputation(%d, %d) = %v, want %v", tt.up, tt.down, got, tt.want)
+		}
+		if got <= 0 || got >= 100 {
+			t.Errorf("CalculateReputation(%d, %d) = %v, want strictly between 0 and 100", tt.up, tt.down, got)
+		}
+	}
+}
+
+func TestReputationIsLow(t *testing.T) {
+	previous := *config.C
+	t.Cleanup(func() { *config.C = previous })
+	config.C.MinRatingsForFlag = 10
+	config.C.LowReputationThreshold = 30
+
+	tests := []struct {
+		name string
+		rep  Reputation
+		want bool
+	}{
+		{"low score, enough ratings", Reputation{Up: 1, Down: 9, Score: 20}, true},
+		{"low score, too few ratings", Reputation{Up: 0, Down: 9, Score: 9}, false},
+		{"at threshold", Reputation{Up: 3, Down: 7, Score: 30}, false},
+		{"good score", Reputation{Up: 9, Down: 1, Score: 80}, false},
+	}
+
+	for _, tt := range tests {
+		if got := tt.rep.IsLow(); got != tt.want {
+			t.Errorf("%s: IsLow() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,30 @@
+package databases
+
+import (
+	"context"
+	"time"
+)
+
+// PartnerLink adalah user berstatus chatting beserta partner_id yang tersimpan
+type PartnerLink struct {
+	UserID    int64
+	PartnerID int64 // 0 jika partner_id kosong
+}
+
+// FindBrokenPartnerLinks mencari user chatting yang partnernya tidak ada, tidak chatting,
+// atau tidak menunjuk balik ke user tersebut (partner satu arah)
+func FindBrokenPartnerLinks(ctx context.Context) ([]PartnerLink, error) {
+	return Users.BrokenPartnerLinks(ctx)
+}
+
+// FindOrphanedSessions mencari sesi aktif yang kedua user-nya tidak lagi saling terhubung
+// (misalnya salah satu sudah idle atau sudah dipasangkan dengan user lain)
+func FindOrphanedSessions(ctx context.Context) ([]ChatSession, error) {
+	return Sessions.Orphaned(ctx)
+}
+
+// GetSearchingUserIDs mengambil user berstatus searching yang mulai mencari lebih dari olderThan lalu.
+// Grace period mencegah user yang baru saja mulai mencari (belum dipublish ke Redis) ikut diperbaiki.
+func GetSearchingUserIDs(ctx context.Context, olderThan time.Duration) ([]int64, error) {
+	return Users.SearchingIDs(ctx, olderThan)
+}
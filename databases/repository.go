@@ -0,0 +1,130 @@
+package databases
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"tg-anon-go/cache"
+)
+
+// ErrNotFound dikembalikan repository jika data yang diminta tidak ada
+var ErrNotFound = errors.New("not found")
+
+// ErrNotSupported dikembalikan fitur yang belum tersedia di driver database yang dipakai
+// (misalnya analytics di SQLite)
+var ErrNotSupported = errors.New("not supported by this database driver")
+
+// UserRepository menyimpan akun Telegram, profil, state chat dan statistik user
+type UserRepository interface {
+	// Save membuat atau memperbarui akun Telegram user
+	Save(ctx context.Context, telegramID int64, username, firstName string) error
+	Count(ctx context.Context) (int64, error)
+	// ActiveIDs mengambil semua user ID yang tidak memblokir bot
+	ActiveIDs(ctx context.Context) ([]int64, error)
+	CountByStatus(ctx context.Context, status string) (int, error)
+	// SearchingIDs mengambil user searching yang mulai mencari lebih dari olderThan lalu
+	SearchingIDs(ctx context.Context, olderThan time.Duration) ([]int64, error)
+	// BrokenPartnerLinks mencari user chatting yang partnernya tidak valid
+	BrokenPartnerLinks(ctx context.Context) ([]PartnerLink, error)
+
+	Profile(ctx context.Context, userID int64) (*UserProfile, error)
+	State(ctx context.Context, userID int64) (*UserState, error)
+	Stats(ctx context.Context, userID int64) (*UserStats, error)
+
+	// Update menyimpan beberapa kolom ke user_profiles/user_state/user_stats (upsert)
+	Update(ctx context.Context, table string, userID int64, columns []string, values ...interface{}) error
+	// Increment menambah satu counter dan mengembalikan nilai barunya
+	Increment(ctx context.Context, table string, userID int64, column string) (int, error)
+	MarkRegistered(ctx context.Context, userID int64) error
+	// ApplyCounters menyimpan satu batch counter dari Redis ke user_stats
+	ApplyCounters(ctx context.Context, deltas []cache.CounterDelta) error
+
+	// Reset menghapus semua profil, state dan statistik user
+	Reset(ctx context.Context) error
+}
+
+// VarRepository menyimpan variabel key-value per user (userID 0 untuk variabel global)
+type VarRepository interface {
+	Set(ctx context.Context, userID int64, key, value string) error
+	// Get mengembalikan "" jika variabel tidak ada
+	Get(ctx context.Context, userID int64, key string) (string, error)
+	Delete(ctx context.Context, userID int64, key string) error
+	DeleteAll(ctx context.Context, userID int64) error
+	All(ctx context.Context, userID int64) (map[string]string, error)
+	Reset(ctx context.Context) error
+}
+
+// SessionRepository menyimpan sesi chat beserta rating-nya
+type SessionRepository interface {
+	// Connect memasangkan dua user yang sedang searching secara atomik.
+	// Mengembalikan ErrUserUnavailable jika salah satu sudah tidak searching.
+	Connect(ctx context.Context, user1ID, user2ID int64, matchMode string, distanceKm float64) (int64, error)
+	// Disconnect mengakhiri sesi dan mereset state kedua user secara atomik
+	Disconnect(ctx context.Context, user1ID, user2ID, endedBy int64, reason string) error
+	Create(ctx context.Context, user1ID, user2ID int64, matchMode string, distanceKm float64, user1Wait, user2Wait int) (int64, error)
+	End(ctx context.Context, user1ID, user2ID, endedBy int64, reason string) error
+
+	ActiveID(ctx context.Context, userID int64) (int64, error)
+	CountActive(ctx context.Context) (int64, error)
+	Active(ctx context.Context) ([]ChatSession, error)
+	Idle(ctx context.Context, idleFor time.Duration, onlyUnwarned bool) ([]ChatSession, error)
+	// Orphaned mencari sesi aktif yang kedua user-nya tidak lagi saling terhubung
+	Orphaned(ctx context.Context) ([]ChatSession, error)
+	Touch(ctx context.Context, sessionID, senderID int64) error
+	MarkIdleWarned(ctx context.Context, sessionID int64) error
+	// Participants mengembalikan ErrNotFound jika sesi tidak ada
+	Participants(ctx context.Context, sessionID int64) (user1ID, user2ID int64, err error)
+
+	// Rate menyimpan rating, false jika rater sudah pernah menilai sesi ini
+	Rate(ctx context.Context, sessionID, raterID, rateeID int64, score int) (bool, error)
+	RatingCounts(ctx context.Context, userID int64) (up, down int, err error)
+
+	Reset(ctx context.Context) error
+}
+
+// MessageRepository menyimpan log pesan yang di-relay
+type MessageRepository interface {
+	Save(ctx context.Context, sessionID, senderID, receiverID int64, msgType, content string) error
+	Reset(ctx context.Context) error
+}
+
+// Ad adalah iklan yang disisipkan setiap beberapa pesan
+type Ad struct {
+	ID        int       `json:"id"`
+	Message   string    `json:"message"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// AdsRepository menyimpan daftar iklan dan status aktifnya
+type AdsRepository interface {
+	List(ctx context.Context) ([]Ad, error)
+	Add(ctx context.Context, message string) (Ad, error)
+	// Delete mengembalikan false jika iklan tidak ditemukan
+	Delete(ctx context.Context, id int) (bool, error)
+	Enabled(ctx context.Context) (bool, error)
+	SetEnabled(ctx context.Context, enabled bool) error
+}
+
+// Repository yang dipakai plugin, diisi oleh InitDatabase sesuai DATABASE_DRIVER
+var (
+	Users    UserRepository
+	Vars     VarRepository
+	Sessions SessionRepository
+	Messages MessageRepository
+	Ads      AdsRepository
+)
+
+// ResetAll menghapus semua pesan, sesi, variabel dan data user (admin /resetdb).
+// Akun Telegram di tabel users tetap disimpan supaya broadcast masih sampai.
+func ResetAll(ctx context.Context) error {
+	var errs []error
+	for _, reset := range []func(context.Context) error{
+		Messages.Reset, Sessions.Reset, Vars.Reset, Users.Reset,
+	} {
+		if err := reset(ctx); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
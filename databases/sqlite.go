@@ -0,0 +1,702 @@
+package databases
+
+import (
+	"context"
+	"database/sql"
+	_ "embed"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"tg-anon-go/cache"
+	"tg-anon-go/config"
+	"tg-anon-go/constants"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+//go:embed sqlite_schema.sql
+var sqliteSchema string
+
+// SQLite adalah koneksi database jika DATABASE_DRIVER=sqlite (nil jika memakai Postgres)
+var SQLite *sql.DB
+
+// sqlQuerier adalah method query yang dimiliki *sql.DB maupun *sql.Tx
+type sqlQuerier interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+// initSQLite membuka file SQLite, membuat skema lalu memasang repository SQLite
+func initSQLite(path string) error {
+	// _txlock=immediate: transaksi langsung mengambil write lock, pengganti FOR UPDATE di Postgres
+	dsn := fmt.Sprintf("file:%s?_txlock=immediate&_busy_timeout=5000&_foreign_keys=on&_journal_mode=WAL", path)
+	db, err := sql.Open("sqlite3", dsn)
+	if err != nil {
+		return err
+	}
+	// SQLite hanya mengizinkan satu writer, satu koneksi menghindari error "database is locked"
+	db.SetMaxOpenConns(1)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	if err := db.PingContext(ctx); err != nil {
+		db.Close()
+		return fmt.Errorf("failed to open sqlite database: %v", err)
+	}
+	log.Printf("✅ Opened SQLite database %s", path)
+
+	if config.C.MigrationsDryRun {
+		log.Println("🗄️ [dry-run] SQLite schema is applied idempotently on start, nothing to preview")
+	} else if _, err := db.ExecContext(ctx, sqliteSchema); err != nil {
+		db.Close()
+		return fmt.Errorf("failed to apply sqlite schema: %v", err)
+	}
+
+	SQLite = db
+	useSQLite(db)
+	return nil
+}
+
+// useSQLite memasang repository SQLite
+func useSQLite(db *sql.DB) {
+	Users = sqliteUsers{db}
+	Vars = sqliteVars{db}
+	Sessions = sqliteSessions{db}
+	Messages = sqliteMessages{db}
+	Ads = varAds{Vars}
+}
+
+// sqliteNow adalah waktu sekarang dalam UTC. Timestamp disimpan sebagai teks,
+// jadi semua nilai harus memakai zona yang sama supaya perbandingan < dan > benar.
+func sqliteNow() time.Time {
+	return time.Now().UTC()
+}
+
+// sqliteIDs membaca satu kolom ID lalu menutup rows
+func sqliteIDs(rows *sql.Rows) ([]int64, error) {
+	defer rows.Close()
+
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// ============================================================
+// USERS
+// ============================================================
+
+type sqliteUsers struct {
+	db *sql.DB
+}
+
+func (r sqliteUsers) Save(ctx context.Context, telegramID int64, username, firstName string) error {
+	query := `
+		INSERT INTO users (telegram_id, username, first_name, status, updated_at)
+		VALUES (?1, ?2, ?3, ?4, ?5)
+		ON CONFLICT (telegram_id)
+		DO UPDATE SET username = ?2, first_name = ?3, updated_at = ?5
+	`
+	_, err := r.db.ExecContext(ctx, query, telegramID, username, firstName, constants.StatusIdle, sqliteNow())
+	return err
+}
+
+func (r sqliteUsers) Count(ctx context.Context) (int64, error) {
+	var count int64
+	err := r.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM users").Scan(&count)
+	return count, err
+}
+
+func (r sqliteUsers) ActiveIDs(ctx context.Context) ([]int64, error) {
+	query := `
+		SELECT u.telegram_id FROM users u
+		LEFT JOIN user_state s ON s.user_id = u.telegram_id
+		WHERE COALESCE(s.is_inactive, FALSE) = FALSE
+	`
+	rows, err := r.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	return sqliteIDs(rows)
+}
+
+func (r sqliteUsers) CountByStatus(ctx context.Context, status string) (int, error) {
+	var count int
+	err := r.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM user_state WHERE status = ?`, status).Scan(&count)
+	return count, err
+}
+
+func (r sqliteUsers) SearchingIDs(ctx context.Context, olderThan time.Duration) ([]int64, error) {
+	query := `
+		SELECT user_id FROM user_state
+		WHERE status = ? AND (search_started_at IS NULL OR search_started_at < ?)
+	`
+	rows, err := r.db.QueryContext(ctx, query, constants.StatusSearching, sqliteNow().Add(-olderThan))
+	if err != nil {
+		return nil, err
+	}
+	return sqliteIDs(rows)
+}
+
+func (r sqliteUsers) BrokenPartnerLinks(ctx context.Context) ([]PartnerLink, error) {
+	query := `
+		SELECT a.user_id, COALESCE(a.partner_id, 0)
+		FROM user_state a
+		LEFT JOIN user_state b ON b.user_id = a.partner_id
+		WHERE a.status = ?1
+		AND (a.partner_id IS NULL OR b.user_id IS NULL
+			OR b.status <> ?1 OR b.partner_id IS NOT a.user_id)
+	`
+	rows, err := r.db.QueryContext(ctx, query, constants.StatusChatting)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var links []PartnerLink
+	for rows.Next() {
+		var link PartnerLink
+		if err := rows.Scan(&link.UserID, &link.PartnerID); err != nil {
+			return nil, err
+		}
+		links = append(links, link)
+	}
+	return links, rows.Err()
+}
+
+func (r sqliteUsers) Profile(ctx context.Context, userID int64) (*UserProfile, error) {
+	query := `
+		SELECT COALESCE(name, ''), COALESCE(age, 0), COALESCE(gender, ''), COALESCE(location, ''),
+			COALESCE(latitude, 0), COALESCE(longitude, 0), COALESCE(interests, ''),
+			COALESCE(card_fields, ''), COALESCE(search_mode, ''), is_registered, registered_at
+		FROM user_profiles WHERE user_id = ?
+	`
+	profile := &UserProfile{UserID: userID}
+	err := r.db.QueryRowContext(ctx, query, userID).Scan(
+		&profile.Name, &profile.Age, &profile.Gender, &profile.Location,
+		&profile.Latitude, &profile.Longitude, &profile.Interests,
+		&profile.CardFields, &profile.SearchMode, &profile.IsRegistered, &profile.RegisteredAt,
+	)
+	if errors.Is(err, sql.ErrNoRows) {
+		return profile, nil
+	}
+	return profile, err
+}
+
+func (r sqliteUsers) State(ctx context.Context, userID int64) (*UserState, error) {
+	query := `
+		SELECT status, COALESCE(partner_id, 0), COALESCE(session_id, 0), search_started_at,
+			warn_count, is_banned, is_inactive, low_rep_flag, last_active
+		FROM user_state WHERE user_id = ?
+	`
+	state := &UserState{UserID: userID, Status: constants.StatusIdle}
+	err := r.db.QueryRowContext(ctx, query, userID).Scan(
+		&state.Status, &state.PartnerID, &state.SessionID, &state.SearchStartedAt,
+		&state.WarnCount, &state.IsBanned, &state.IsInactive, &state.LowRepFlag, &state.LastActive,
+	)
+	if errors.Is(err, sql.ErrNoRows) {
+		return state, nil
+	}
+	return state, err
+}
+
+func (r sqliteUsers) Stats(ctx context.Context, userID int64) (*UserStats, error) {
+	query := `
+		SELECT total_chats, total_messages, COALESCE(reputation, ?2), rating_count, ads_counter
+		FROM user_stats WHERE user_id = ?1
+	`
+	stats := &UserStats{UserID: userID, Reputation: DefaultReputation}
+	err := r.db.QueryRowContext(ctx, query, userID, DefaultReputation).Scan(
+		&stats.TotalChats, &stats.TotalMessages, &stats.Reputation, &stats.RatingCount, &stats.AdsCounter,
+	)
+	if errors.Is(err, sql.ErrNoRows) {
+		return stats, nil
+	}
+	return stats, err
+}
+
+// Update menyimpan beberapa kolom user (upsert).
+// Nama tabel dan kolom hanya berasal dari package ini, bukan dari input user.
+func (r sqliteUsers) Update(ctx context.Context, table string, userID int64, columns []string, values ...interface{}) error {
+	return sqliteUpdate(ctx, r.db, table, userID, columns, values...)
+}
+
+func sqliteUpdate(ctx context.Context, q sqlQuerier, table string, userID int64, columns []string, values ...interface{}) error {
+	placeholders := make([]string, len(columns))
+	updates := make([]string, len(columns))
+	for i, column := range columns {
+		placeholders[i] = fmt.Sprintf("?%d", i+3)
+		updates[i] = fmt.Sprintf("%s = excluded.%s", column, column)
+	}
+
+	query := fmt.Sprintf(`
+		INSERT INTO %s (user_id, updated_at, %s)
+		VALUES (?1, ?2, %s)
+		ON CONFLICT (user_id)
+		DO UPDATE SET %s, updated_at = excluded.updated_at
+	`, table, strings.Join(columns, ", "), strings.Join(placeholders, ", "), strings.Join(updates, ", "))
+
+	args := append([]interface{}{userID, sqliteNow()}, values...)
+	_, err := q.ExecContext(ctx, query, args...)
+	return err
+}
+
+func (r sqliteUsers) Increment(ctx context.Context, table string, userID int64, column string) (int, error) {
+	return sqliteIncrement(ctx, r.db, table, userID, column)
+}
+
+// sqliteIncrement menambah satu counter user dan mengembalikan nilai barunya
+func sqliteIncrement(ctx context.Context, q sqlQuerier, table string, userID int64, column string) (int, error) {
+	query := fmt.Sprintf(`
+		INSERT INTO %[1]s (user_id, %[2]s, updated_at)
+		VALUES (?1, 1, ?2)
+		ON CONFLICT (user_id)
+		DO UPDATE SET %[2]s = %[1]s.%[2]s + 1, updated_at = excluded.updated_at
+		RETURNING %[2]s
+	`, table, column)
+
+	var value int
+	err := q.QueryRowContext(ctx, query, userID, sqliteNow()).Scan(&value)
+	return value, err
+}
+
+func (r sqliteUsers) MarkRegistered(ctx context.Context, userID int64) error {
+	query := `
+		INSERT INTO user_profiles (user_id, is_registered, registered_at, updated_at)
+		VALUES (?1, TRUE, ?2, ?2)
+		ON CONFLICT (user_id)
+		DO UPDATE SET is_registered = TRUE,
+			registered_at = COALESCE(user_profiles.registered_at, ?2),
+			updated_at = ?2
+	`
+	_, err := r.db.ExecContext(ctx, query, userID, sqliteNow())
+	return err
+}
+
+func (r sqliteUsers) ApplyCounters(ctx context.Context, deltas []cache.CounterDelta) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	messagesQuery := `
+		INSERT INTO user_stats (user_id, total_messages, updated_at)
+		VALUES (?1, ?2, ?3)
+		ON CONFLICT (user_id)
+		DO UPDATE SET total_messages = user_stats.total_messages + excluded.total_messages,
+			updated_at = excluded.updated_at
+	`
+	adsQuery := `
+		INSERT INTO user_stats (user_id, ads_counter, updated_at)
+		VALUES (?1, ?2, ?3)
+		ON CONFLICT (user_id)
+		DO UPDATE SET ads_counter = excluded.ads_counter, updated_at = excluded.updated_at
+	`
+	now := sqliteNow()
+	for _, delta := range deltas {
+		if delta.TotalMessages > 0 {
+			if _, err := tx.ExecContext(ctx, messagesQuery, delta.UserID, delta.TotalMessages, now); err != nil {
+				return err
+			}
+		}
+		if delta.HasAds {
+			if _, err := tx.ExecContext(ctx, adsQuery, delta.UserID, delta.AdsCounter, now); err != nil {
+				return err
+			}
+		}
+	}
+
+	return tx.Commit()
+}
+
+func (r sqliteUsers) Reset(ctx context.Context) error {
+	for _, query := range []string{
+		"DELETE FROM user_profiles",
+		"DELETE FROM user_state",
+		"DELETE FROM user_stats",
+		"UPDATE users SET status = 'idle', partner_id = NULL",
+	} {
+		if _, err := r.db.ExecContext(ctx, query); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ============================================================
+// VARS
+// ============================================================
+
+type sqliteVars struct {
+	db *sql.DB
+}
+
+func (r sqliteVars) Set(ctx context.Context, userID int64, key, value string) error {
+	query := `
+		INSERT INTO vars (user_id, var_key, var_value, updated_at)
+		VALUES (?1, ?2, ?3, ?4)
+		ON CONFLICT (user_id, var_key)
+		DO UPDATE SET var_value = ?3, updated_at = ?4
+	`
+	_, err := r.db.ExecContext(ctx, query, userID, key, value, sqliteNow())
+	return err
+}
+
+func (r sqliteVars) Get(ctx context.Context, userID int64, key string) (string, error) {
+	var value sql.NullString
+	err := r.db.QueryRowContext(ctx, `SELECT var_value FROM vars WHERE user_id = ? AND var_key = ?`, userID, key).Scan(&value)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", nil
+	}
+	return value.String, err
+}
+
+func (r sqliteVars) Delete(ctx context.Context, userID int64, key string) error {
+	_, err := r.db.ExecContext(ctx, `DELETE FROM vars WHERE user_id = ? AND var_key = ?`, userID, key)
+	return err
+}
+
+func (r sqliteVars) DeleteAll(ctx context.Context, userID int64) error {
+	_, err := r.db.ExecContext(ctx, `DELETE FROM vars WHERE user_id = ?`, userID)
+	return err
+}
+
+func (r sqliteVars) All(ctx context.Context, userID int64) (map[string]string, error) {
+	rows, err := r.db.QueryContext(ctx, `SELECT var_key, COALESCE(var_value, '') FROM vars WHERE user_id = ?`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	vars := make(map[string]string)
+	for rows.Next() {
+		var key, value string
+		if err := rows.Scan(&key, &value); err != nil {
+			return nil, err
+		}
+		vars[key] = value
+	}
+	return vars, rows.Err()
+}
+
+func (r sqliteVars) Reset(ctx context.Context) error {
+	_, err := r.db.ExecContext(ctx, "DELETE FROM vars")
+	return err
+}
+
+// ============================================================
+// SESSIONS
+// ============================================================
+
+type sqliteSessions struct {
+	db *sql.DB
+}
+
+// Connect berjalan di transaksi IMMEDIATE (write lock untuk seluruh database),
+// sehingga state kedua user tidak bisa berubah sampai commit
+func (r sqliteSessions) Connect(ctx context.Context, user1ID, user2ID int64, matchMode string, distanceKm float64) (int64, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	states, err := sqliteUserStates(ctx, tx, user1ID, user2ID)
+	if err != nil {
+		return 0, err
+	}
+	for _, id := range []int64{user1ID, user2ID} {
+		if states[id].Status != constants.StatusSearching {
+			return 0, fmt.Errorf("user %d: %w", id, ErrUserUnavailable)
+		}
+	}
+
+	// Create session
+	sessionID, err := sqliteCreateSession(ctx, tx, user1ID, user2ID, matchMode, distanceKm,
+		states[user1ID].waitSeconds(), states[user2ID].waitSeconds())
+	if err != nil {
+		return 0, err
+	}
+
+	// Set status, partner dan sesi untuk kedua user
+	query := `
+		UPDATE user_state
+		SET status = ?2, partner_id = ?3, session_id = ?4, search_started_at = NULL, updated_at = ?5
+		WHERE user_id = ?1
+	`
+	now := sqliteNow()
+	if _, err := tx.ExecContext(ctx, query, user1ID, constants.StatusChatting, user2ID, sessionID, now); err != nil {
+		return 0, err
+	}
+	if _, err := tx.ExecContext(ctx, query, user2ID, constants.StatusChatting, user1ID, sessionID, now); err != nil {
+		return 0, err
+	}
+
+	// Increment total chats
+	if _, err := sqliteIncrement(ctx, tx, constants.TableUserStats, user1ID, "total_chats"); err != nil {
+		return 0, err
+	}
+	if _, err := sqliteIncrement(ctx, tx, constants.TableUserStats, user2ID, "total_chats"); err != nil {
+		return 0, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+	return sessionID, nil
+}
+
+func (r sqliteSessions) Disconnect(ctx context.Context, user1ID, user2ID, endedBy int64, reason string) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// End session di database
+	if err := sqliteEndSession(ctx, tx, user1ID, user2ID, endedBy, reason); err != nil {
+		return err
+	}
+
+	// Reset status, partner dan sesi untuk kedua user
+	query := `
+		UPDATE user_state
+		SET status = ?3, partner_id = NULL, session_id = NULL, updated_at = ?5
+		WHERE user_id = ?1 AND (partner_id = ?2 OR (partner_id IS NULL AND status = ?4))
+	`
+	now := sqliteNow()
+	if _, err := tx.ExecContext(ctx, query, user1ID, user2ID, constants.StatusIdle, constants.StatusChatting, now); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, query, user2ID, user1ID, constants.StatusIdle, constants.StatusChatting, now); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// sqliteUserStates membuat baris user_state jika belum ada lalu membacanya di dalam transaksi
+func sqliteUserStates(ctx context.Context, tx *sql.Tx, userIDs ...int64) (map[int64]lockedState, error) {
+	states := make(map[int64]lockedState, len(userIDs))
+	for _, userID := range userIDs {
+		if _, err := tx.ExecContext(ctx, `INSERT INTO user_state (user_id) VALUES (?) ON CONFLICT (user_id) DO NOTHING`, userID); err != nil {
+			return nil, err
+		}
+
+		var state lockedState
+		err := tx.QueryRowContext(ctx, `SELECT status, search_started_at FROM user_state WHERE user_id = ?`, userID).
+			Scan(&state.Status, &state.SearchStartedAt)
+		if err != nil {
+			return nil, err
+		}
+		states[userID] = state
+	}
+	return states, nil
+}
+
+func (r sqliteSessions) Create(ctx context.Context, user1ID, user2ID int64, matchMode string, distanceKm float64, user1Wait, user2Wait int) (int64, error) {
+	return sqliteCreateSession(ctx, r.db, user1ID, user2ID, matchMode, distanceKm, user1Wait, user2Wait)
+}
+
+func sqliteCreateSession(ctx context.Context, q sqlQuerier, user1ID, user2ID int64, matchMode string, distanceKm float64, user1Wait, user2Wait int) (int64, error) {
+	query := `
+		INSERT INTO chat_sessions (user1_id, user2_id, started_at, is_active, last_message_at,
+			match_mode, distance_km, user1_wait_seconds, user2_wait_seconds)
+		VALUES (?1, ?2, ?3, TRUE, ?3, ?4, ?5, ?6, ?7)
+		RETURNING id
+	`
+	var sessionID int64
+	err := q.QueryRowContext(ctx, query, user1ID, user2ID, sqliteNow(), matchMode,
+		matchDistance(matchMode, distanceKm), user1Wait, user2Wait).Scan(&sessionID)
+	return sessionID, err
+}
+
+func (r sqliteSessions) End(ctx context.Context, user1ID, user2ID, endedBy int64, reason string) error {
+	return sqliteEndSession(ctx, r.db, user1ID, user2ID, endedBy, reason)
+}
+
+func sqliteEndSession(ctx context.Context, q sqlQuerier, user1ID, user2ID, endedBy int64, reason string) error {
+	query := `
+		UPDATE chat_sessions
+		SET is_active = FALSE, ended_at = ?1, end_reason = ?4, ended_by = ?5
+		WHERE ((user1_id = ?2 AND user2_id = ?3) OR (user1_id = ?3 AND user2_id = ?2))
+		AND is_active = TRUE
+	`
+	_, err := q.ExecContext(ctx, query, sqliteNow(), user1ID, user2ID, reason, endedByValue(endedBy))
+	return err
+}
+
+func (r sqliteSessions) ActiveID(ctx context.Context, userID int64) (int64, error) {
+	query := `
+		SELECT id FROM chat_sessions
+		WHERE (user1_id = ?1 OR user2_id = ?1) AND is_active = TRUE
+		ORDER BY started_at DESC
+		LIMIT 1
+	`
+	var sessionID int64
+	err := r.db.QueryRowContext(ctx, query, userID).Scan(&sessionID)
+	return sessionID, err
+}
+
+func (r sqliteSessions) CountActive(ctx context.Context) (int64, error) {
+	var count int64
+	err := r.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM chat_sessions WHERE is_active = TRUE").Scan(&count)
+	return count, err
+}
+
+func (r sqliteSessions) Active(ctx context.Context) ([]ChatSession, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT id, user1_id, user2_id, started_at, ended_at, is_active, last_message_at
+		FROM chat_sessions
+		WHERE is_active = TRUE
+		ORDER BY id ASC
+	`)
+	if err != nil {
+		return nil, err
+	}
+	return sqliteScanSessions(rows)
+}
+
+func (r sqliteSessions) Idle(ctx context.Context, idleFor time.Duration, onlyUnwarned bool) ([]ChatSession, error) {
+	query := `
+		SELECT id, user1_id, user2_id, started_at, ended_at, is_active, last_message_at
+		FROM chat_sessions
+		WHERE is_active = TRUE AND last_message_at < ?
+	`
+	if onlyUnwarned {
+		query += ` AND idle_warned_at IS NULL`
+	}
+	query += ` ORDER BY last_message_at ASC`
+
+	rows, err := r.db.QueryContext(ctx, query, sqliteNow().Add(-idleFor))
+	if err != nil {
+		return nil, err
+	}
+	return sqliteScanSessions(rows)
+}
+
+func (r sqliteSessions) Orphaned(ctx context.Context) ([]ChatSession, error) {
+	query := `
+		SELECT s.id, s.user1_id, s.user2_id, s.started_at, s.ended_at, s.is_active, s.last_message_at
+		FROM chat_sessions s
+		LEFT JOIN user_state a ON a.user_id = s.user1_id
+		LEFT JOIN user_state b ON b.user_id = s.user2_id
+		WHERE s.is_active = TRUE
+		AND NOT (a.status IS ?1 AND a.partner_id IS s.user2_id
+			AND b.status IS ?1 AND b.partner_id IS s.user1_id)
+	`
+	rows, err := r.db.QueryContext(ctx, query, constants.StatusChatting)
+	if err != nil {
+		return nil, err
+	}
+	return sqliteScanSessions(rows)
+}
+
+// sqliteScanSessions membaca baris chat_sessions lalu menutup rows
+func sqliteScanSessions(rows *sql.Rows) ([]ChatSession, error) {
+	defer rows.Close()
+
+	var sessions []ChatSession
+	for rows.Next() {
+		var session ChatSession
+		if err := rows.Scan(&session.ID, &session.User1ID, &session.User2ID,
+			&session.StartedAt, &session.EndedAt, &session.IsActive, &session.LastMessageAt); err != nil {
+			return nil, err
+		}
+		sessions = append(sessions, session)
+	}
+	return sessions, rows.Err()
+}
+
+func (r sqliteSessions) Touch(ctx context.Context, sessionID, senderID int64) error {
+	query := `
+		UPDATE chat_sessions SET
+			last_message_at = ?1,
+			idle_warned_at = NULL,
+			user1_messages = user1_messages + CASE WHEN user1_id = ?3 THEN 1 ELSE 0 END,
+			user2_messages = user2_messages + CASE WHEN user2_id = ?3 THEN 1 ELSE 0 END
+		WHERE id = ?2 AND is_active = TRUE
+	`
+	_, err := r.db.ExecContext(ctx, query, sqliteNow(), sessionID, senderID)
+	return err
+}
+
+func (r sqliteSessions) MarkIdleWarned(ctx context.Context, sessionID int64) error {
+	_, err := r.db.ExecContext(ctx, `UPDATE chat_sessions SET idle_warned_at = ? WHERE id = ?`, sqliteNow(), sessionID)
+	return err
+}
+
+func (r sqliteSessions) Participants(ctx context.Context, sessionID int64) (user1ID, user2ID int64, err error) {
+	err = r.db.QueryRowContext(ctx, `SELECT user1_id, user2_id FROM chat_sessions WHERE id = ?`, sessionID).Scan(&user1ID, &user2ID)
+	if errors.Is(err, sql.ErrNoRows) {
+		err = ErrNotFound
+	}
+	return
+}
+
+func (r sqliteSessions) Rate(ctx context.Context, sessionID, raterID, rateeID int64, score int) (bool, error) {
+	query := `
+		INSERT INTO session_ratings (session_id, rater_id, ratee_id, score, created_at)
+		VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT (session_id, rater_id) DO NOTHING
+	`
+	result, err := r.db.ExecContext(ctx, query, sessionID, raterID, rateeID, score, sqliteNow())
+	if err != nil {
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	return affected > 0, err
+}
+
+func (r sqliteSessions) RatingCounts(ctx context.Context, userID int64) (up, down int, err error) {
+	query := `
+		SELECT
+			COALESCE(SUM(CASE WHEN score > 0 THEN 1 ELSE 0 END), 0),
+			COALESCE(SUM(CASE WHEN score < 0 THEN 1 ELSE 0 END), 0)
+		FROM session_ratings WHERE ratee_id = ?
+	`
+	err = r.db.QueryRowContext(ctx, query, userID).Scan(&up, &down)
+	return
+}
+
+func (r sqliteSessions) Reset(ctx context.Context) error {
+	// Rating ikut dihapus, sama seperti TRUNCATE ... CASCADE di Postgres
+	if _, err := r.db.ExecContext(ctx, "DELETE FROM session_ratings"); err != nil {
+		return err
+	}
+	_, err := r.db.ExecContext(ctx, "DELETE FROM chat_sessions")
+	return err
+}
+
+// ============================================================
+// MESSAGES
+// ============================================================
+
+type sqliteMessages struct {
+	db *sql.DB
+}
+
+func (r sqliteMessages) Save(ctx context.Context, sessionID, senderID, receiverID int64, msgType, content string) error {
+	query := `
+		INSERT INTO messages (session_id, sender_id, receiver_id, message_type, content, created_at)
+		VALUES (?, ?, ?, ?, ?, ?)
+	`
+	_, err := r.db.ExecContext(ctx, query, sessionID, senderID, receiverID, msgType, content, sqliteNow())
+	return err
+}
+
+func (r sqliteMessages) Reset(ctx context.Context) error {
+	_, err := r.db.ExecContext(ctx, "DELETE FROM messages")
+	return err
+}
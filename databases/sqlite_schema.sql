@@ -0,0 +1,108 @@
+-- Skema SQLite (DATABASE_DRIVER=sqlite). Padanan tabel hasil migrasi Postgres, tanpa tabel analytics harian.
+-- Semua statement idempotent dan dijalankan setiap start.
+
+CREATE TABLE IF NOT EXISTS users (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	telegram_id BIGINT UNIQUE NOT NULL,
+	username VARCHAR(255),
+	first_name VARCHAR(255),
+	status VARCHAR(50) DEFAULT 'idle',
+	partner_id BIGINT DEFAULT NULL,
+	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS chat_sessions (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	user1_id BIGINT NOT NULL,
+	user2_id BIGINT NOT NULL,
+	started_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	ended_at TIMESTAMP DEFAULT NULL,
+	is_active BOOLEAN DEFAULT TRUE,
+	last_message_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	idle_warned_at TIMESTAMP DEFAULT NULL,
+	end_reason VARCHAR(50) DEFAULT NULL,
+	match_mode VARCHAR(20) DEFAULT NULL,
+	distance_km DOUBLE PRECISION DEFAULT NULL,
+	user1_wait_seconds INT DEFAULT NULL,
+	user2_wait_seconds INT DEFAULT NULL,
+	user1_messages INT DEFAULT 0,
+	user2_messages INT DEFAULT 0,
+	ended_by BIGINT DEFAULT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_sessions_last_message ON chat_sessions(is_active, last_message_at);
+CREATE INDEX IF NOT EXISTS idx_sessions_user1 ON chat_sessions(user1_id);
+CREATE INDEX IF NOT EXISTS idx_sessions_user2 ON chat_sessions(user2_id);
+
+CREATE TABLE IF NOT EXISTS messages (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	session_id INT REFERENCES chat_sessions(id),
+	sender_id BIGINT NOT NULL,
+	receiver_id BIGINT NOT NULL,
+	message_type VARCHAR(50) DEFAULT 'text',
+	content TEXT,
+	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS vars (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	user_id BIGINT NOT NULL,
+	var_key VARCHAR(255) NOT NULL,
+	var_value TEXT,
+	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	UNIQUE(user_id, var_key)
+);
+
+CREATE TABLE IF NOT EXISTS session_ratings (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	session_id INT NOT NULL REFERENCES chat_sessions(id),
+	rater_id BIGINT NOT NULL,
+	ratee_id BIGINT NOT NULL,
+	score SMALLINT NOT NULL,
+	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	UNIQUE(session_id, rater_id)
+);
+CREATE INDEX IF NOT EXISTS idx_ratings_ratee ON session_ratings(ratee_id);
+
+CREATE TABLE IF NOT EXISTS user_profiles (
+	user_id BIGINT PRIMARY KEY,
+	name VARCHAR(255),
+	age SMALLINT,
+	gender VARCHAR(20),
+	location VARCHAR(255),
+	latitude DOUBLE PRECISION,
+	longitude DOUBLE PRECISION,
+	interests TEXT,
+	card_fields VARCHAR(255),
+	search_mode VARCHAR(20),
+	is_registered BOOLEAN NOT NULL DEFAULT FALSE,
+	registered_at TIMESTAMP DEFAULT NULL,
+	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS user_state (
+	user_id BIGINT PRIMARY KEY,
+	status VARCHAR(20) NOT NULL DEFAULT 'idle',
+	partner_id BIGINT DEFAULT NULL,
+	session_id BIGINT DEFAULT NULL,
+	search_started_at TIMESTAMP DEFAULT NULL,
+	warn_count INT NOT NULL DEFAULT 0,
+	is_banned BOOLEAN NOT NULL DEFAULT FALSE,
+	is_inactive BOOLEAN NOT NULL DEFAULT FALSE,
+	low_rep_flag BOOLEAN NOT NULL DEFAULT FALSE,
+	last_active TIMESTAMP DEFAULT NULL,
+	updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS idx_user_state_status ON user_state(status);
+
+CREATE TABLE IF NOT EXISTS user_stats (
+	user_id BIGINT PRIMARY KEY,
+	total_chats INT NOT NULL DEFAULT 0,
+	total_messages INT NOT NULL DEFAULT 0,
+	reputation DOUBLE PRECISION DEFAULT NULL,
+	rating_count INT NOT NULL DEFAULT 0,
+	ads_counter INT NOT NULL DEFAULT 0,
+	updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
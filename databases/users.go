@@ -0,0 +1,232 @@
+package databases
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"tg-anon-go/cache"
+	"tg-anon-go/constants"
+)
+
+// UserProfile adalah data profil user (tabel user_profiles)
+type UserProfile struct {
+	UserID       int64
+	Name         string
+	Age          int
+	Gender       string
+	Location     string
+	Latitude     float64
+	Longitude    float64
+	Interests    string
+	CardFields   string
+	SearchMode   string
+	IsRegistered bool
+	RegisteredAt *time.Time
+}
+
+// AgeString mengembalikan umur sebagai string ("" jika belum diisi)
+func (p *UserProfile) AgeString() string {
+	if p.Age == 0 {
+		return ""
+	}
+	return strconv.Itoa(p.Age)
+}
+
+// HasLocation mengecek apakah profil memiliki koordinat
+func (p *UserProfile) HasLocation() bool {
+	return p.Latitude != 0 || p.Longitude != 0
+}
+
+// UserState adalah status chat dan flag moderasi user (tabel user_state)
+type UserState struct {
+	UserID          int64
+	Status          string
+	PartnerID       int64
+	SessionID       int64
+	SearchStartedAt *time.Time
+	WarnCount       int
+	IsBanned        bool
+	IsInactive      bool
+	LowRepFlag      bool
+	LastActive      *time.Time
+}
+
+// UserStats adalah counter dan reputasi user (tabel user_stats)
+type UserStats struct {
+	UserID        int64
+	TotalChats    int
+	TotalMessages int
+	Reputation    float64
+	RatingCount   int
+	AdsCounter    int
+}
+
+// GetUserProfile mengambil profil user. User tanpa profil mendapat profil kosong.
+func GetUserProfile(ctx context.Context, userID int64) (*UserProfile, error) {
+	return Users.Profile(ctx, userID)
+}
+
+// GetUserState mengambil state user. User tanpa state dianggap idle.
+func GetUserState(ctx context.Context, userID int64) (*UserState, error) {
+	return Users.State(ctx, userID)
+}
+
+// GetUserStatsByID mengambil counter dan reputasi user
+func GetUserStatsByID(ctx context.Context, userID int64) (*UserStats, error) {
+	return Users.Stats(ctx, userID)
+}
+
+// ============================================================
+// PROFILE
+// ============================================================
+
+// SetProfileName menyimpan nama user
+func SetProfileName(ctx context.Context, userID int64, name string) error {
+	return Users.Update(ctx, constants.TableUserProfiles, userID, []string{"name"}, name)
+}
+
+// SetProfileAge menyimpan umur user
+func SetProfileAge(ctx context.Context, userID int64, age int) error {
+	return Users.Update(ctx, constants.TableUserProfiles, userID, []string{"age"}, age)
+}
+
+// SetProfileGender menyimpan jenis kelamin user
+func SetProfileGender(ctx context.Context, userID int64, gender string) error {
+	return Users.Update(ctx, constants.TableUserProfiles, userID, []string{"gender"}, gender)
+}
+
+// SetProfileLocation menyimpan nama lokasi beserta koordinatnya
+func SetProfileLocation(ctx context.Context, userID int64, location string, lat, lon float64) error {
+	return Users.Update(ctx, constants.TableUserProfiles, userID,
+		[]string{"location", "latitude", "longitude"}, location, lat, lon)
+}
+
+// SetProfileInterests menyimpan minat/hobi user
+func SetProfileInterests(ctx context.Context, userID int64, interests string) error {
+	return Users.Update(ctx, constants.TableUserProfiles, userID, []string{"interests"}, interests)
+}
+
+// SetSearchMode menyimpan mode pencarian terakhir user
+func SetSearchMode(ctx context.Context, userID int64, searchMode string) error {
+	return Users.Update(ctx, constants.TableUserProfiles, userID, []string{"search_mode"}, searchMode)
+}
+
+// GetSearchMode mengambil mode pencarian terakhir user (default random)
+func GetSearchMode(ctx context.Context, userID int64) string {
+	profile, err := Users.Profile(ctx, userID)
+	if err != nil || profile.SearchMode == "" {
+		return constants.SearchModeRandom
+	}
+	return profile.SearchMode
+}
+
+// MarkRegistered menandai user sudah menyelesaikan registrasi
+func MarkRegistered(ctx context.Context, userID int64) error {
+	return Users.MarkRegistered(ctx, userID)
+}
+
+// IsUserRegistered mengecek apakah user sudah registrasi
+func IsUserRegistered(ctx context.Context, userID int64) (bool, error) {
+	profile, err := Users.Profile(ctx, userID)
+	if err != nil {
+		return false, err
+	}
+	return profile.IsRegistered, nil
+}
+
+// GetUserLocation mengambil koordinat lokasi user
+func GetUserLocation(ctx context.Context, userID int64) (lat, lon float64, err error) {
+	profile, err := Users.Profile(ctx, userID)
+	if err != nil {
+		return 0, 0, err
+	}
+	return profile.Latitude, profile.Longitude, nil
+}
+
+// HasLocation mengecek apakah user memiliki lokasi tersimpan
+func HasLocation(ctx context.Context, userID int64) bool {
+	lat, lon, _ := GetUserLocation(ctx, userID)
+	return lat != 0 || lon != 0
+}
+
+// ============================================================
+// STATS
+// ============================================================
+
+// IncrementUserTotalChats menambah total chat user
+func IncrementUserTotalChats(ctx context.Context, userID int64) error {
+	_, err := Users.Increment(ctx, constants.TableUserStats, userID, "total_chats")
+	return err
+}
+
+// IncrementUserTotalMessages menambah total pesan user.
+// Jika Redis tersedia, counter ditambah di Redis dan di-flush berkala oleh FlushCounters.
+func IncrementUserTotalMessages(ctx context.Context, userID int64) error {
+	if cache.Enabled() {
+		if err := cache.IncrCounter(ctx, userID, cache.CounterTotalMessages); err == nil {
+			return nil
+		}
+	}
+	_, err := Users.Increment(ctx, constants.TableUserStats, userID, "total_messages")
+	return err
+}
+
+// IncrementAdsCounter menambah counter pesan untuk interval ads dan mengembalikan nilai barunya
+func IncrementAdsCounter(ctx context.Context, userID int64) (int, error) {
+	if cache.Enabled() {
+		count, ok, err := cache.IncrAdsCounter(ctx, userID)
+		if err == nil && !ok {
+			// Counter belum ada di Redis (restart / cache kosong): lanjutkan dari nilai di Postgres.
+			// Counter yang di-reset ke 0 tetap ada di Redis sehingga tidak diisi ulang dari sini.
+			var seed int64
+			if stats, statsErr := Users.Stats(ctx, userID); statsErr == nil {
+				seed = int64(stats.AdsCounter)
+			}
+			count, err = cache.SeedAdsCounter(ctx, userID, seed)
+		}
+		if err == nil {
+			return int(count), nil
+		}
+	}
+	return Users.Increment(ctx, constants.TableUserStats, userID, "ads_counter")
+}
+
+// ResetAdsCounter mereset counter pesan ads setelah ads dikirim
+func ResetAdsCounter(ctx context.Context, userID int64) error {
+	if cache.Enabled() {
+		if err := cache.ResetAdsCounter(ctx, userID); err == nil {
+			return nil
+		}
+	}
+	return Users.Update(ctx, constants.TableUserStats, userID, []string{"ads_counter"}, 0)
+}
+
+// counterFlushBatch adalah jumlah user maksimal per batch flush counter
+const counterFlushBatch = 500
+
+// FlushCounters menulis counter yang terkumpul di Redis ke user_stats dalam batch.
+// Mengembalikan jumlah user yang di-flush.
+func FlushCounters(ctx context.Context) (int, error) {
+	flushed := 0
+	for {
+		deltas, err := cache.PopDirtyCounters(ctx, counterFlushBatch)
+		if err != nil {
+			cache.RestoreCounters(ctx, deltas)
+			return flushed, err
+		}
+		if len(deltas) == 0 {
+			return flushed, nil
+		}
+
+		if err := Users.ApplyCounters(ctx, deltas); err != nil {
+			cache.RestoreCounters(ctx, deltas)
+			return flushed, err
+		}
+		flushed += len(deltas)
+
+		if len(deltas) < counterFlushBatch {
+			return flushed, nil
+		}
+	}
+}
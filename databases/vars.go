@@ -5,8 +5,6 @@ import (
 	"encoding/json"
 	"strconv"
 	"time"
-
-	"github.com/jackc/pgx/v5"
 )
 
 // Var represents a variable in the database
@@ -44,28 +42,12 @@ func SetVar(ctx context.Context, userID int64, key string, value interface{}) er
 		strValue = string(jsonBytes)
 	}
 
-	query := `
-		INSERT INTO vars (user_id, var_key, var_value, updated_at)
-		VALUES ($1, $2, $3, $4)
-		ON CONFLICT (user_id, var_key)
-		DO UPDATE SET var_value = $3, updated_at = $4
-	`
-	_, err := DB.Exec(ctx, query, userID, key, strValue, time.Now())
-	return err
+	return Vars.Set(ctx, userID, key, strValue)
 }
 
 // GetVar mengambil variabel sebagai string
 func GetVar(ctx context.Context, userID int64, key string) (string, error) {
-	query := `SELECT var_value FROM vars WHERE user_id = $1 AND var_key = $2`
-	var value string
-	err := DB.QueryRow(ctx, query, userID, key).Scan(&value)
-	if err != nil {
-		if err == pgx.ErrNoRows {
-			return "", nil
-		}
-		return "", err
-	}
-	return value, nil
+	return Vars.Get(ctx, userID, key)
 }
 
 // GetVarInt mengambil variabel sebagai int
@@ -130,44 +112,23 @@ func GetVarJSON(ctx context.Context, userID int64, key string, dest interface{})
 
 // DeleteVar menghapus variabel tertentu
 func DeleteVar(ctx context.Context, userID int64, key string) error {
-	query := `DELETE FROM vars WHERE user_id = $1 AND var_key = $2`
-	_, err := DB.Exec(ctx, query, userID, key)
-	return err
+	return Vars.Delete(ctx, userID, key)
 }
 
 // DeleteAllVars menghapus semua variabel untuk user tertentu
 func DeleteAllVars(ctx context.Context, userID int64) error {
-	query := `DELETE FROM vars WHERE user_id = $1`
-	_, err := DB.Exec(ctx, query, userID)
-	return err
+	return Vars.DeleteAll(ctx, userID)
 }
 
 // GetAllVars mengambil semua variabel untuk user tertentu
 func GetAllVars(ctx context.Context, userID int64) (map[string]string, error) {
-	query := `SELECT var_key, var_value FROM vars WHERE user_id = $1`
-	rows, err := DB.Query(ctx, query, userID)
-	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
-
-	vars := make(map[string]string)
-	for rows.Next() {
-		var key, value string
-		if err := rows.Scan(&key, &value); err != nil {
-			return nil, err
-		}
-		vars[key] = value
-	}
-	return vars, nil
+	return Vars.All(ctx, userID)
 }
 
 // HasVar mengecek apakah variabel ada
 func HasVar(ctx context.Context, userID int64, key string) (bool, error) {
-	query := `SELECT EXISTS(SELECT 1 FROM vars WHERE user_id = $1 AND var_key = $2)`
-	var exists bool
-	err := DB.QueryRow(ctx, query, userID, key).Scan(&exists)
-	return exists, err
+	value, err := Vars.Get(ctx, userID, key)
+	return value != "", err
 }
 
 // SetGlobalVar menyimpan variabel global (userID = 0)
@@ -259,23 +220,3 @@ func atanApprox(y, x float64) float64 {
 	}
 	return y / x // Simplified for small values
 }
-
-// GetUserLocation mengambil koordinat lokasi user
-func GetUserLocation(ctx context.Context, userID int64) (lat, lon float64, err error) {
-	lat, err = GetVarFloat64(ctx, userID, "latitude")
-	if err != nil {
-		return 0, 0, err
-	}
-	lon, err = GetVarFloat64(ctx, userID, "longitude")
-	if err != nil {
-		return 0, 0, err
-	}
-	return lat, lon, nil
-}
-
-// HasLocation mengecek apakah user memiliki lokasi tersimpan
-func HasLocation(ctx context.Context, userID int64) bool {
-	lat, _ := GetVarFloat64(ctx, userID, "latitude")
-	lon, _ := GetVarFloat64(ctx, userID, "longitude")
-	return lat != 0 || lon != 0
-}
@@ -3,14 +3,20 @@ package matcher
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"math"
+	"sort"
+	"strings"
 	"sync"
 	"time"
 
+	"tg-anon-go/config"
 	"tg-anon-go/constants"
 	"tg-anon-go/databases"
+	"tg-anon-go/sender"
+	"tg-anon-go/settings"
 
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
 	"github.com/redis/go-redis/v9"
@@ -27,7 +33,6 @@ const (
 	KeyMatchLock      = "match:lock:%d"   // Lock to prevent double matching
 	LockExpiration    = 10 * time.Second  // Lock expiration time
 	UserDataTTL       = 5 * time.Minute   // User data expiration
-	MaxNearbyDistance = 100.0             // Maximum distance in km for nearby search
 )
 
 // SearchRequest represents a search request from a user
@@ -37,6 +42,8 @@ type SearchRequest struct {
 	Latitude   float64 `json:"latitude,omitempty"`
 	Longitude  float64 `json:"longitude,omitempty"`
 	Timestamp  int64   `json:"timestamp"`
+	Reputation float64 `json:"reputation"`
+	Restricted bool    `json:"restricted,omitempty"` // Reputasi rendah, hanya dipasangkan sesama restricted
 }
 
 // Matcher menangani realtime matching menggunakan Redis Pub/Sub
@@ -51,25 +58,12 @@ type Matcher struct {
 	cancelCtx context.CancelFunc
 }
 
-// NewMatcher membuat instance Matcher baru dengan Redis
-func NewMatcher(bot *tgbotapi.BotAPI, redisURL string) (*Matcher, error) {
-	// Parse Redis URL
-	opt, err := redis.ParseURL(redisURL)
-	if err != nil {
-		return nil, fmt.Errorf("failed to parse Redis URL: %w", err)
-	}
-
-	// Create Redis client
-	rdb := redis.NewClient(opt)
-
-	// Test connection
-	ctx := context.Background()
-	if err := rdb.Ping(ctx).Err(); err != nil {
-		return nil, fmt.Errorf("failed to connect to Redis: %w", err)
+// NewMatcher membuat instance Matcher baru dengan client Redis bersama (cache.Redis)
+func NewMatcher(bot *tgbotapi.BotAPI, rdb *redis.Client) (*Matcher, error) {
+	if rdb == nil {
+		return nil, fmt.Errorf("redis client is not initialized")
 	}
 
-	log.Println("✅ Connected to Redis successfully")
-
 	ctx, cancel := context.WithCancel(context.Background())
 
 	return &Matcher{
@@ -124,10 +118,7 @@ func (m *Matcher) Stop() {
 	close(m.stopChan)
 	m.wg.Wait()
 
-	if err := m.rdb.Close(); err != nil {
-		log.Printf("Error closing Redis connection: %v", err)
-	}
-
+	// Koneksi Redis ditutup oleh pemiliknya (cache.CloseRedis)
 	log.Println("⏹️ Redis Matcher stopped")
 }
 
@@ -140,6 +131,7 @@ func (m *Matcher) PublishSearch(ctx context.Context, userID int64, searchMode st
 		Longitude:  lon,
 		Timestamp:  time.Now().Unix(),
 	}
+	applyReputation(ctx, &req)
 
 	data, err := json.Marshal(req)
 	if err != nil {
@@ -268,7 +260,7 @@ func (m *Matcher) handleRandomSearch(req *SearchRequest) {
 	defer m.rdb.Del(ctx, lockKey)
 
 	// Check if user still searching in database
-	status, _ := databases.GetVar(ctx, req.UserID, constants.VarStatus)
+	status, _ := databases.GetUserStatus(ctx, req.UserID)
 	if status != constants.StatusSearching {
 		m.RemoveSearchingUser(ctx, req.UserID)
 		return
@@ -280,7 +272,8 @@ func (m *Matcher) handleRandomSearch(req *SearchRequest) {
 		log.Printf("Error getting searching users: %v", err)
 		return
 	}
-	// Find a partner
+	// Collect compatible partners
+	var candidates []SearchRequest
 	for _, memberStr := range members {
 		var partnerID int64
 		fmt.Sscanf(memberStr, "%d", &partnerID)
@@ -301,6 +294,21 @@ func (m *Matcher) handleRandomSearch(req *SearchRequest) {
 			continue
 		}
 
+		if !isCompatible(req, &partnerReq) {
+			continue
+		}
+		candidates = append(candidates, partnerReq)
+	}
+
+	// Prioritaskan partner dengan reputasi paling mirip
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return math.Abs(candidates[i].Reputation-req.Reputation) < math.Abs(candidates[j].Reputation-req.Reputation)
+	})
+
+	// Find a partner
+	for _, partnerReq := range candidates {
+		partnerID := partnerReq.UserID
+
 		// Try to lock partner
 		partnerLockKey := fmt.Sprintf(KeyMatchLock, partnerID)
 		partnerLocked, err := m.rdb.SetNX(ctx, partnerLockKey, "1", LockExpiration).Result()
@@ -309,17 +317,16 @@ func (m *Matcher) handleRandomSearch(req *SearchRequest) {
 		}
 
 		// Check if partner still searching
-		partnerStatus, _ := databases.GetVar(ctx, partnerID, constants.VarStatus)
-		if partnerStatus != constants.StatusSearching {
+		if !isAvailable(ctx, partnerID) {
 			m.rdb.Del(ctx, partnerLockKey)
 			m.RemoveSearchingUser(ctx, partnerID)
 			continue
 		}
 
 		// Match found! Connect users
-		_, err = databases.ConnectUsers(ctx, req.UserID, partnerID)
+		_, err = databases.ConnectUsers(ctx, req.UserID, partnerID, constants.SearchModeRandom, 0)
 		if err != nil {
-			log.Printf("Error connecting users: %v", err)
+			logConnectError(err)
 			m.rdb.Del(ctx, partnerLockKey)
 			continue
 		}
@@ -352,7 +359,7 @@ func (m *Matcher) handleNearbySearch(req *SearchRequest) {
 	defer m.rdb.Del(ctx, lockKey)
 
 	// Check if user still searching
-	status, _ := databases.GetVar(ctx, req.UserID, constants.VarStatus)
+	status, _ := databases.GetUserStatus(ctx, req.UserID)
 	if status != constants.StatusSearching {
 		m.RemoveSearchingUser(ctx, req.UserID)
 		return
@@ -401,6 +408,10 @@ func (m *Matcher) handleNearbySearch(req *SearchRequest) {
 			continue
 		}
 
+		if !isCompatible(req, &partnerReq) {
+			continue
+		}
+
 		// Calculate distance (use max distance if partner has no location)
 		var distance float64
 		if partnerReq.Latitude != 0 || partnerReq.Longitude != 0 {
@@ -428,9 +439,9 @@ func (m *Matcher) handleNearbySearch(req *SearchRequest) {
 	// Try to match with closest available partner (within max distance)
 	for _, candidate := range candidates {
 		// Skip if too far away
-		if candidate.distance > MaxNearbyDistance {
+		if candidate.distance > maxNearbyDistance() {
 			log.Printf("⏩ Skipping user %d for user %d - too far away (%.1f km > %.0f km max)",
-				candidate.partnerID, req.UserID, candidate.distance, MaxNearbyDistance)
+				candidate.partnerID, req.UserID, candidate.distance, maxNearbyDistance())
 			continue
 		}
 
@@ -442,17 +453,22 @@ func (m *Matcher) handleNearbySearch(req *SearchRequest) {
 		}
 
 		// Check if partner still searching
-		partnerStatus, _ := databases.GetVar(ctx, candidate.partnerID, constants.VarStatus)
-		if partnerStatus != constants.StatusSearching {
+		if !isAvailable(ctx, candidate.partnerID) {
 			m.rdb.Del(ctx, partnerLockKey)
 			m.RemoveSearchingUser(ctx, candidate.partnerID)
 			continue
 		}
 
+		// Partner tanpa lokasi dihitung sebagai random match
+		matchMode := constants.SearchModeRandom
+		if candidate.distance < 9999 {
+			matchMode = constants.SearchModeNearby
+		}
+
 		// Match found! Connect users
-		_, err = databases.ConnectUsers(ctx, req.UserID, candidate.partnerID)
+		_, err = databases.ConnectUsers(ctx, req.UserID, candidate.partnerID, matchMode, candidate.distance)
 		if err != nil {
-			log.Printf("Error connecting users: %v", err)
+			logConnectError(err)
 			m.rdb.Del(ctx, partnerLockKey)
 			continue
 		}
@@ -463,7 +479,7 @@ func (m *Matcher) handleNearbySearch(req *SearchRequest) {
 		m.rdb.Del(ctx, partnerLockKey)
 
 		// Notify both users (show distance if nearby)
-		if candidate.distance < 9999 {
+		if matchMode == constants.SearchModeNearby {
 			m.notifyMatch(req.UserID, candidate.partnerID, constants.SearchModeNearby, candidate.distance)
 			log.Printf("✅ Nearby Match: User %d <-> User %d (%.2f km)", req.UserID, candidate.partnerID, candidate.distance)
 		} else {
@@ -473,7 +489,7 @@ func (m *Matcher) handleNearbySearch(req *SearchRequest) {
 		return
 	}
 
-	log.Printf("⏳ No nearby partner within %.0fkm for user %d, will retry", MaxNearbyDistance, req.UserID)
+	log.Printf("⏳ No nearby partner within %.0fkm for user %d, will retry", maxNearbyDistance(), req.UserID)
 }
 
 // cleanupWorker removes stale searching users periodically
@@ -510,7 +526,7 @@ func (m *Matcher) cleanup() {
 		fmt.Sscanf(memberStr, "%d", &userID)
 
 		// Check database status
-		status, _ := databases.GetVar(ctx, userID, constants.VarStatus)
+		status, _ := databases.GetUserStatus(ctx, userID)
 		if status != constants.StatusSearching {
 			m.RemoveSearchingUser(ctx, userID)
 		}
@@ -568,7 +584,7 @@ func (m *Matcher) retryMatchAllUsers() {
 		fmt.Sscanf(memberStr, "%d", &userID)
 
 		// Verify still searching in database
-		status, _ := databases.GetVar(ctx, userID, constants.VarStatus)
+		status, _ := databases.GetUserStatus(ctx, userID)
 		if status != constants.StatusSearching {
 			m.RemoveSearchingUser(ctx, userID)
 			continue
@@ -579,14 +595,10 @@ func (m *Matcher) retryMatchAllUsers() {
 		userData, err := m.rdb.Get(ctx, userKey).Result()
 		if err != nil {
 			// No data means TTL expired, refresh it
-			searchMode, _ := databases.GetVar(ctx, userID, constants.VarSearchMode)
-			if searchMode == "" {
-				searchMode = constants.SearchModeRandom
-			}
+			searchMode := databases.GetSearchMode(ctx, userID)
 			var lat, lon float64
 			if searchMode == constants.SearchModeNearby {
-				lat, _ = databases.GetVarFloat64(ctx, userID, constants.VarLatitude)
-				lon, _ = databases.GetVarFloat64(ctx, userID, constants.VarLongitude)
+				lat, lon, _ = databases.GetUserLocation(ctx, userID)
 			}
 
 			req := SearchRequest{
@@ -596,6 +608,7 @@ func (m *Matcher) retryMatchAllUsers() {
 				Longitude:  lon,
 				Timestamp:  time.Now().Unix(),
 			}
+			applyReputation(ctx, &req)
 			data, _ := json.Marshal(req)
 			m.rdb.Set(ctx, userKey, data, UserDataTTL)
 
@@ -610,6 +623,11 @@ func (m *Matcher) retryMatchAllUsers() {
 		validUsers = append(validUsers, userWithData{userID: userID, searchReq: req})
 	}
 
+	// Urutkan berdasarkan reputasi supaya pasangan yang dicoba lebih dulu punya reputasi mirip
+	sort.SliceStable(validUsers, func(i, j int) bool {
+		return validUsers[i].searchReq.Reputation < validUsers[j].searchReq.Reputation
+	})
+
 	// Try to match users pairwise
 	matched := make(map[int64]bool)
 
@@ -626,6 +644,10 @@ func (m *Matcher) retryMatchAllUsers() {
 			user1 := validUsers[i]
 			user2 := validUsers[j]
 
+			if !isCompatible(&user1.searchReq, &user2.searchReq) {
+				continue
+			}
+
 			// Try to lock both users
 			lockKey1 := fmt.Sprintf(KeyMatchLock, user1.userID)
 			lockKey2 := fmt.Sprintf(KeyMatchLock, user2.userID)
@@ -642,36 +664,21 @@ func (m *Matcher) retryMatchAllUsers() {
 			}
 
 			// Double-check both still searching
-			status1, _ := databases.GetVar(ctx, user1.userID, constants.VarStatus)
-			status2, _ := databases.GetVar(ctx, user2.userID, constants.VarStatus)
+			available1 := isAvailable(ctx, user1.userID)
+			available2 := isAvailable(ctx, user2.userID)
 
-			if status1 != constants.StatusSearching || status2 != constants.StatusSearching {
+			if !available1 || !available2 {
 				m.rdb.Del(ctx, lockKey1)
 				m.rdb.Del(ctx, lockKey2)
-				if status1 != constants.StatusSearching {
+				if !available1 {
 					m.RemoveSearchingUser(ctx, user1.userID)
 				}
-				if status2 != constants.StatusSearching {
+				if !available2 {
 					m.RemoveSearchingUser(ctx, user2.userID)
 				}
 				continue
 			}
 
-			// Match found! Connect users
-			_, err = databases.ConnectUsers(ctx, user1.userID, user2.userID)
-			if err != nil {
-				log.Printf("Error connecting users in retry: %v", err)
-				m.rdb.Del(ctx, lockKey1)
-				m.rdb.Del(ctx, lockKey2)
-				continue
-			}
-
-			// Remove both from searching
-			m.RemoveSearchingUser(ctx, user1.userID)
-			m.RemoveSearchingUser(ctx, user2.userID)
-			m.rdb.Del(ctx, lockKey1)
-			m.rdb.Del(ctx, lockKey2)
-
 			// Calculate distance if both have location
 			var distance float64
 			var searchMode = constants.SearchModeRandom
@@ -685,18 +692,33 @@ func (m *Matcher) retryMatchAllUsers() {
 				// Check if within max distance for nearby mode
 				if (user1.searchReq.SearchMode == constants.SearchModeNearby ||
 					user2.searchReq.SearchMode == constants.SearchModeNearby) &&
-					distance > MaxNearbyDistance {
+					distance > maxNearbyDistance() {
 					// Skip this pair - too far for nearby mode
 					m.rdb.Del(ctx, lockKey1)
 					m.rdb.Del(ctx, lockKey2)
 					log.Printf("⏩ Skipping retry match %d <-> %d - too far (%.1f km > %.0f km)",
-						user1.userID, user2.userID, distance, MaxNearbyDistance)
+						user1.userID, user2.userID, distance, maxNearbyDistance())
 					continue
 				}
 
 				searchMode = constants.SearchModeNearby
 			}
 
+			// Match found! Connect users
+			_, err = databases.ConnectUsers(ctx, user1.userID, user2.userID, searchMode, distance)
+			if err != nil {
+				logConnectError(err)
+				m.rdb.Del(ctx, lockKey1)
+				m.rdb.Del(ctx, lockKey2)
+				continue
+			}
+
+			// Remove both from searching
+			m.RemoveSearchingUser(ctx, user1.userID)
+			m.RemoveSearchingUser(ctx, user2.userID)
+			m.rdb.Del(ctx, lockKey1)
+			m.rdb.Del(ctx, lockKey2)
+
 			// Notify both users
 			m.notifyMatch(user1.userID, user2.userID, searchMode, distance)
 
@@ -713,29 +735,121 @@ func (m *Matcher) retryMatchAllUsers() {
 	}
 }
 
-// notifyMatch mengirim notifikasi ke kedua user saat match ditemukan
+// logConnectError mencatat kegagalan ConnectUsers. User yang sudah dipasangkan
+// matcher lain (ErrUserUnavailable) bukan error.
+func logConnectError(err error) {
+	if errors.Is(err, databases.ErrUserUnavailable) {
+		log.Printf("⏩ Skipping match: %v", err)
+		return
+	}
+	log.Printf("Error connecting users: %v", err)
+}
+
+// isAvailable mengecek apakah user masih searching dan tidak memblokir bot
+func isAvailable(ctx context.Context, userID int64) bool {
+	status, _ := databases.GetUserStatus(ctx, userID)
+	if status != constants.StatusSearching {
+		return false
+	}
+	inactive, _ := databases.IsUserInactive(ctx, userID)
+	return !inactive
+}
+
+// applyReputation mengisi reputasi dan status restricted ke search request
+func applyReputation(ctx context.Context, req *SearchRequest) {
+	req.Reputation = databases.GetUserReputationScore(ctx, req.UserID)
+	req.Restricted = config.C.RestrictLowReputation && databases.IsLowReputationFlagged(ctx, req.UserID)
+}
+
+// maxNearbyDistance adalah jarak maksimal (km) pencarian nearby, bisa diubah admin lewat /config
+func maxNearbyDistance() float64 {
+	return float64(settings.NearbyRadius.Get())
+}
+
+// isCompatible mengecek apakah dua user boleh dipasangkan berdasarkan reputasi.
+// User reputasi rendah hanya dipasangkan dengan sesama user reputasi rendah.
+func isCompatible(a, b *SearchRequest) bool {
+	if !config.C.RestrictLowReputation {
+		return true
+	}
+	return a.Restricted == b.Restricted
+}
+
+// notifyMatch mengirim notifikasi ke kedua user saat match ditemukan,
+// lengkap dengan kartu profil partner sesuai pengaturan privasi masing-masing
 func (m *Matcher) notifyMatch(userID1, userID2 int64, searchMode string, distance float64) {
-	var msg1, msg2 string
+	ctx := context.Background()
 
+	header := constants.MsgPartnerFound
 	if searchMode == constants.SearchModeNearby && distance > 0 {
 		distStr := formatDistance(distance)
-		msg1 = fmt.Sprintf("🎉 Partner ditemukan! (📍 Jarak: *%s*)\n\nSilakan mulai percakapan.\nKetik /next untuk skip atau /stop untuk mengakhiri.", distStr)
-		msg2 = msg1
-	} else {
-		msg1 = constants.MsgPartnerFound
-		msg2 = constants.MsgPartnerFound
+		header = fmt.Sprintf("🎉 Partner ditemukan! (📍 Jarak: *%s*)\n\nSilakan mulai percakapan.\nKetik /next untuk skip atau /stop untuk mengakhiri.", distStr)
 	}
 
+	// Kartu user2 dikirim ke user1 dan sebaliknya
+	msg1 := header + "\n\n" + BuildPartnerCard(ctx, userID2)
+	msg2 := header + "\n\n" + BuildPartnerCard(ctx, userID1)
+
 	// Send to both users
 	m.sendMessage(userID1, msg1)
 	m.sendMessage(userID2, msg2)
 }
 
+// BuildPartnerCard membuat kartu profil user yang ditampilkan ke partner.
+// Hanya field yang diizinkan user yang dimasukkan; nama dan ID Telegram
+// hanya muncul jika user mengaktifkannya sendiri.
+func BuildPartnerCard(ctx context.Context, userID int64) string {
+	fields := databases.GetCardFields(ctx, userID)
+	profile, _ := databases.GetUserProfile(ctx, userID)
+
+	var lines []string
+	for _, field := range constants.CardFields {
+		if !fields[field] {
+			continue
+		}
+
+		switch field {
+		case constants.CardFieldAge:
+			if age := profile.AgeString(); age != "" {
+				lines = append(lines, fmt.Sprintf(constants.MsgCardFieldAge, escapeMarkdown(age)))
+			}
+		case constants.CardFieldGender:
+			if gender := profile.Gender; gender != "" {
+				lines = append(lines, fmt.Sprintf(constants.MsgCardFieldGender, escapeMarkdown(gender)))
+			}
+		case constants.CardFieldCity:
+			if city := strings.TrimSpace(strings.TrimPrefix(profile.Location, "📍")); city != "" {
+				lines = append(lines, fmt.Sprintf(constants.MsgCardFieldCity, escapeMarkdown(city)))
+			}
+		case constants.CardFieldInterests:
+			if interests := profile.Interests; interests != "" {
+				lines = append(lines, fmt.Sprintf(constants.MsgCardFieldInterests, escapeMarkdown(interests)))
+			}
+		case constants.CardFieldName:
+			if name := profile.Name; name != "" {
+				lines = append(lines, fmt.Sprintf(constants.MsgCardFieldName, escapeMarkdown(name)))
+			}
+		case constants.CardFieldTelegramID:
+			lines = append(lines, fmt.Sprintf(constants.MsgCardFieldTelegramID, userID))
+		}
+	}
+
+	if len(lines) == 0 {
+		return constants.MsgPartnerAnonim
+	}
+	return fmt.Sprintf(constants.MsgPartnerCard, strings.Join(lines, "\n"))
+}
+
+// escapeMarkdown meng-escape input user supaya tidak merusak format Markdown
+func escapeMarkdown(text string) string {
+	return tgbotapi.EscapeText(tgbotapi.ModeMarkdown, text)
+}
+
 // sendMessage mengirim pesan ke user
 func (m *Matcher) sendMessage(userID int64, text string) {
 	msg := tgbotapi.NewMessage(userID, text)
 	msg.ParseMode = "Markdown"
-	_, err := m.bot.Send(msg)
+	_, err := sender.Send(msg, sender.PriorityNormal)
 	if err != nil {
 		log.Printf("Error sending match notification to %d: %v", userID, err)
 	}
@@ -0,0 +1,149 @@
+package matcher
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strconv"
+	"time"
+
+	"tg-anon-go/config"
+	"tg-anon-go/constants"
+	"tg-anon-go/databases"
+	"tg-anon-go/sender"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// reconcileGracePeriod melewati user yang baru saja mulai mencari (status sudah searching,
+// tapi PublishSearch mungkin belum selesai)
+const reconcileGracePeriod = time.Minute
+
+// ReconcileResult berisi jumlah inkonsistensi yang diperbaiki dalam satu putaran
+type ReconcileResult struct {
+	BrokenPartners   int // User chatting dengan partner satu arah / tanpa partner
+	OrphanedSessions int // Sesi aktif yang user-nya sudah tidak saling terhubung
+	StaleSearchers   int // Ada di Redis searching:users tapi tidak searching di database
+	MissingSearchers int // Searching di database tapi tidak ada di Redis
+}
+
+// Total mengembalikan jumlah semua perbaikan
+func (r ReconcileResult) Total() int {
+	return r.BrokenPartners + r.OrphanedSessions + r.StaleSearchers + r.MissingSearchers
+}
+
+// Reconcile menyamakan state user di Redis, user_state dan chat_sessions.
+// Sumber kebenaran adalah user_state; Redis dan sesi mengikuti.
+func (m *Matcher) Reconcile(ctx context.Context) (ReconcileResult, error) {
+	var result ReconcileResult
+
+	// 1. User chatting dengan partner satu arah: putuskan dari sisi user tersebut
+	links, err := databases.FindBrokenPartnerLinks(ctx)
+	if err != nil {
+		return result, fmt.Errorf("find broken partner links: %w", err)
+	}
+	for _, link := range links {
+		if err := databases.DisconnectUsers(ctx, link.UserID, link.PartnerID, 0, constants.EndReasonStale); err != nil {
+			log.Printf("Reconcile: error resetting user %d: %v", link.UserID, err)
+			continue
+		}
+		log.Printf("🩹 Reconcile: reset user %d (one-sided partner %d)", link.UserID, link.PartnerID)
+		result.BrokenPartners++
+	}
+
+	// 2. Sesi aktif yang user-nya sudah tidak saling terhubung
+	sessions, err := databases.FindOrphanedSessions(ctx)
+	if err != nil {
+		return result, fmt.Errorf("find orphaned sessions: %w", err)
+	}
+	for _, session := range sessions {
+		if err := databases.DisconnectUsers(ctx, session.User1ID, session.User2ID, 0, constants.EndReasonStale); err != nil {
+			log.Printf("Reconcile: error ending session %d: %v", session.ID, err)
+			continue
+		}
+		log.Printf("🩹 Reconcile: ended orphaned session %d (%d <-> %d)", session.ID, session.User1ID, session.User2ID)
+		result.OrphanedSessions++
+	}
+
+	// 3. Redis searching set vs status searching di database
+	members, err := m.rdb.SMembers(ctx, KeySearchingUsers).Result()
+	if err != nil {
+		return result, fmt.Errorf("read %s: %w", KeySearchingUsers, err)
+	}
+	inRedis := make(map[int64]bool, len(members))
+	for _, member := range members {
+		userID, err := strconv.ParseInt(member, 10, 64)
+		if err != nil {
+			m.rdb.SRem(ctx, KeySearchingUsers, member)
+			continue
+		}
+		inRedis[userID] = true
+
+		if status, _ := databases.GetUserStatus(ctx, userID); status != constants.StatusSearching {
+			m.RemoveSearchingUser(ctx, userID)
+			result.StaleSearchers++
+		}
+	}
+
+	searching, err := databases.GetSearchingUserIDs(ctx, reconcileGracePeriod)
+	if err != nil {
+		return result, fmt.Errorf("get searching users: %w", err)
+	}
+	for _, userID := range searching {
+		if inRedis[userID] {
+			continue
+		}
+
+		searchMode := databases.GetSearchMode(ctx, userID)
+		var lat, lon float64
+		if searchMode == constants.SearchModeNearby {
+			lat, lon, _ = databases.GetUserLocation(ctx, userID)
+		}
+		if err := m.PublishSearch(ctx, userID, searchMode, lat, lon); err != nil {
+			log.Printf("Reconcile: error republishing search for user %d: %v", userID, err)
+			continue
+		}
+		log.Printf("🩹 Reconcile: republished missing searcher %d", userID)
+		result.MissingSearchers++
+	}
+
+	return result, nil
+}
+
+// RunReconciler menjalankan Reconcile saat start lalu setiap interval sampai ctx selesai.
+// Hasil perbaikan dilaporkan ke log group.
+func (m *Matcher) RunReconciler(ctx context.Context, interval time.Duration) {
+	log.Printf("🩹 State reconciler started (every %s)", interval)
+
+	m.reconcileAndReport(ctx)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			m.reconcileAndReport(ctx)
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// reconcileAndReport menjalankan satu putaran reconcile dan mengirim laporan jika ada perbaikan
+func (m *Matcher) reconcileAndReport(ctx context.Context) {
+	result, err := m.Reconcile(ctx)
+	if err != nil {
+		log.Printf("Error reconciling user state: %v", err)
+	}
+	if result.Total() == 0 {
+		return
+	}
+
+	log.Printf("🩹 Reconcile fixed %d inconsistencies", result.Total())
+	if config.C.LogGroupID == 0 {
+		return
+	}
+	text := fmt.Sprintf(constants.MsgReconcileReport,
+		result.BrokenPartners, result.OrphanedSessions, result.StaleSearchers, result.MissingSearchers)
+	sender.SendAsync(tgbotapi.NewMessage(config.C.LogGroupID, text), sender.PriorityLow)
+}
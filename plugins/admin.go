@@ -1,34 +1,37 @@
 package plugins
 
 import (
+	"bytes"
 	"context"
-	"encoding/json"
+	"encoding/csv"
+	"errors"
 	"fmt"
 	"log"
 	"math/rand"
 	"os"
 	"os/exec"
+	"runtime/debug"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
 
+	"tg-anon-go/cache"
+	"tg-anon-go/config"
 	"tg-anon-go/constants"
 	"tg-anon-go/databases"
+	"tg-anon-go/matcher"
+	"tg-anon-go/sender"
+	"tg-anon-go/settings"
 
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
 )
 
-// Ad represents a single advertisement
-type Ad struct {
-	ID        int       `json:"id"`
-	Message   string    `json:"message"`
-	CreatedAt time.Time `json:"created_at"`
-}
-
 // AdminPlugin menangani command admin
 type AdminPlugin struct {
 	BasePlugin
 	pendingReset map[int64]bool
+	matcher      *matcher.Matcher
 }
 
 // NewAdminPlugin membuat instance AdminPlugin baru
@@ -38,6 +41,11 @@ func NewAdminPlugin() *AdminPlugin {
 	}
 }
 
+// SetMatcher sets the matcher instance (untuk membatalkan pencarian saat maintenance)
+func (p *AdminPlugin) SetMatcher(m *matcher.Matcher) {
+	p.matcher = m
+}
+
 // Name mengembalikan nama plugin
 func (p *AdminPlugin) Name() string {
 	return "admin"
@@ -61,18 +69,16 @@ func (p *AdminPlugin) Commands() []string {
 		constants.CmdSetFsub,
 		constants.CmdRemoveFsub,
 		constants.CmdFsubInfo,
+		constants.CmdReport,
+		constants.CmdConfig,
+		constants.CmdMaintenance,
 		"confirmreset",
 	}
 }
 
 // isOwner mengecek apakah user adalah owner
 func (p *AdminPlugin) isOwner(userID int64) bool {
-	for _, ownerID := range constants.OwnerIDs {
-		if userID == ownerID {
-			return true
-		}
-	}
-	return false
+	return config.C.IsOwner(userID)
 }
 
 // HandleCommand menangani command admin
@@ -89,6 +95,9 @@ func (p *AdminPlugin) HandleCommand(bot *tgbotapi.BotAPI, message *tgbotapi.Mess
 	case constants.CmdAdmin:
 		return p.handleAdminPanel(ctx, bot, chatID)
 	case constants.CmdStats:
+		if strings.EqualFold(strings.TrimSpace(message.CommandArguments()), "csv") {
+			return p.handleStatsCSV(ctx, bot, chatID)
+		}
 		return p.handleStats(ctx, bot, chatID)
 	case constants.CmdEnv:
 		return p.handleEnv(bot, chatID)
@@ -118,6 +127,12 @@ func (p *AdminPlugin) HandleCommand(bot *tgbotapi.BotAPI, message *tgbotapi.Mess
 		return p.handleRemoveFsub(ctx, bot, chatID)
 	case constants.CmdFsubInfo:
 		return p.handleFsubInfo(ctx, bot, chatID)
+	case constants.CmdReport:
+		return p.handleReport(ctx, bot, chatID, message)
+	case constants.CmdConfig:
+		return p.handleConfig(ctx, bot, chatID, message)
+	case constants.CmdMaintenance:
+		return p.handleMaintenance(ctx, bot, chatID, message)
 	}
 
 	return nil
@@ -141,24 +156,138 @@ func (p *AdminPlugin) handleStats(ctx context.Context, bot *tgbotapi.BotAPI, cha
 	searchingCount, _ := p.countSearchingUsers(ctx)
 
 	// Ads info
-	adsEnabled, _ := databases.GetGlobalVarBool(ctx, constants.VarGlobalAdsEnabled)
+	adsEnabled, _ := databases.Ads.Enabled(ctx)
 	adsEnabledStr := "Tidak"
 	if adsEnabled {
 		adsEnabledStr = "Ya"
 	}
 
-	ads, _ := p.getAds(ctx)
+	ads, _ := databases.Ads.List(ctx)
 
 	msg := fmt.Sprintf(constants.MsgStatsInfo, totalUsers, activeChats, searchingCount, totalMessages, adsEnabledStr, len(ads))
+	msg += p.formatStatsTrends(ctx)
 	return p.sendMessage(bot, chatID, msg)
 }
 
+// statsSummary adalah ringkasan daily_stats dalam satu periode
+type statsSummary struct {
+	newUsers   int
+	avgDAU     float64
+	matches    int
+	medianWait float64 // rata-rata median harian, dibobot jumlah match
+	avgSession float64 // dibobot jumlah match
+	messages   int64
+}
+
+// summarizeDailyStats meringkas beberapa hari daily_stats menjadi satu periode
+func summarizeDailyStats(days []databases.DailyStats) statsSummary {
+	var s statsSummary
+	if len(days) == 0 {
+		return s
+	}
+
+	var totalDAU int
+	var waitWeighted, sessionWeighted float64
+	for _, d := range days {
+		s.newUsers += d.NewUsers
+		totalDAU += d.DAU
+		s.matches += d.Matches
+		s.messages += d.Messages
+		waitWeighted += d.MedianWaitSeconds * float64(d.Matches)
+		sessionWeighted += d.AvgSessionSeconds * float64(d.Matches)
+	}
+	s.avgDAU = float64(totalDAU) / float64(len(days))
+	if s.matches > 0 {
+		s.medianWait = waitWeighted / float64(s.matches)
+		s.avgSession = sessionWeighted / float64(s.matches)
+	}
+	return s
+}
+
+// formatStatsTrends membuat bagian tren 7 dan 30 hari untuk /stats
+func (p *AdminPlugin) formatStatsTrends(ctx context.Context) string {
+	days, err := databases.GetDailyStats(ctx, 30)
+	if errors.Is(err, databases.ErrNotSupported) {
+		return ""
+	}
+	if err != nil {
+		log.Printf("Error getting daily stats: %v", err)
+		return ""
+	}
+	if len(days) == 0 {
+		return constants.MsgStatsNoRollup
+	}
+
+	last7 := days
+	if len(days) > 7 {
+		last7 = days[len(days)-7:]
+	}
+
+	var out strings.Builder
+	for _, period := range []struct {
+		days int
+		data []databases.DailyStats
+	}{{7, last7}, {30, days}} {
+		s := summarizeDailyStats(period.data)
+		out.WriteString(fmt.Sprintf(constants.MsgStatsTrend, period.days,
+			s.newUsers, s.avgDAU, s.matches, formatSeconds(s.medianWait), formatSeconds(s.avgSession), s.messages))
+	}
+
+	var lines []string
+	for _, d := range last7 {
+		lines = append(lines, fmt.Sprintf("`%s  %4d %5d %5d`", d.Day.Format("02-01"), d.NewUsers, d.DAU, d.Matches))
+	}
+	out.WriteString(fmt.Sprintf(constants.MsgStatsDaily, strings.Join(lines, "\n")))
+	return out.String()
+}
+
+// handleStatsCSV mengirim data daily_stats sebagai dokumen CSV
+func (p *AdminPlugin) handleStatsCSV(ctx context.Context, bot *tgbotapi.BotAPI, chatID int64) error {
+	const csvDays = 365
+
+	days, err := databases.GetDailyStats(ctx, csvDays)
+	if errors.Is(err, databases.ErrNotSupported) {
+		return p.sendMessage(bot, chatID, constants.MsgNotSupported)
+	}
+	if err != nil {
+		log.Printf("Error getting daily stats: %v", err)
+		return p.sendMessage(bot, chatID, constants.MsgError)
+	}
+	if len(days) == 0 {
+		return p.sendMessage(bot, chatID, constants.MsgStatsCSVEmpty)
+	}
+
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	w.Write([]string{"day", "new_users", "dau", "matches", "median_wait_seconds", "avg_session_seconds", "messages"})
+	for _, d := range days {
+		w.Write([]string{
+			d.Day.Format("2006-01-02"),
+			strconv.Itoa(d.NewUsers),
+			strconv.Itoa(d.DAU),
+			strconv.Itoa(d.Matches),
+			strconv.FormatFloat(d.MedianWaitSeconds, 'f', 1, 64),
+			strconv.FormatFloat(d.AvgSessionSeconds, 'f', 1, 64),
+			strconv.FormatInt(d.Messages, 10),
+		})
+	}
+	w.Flush()
+	if err := w.Error(); err != nil {
+		return err
+	}
+
+	doc := tgbotapi.NewDocument(chatID, tgbotapi.FileBytes{
+		Name:  fmt.Sprintf("daily_stats_%s.csv", time.Now().Format("20060102")),
+		Bytes: buf.Bytes(),
+	})
+	doc.Caption = fmt.Sprintf(constants.MsgStatsCSVCaption, len(days))
+	_, err = sender.Send(doc, sender.PriorityNormal)
+	return err
+}
+
 // countSearchingUsers menghitung jumlah user yang sedang searching
 func (p *AdminPlugin) countSearchingUsers(ctx context.Context) (int, error) {
-	query := `SELECT COUNT(*) FROM vars WHERE var_key = $1 AND var_value = $2`
-	var count int
-	err := databases.DB.QueryRow(ctx, query, constants.VarStatus, constants.StatusSearching).Scan(&count)
-	return count, err
+	return databases.Users.CountByStatus(ctx, constants.StatusSearching)
 }
 
 // handleBroadcast mengirim broadcast ke semua user
@@ -179,45 +308,44 @@ func (p *AdminPlugin) handleBroadcast(ctx context.Context, bot *tgbotapi.BotAPI,
 
 	p.sendMessage(bot, chatID, fmt.Sprintf(constants.MsgBroadcastStart, len(users)))
 
-	// Send broadcast
-	success := 0
-	failed := 0
+	// Antrian dan penghitungan hasil berjalan di goroutine terpisah supaya handler langsung
+	// selesai dan tidak menahan update lain di shard dispatcher yang sama selama broadcast
 	broadcastMsg := "📢 *Broadcast dari Admin:*\n\n" + args
+	go p.runBroadcast(bot, chatID, users, broadcastMsg)
+	return nil
+}
+
+// runBroadcast mengirim pesan broadcast dengan prioritas rendah (rate limit diatur oleh sender)
+// lalu melaporkan jumlah berhasil/gagal ke admin
+func (p *AdminPlugin) runBroadcast(bot *tgbotapi.BotAPI, chatID int64, users []int64, text string) {
+	defer func() {
+		if r := recover(); r != nil {
+			reporter.Panic(p.Name(), "broadcast", chatID, r, debug.Stack())
+		}
+	}()
 
+	results := make([]<-chan sender.Result, 0, len(users))
 	for _, userID := range users {
-		msg := tgbotapi.NewMessage(userID, broadcastMsg)
+		msg := tgbotapi.NewMessage(userID, text)
 		msg.ParseMode = "Markdown"
-		_, err := bot.Send(msg)
-		if err != nil {
+		results = append(results, sender.SendAsync(msg, sender.PriorityLow))
+	}
+
+	success, failed := 0, 0
+	for _, result := range results {
+		if res := <-result; res.Err != nil {
 			failed++
 		} else {
 			success++
 		}
-		// Rate limiting
-		time.Sleep(50 * time.Millisecond)
 	}
 
-	return p.sendMessage(bot, chatID, fmt.Sprintf(constants.MsgBroadcastDone, success, failed))
+	p.sendMessage(bot, chatID, fmt.Sprintf(constants.MsgBroadcastDone, success, failed))
 }
 
-// getAllRegisteredUsers mengambil semua user ID yang terdaftar
+// getAllRegisteredUsers mengambil semua user ID yang terdaftar dan masih aktif
 func (p *AdminPlugin) getAllRegisteredUsers(ctx context.Context) ([]int64, error) {
-	query := `SELECT telegram_id FROM users`
-	rows, err := databases.DB.Query(ctx, query)
-	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
-
-	var users []int64
-	for rows.Next() {
-		var userID int64
-		if err := rows.Scan(&userID); err != nil {
-			continue
-		}
-		users = append(users, userID)
-	}
-	return users, nil
+	return databases.Users.ActiveIDs(ctx)
 }
 
 // handleResetDBRequest meminta konfirmasi reset database
@@ -234,18 +362,18 @@ func (p *AdminPlugin) handleResetDBConfirm(ctx context.Context, bot *tgbotapi.Bo
 
 	delete(p.pendingReset, userID)
 
-	// Reset tables
-	queries := []string{
-		"TRUNCATE TABLE messages CASCADE",
-		"TRUNCATE TABLE chat_sessions CASCADE",
-		"TRUNCATE TABLE vars CASCADE",
-		"UPDATE users SET status = 'idle', partner_id = NULL",
+	if err := databases.ResetAll(ctx); err != nil {
+		log.Printf("Error resetting database: %v", err)
 	}
 
-	for _, query := range queries {
-		if _, err := databases.DB.Exec(ctx, query); err != nil {
-			log.Printf("Error executing reset query: %v", err)
-		}
+	// State chat yang di-cache juga harus ikut direset
+	if err := cache.ClearUserStates(ctx); err != nil {
+		log.Printf("Error clearing user state cache: %v", err)
+	}
+
+	// Override setting runtime ikut terhapus, kembali ke default environment
+	if err := settings.Load(ctx); err != nil {
+		log.Printf("Error reloading settings: %v", err)
 	}
 
 	return p.sendMessage(bot, chatID, constants.MsgResetDBSuccess)
@@ -260,29 +388,12 @@ func (p *AdminPlugin) handleAddAds(ctx context.Context, bot *tgbotapi.BotAPI, ch
 		return p.sendMessage(bot, chatID, "❌ Format: /addads <pesan iklan>")
 	}
 
-	// Get existing ads
-	ads, _ := p.getAds(ctx)
-
-	// Generate new ID
-	newID := 1
-	if len(ads) > 0 {
-		newID = ads[len(ads)-1].ID + 1
-	}
-
-	// Add new ad
-	newAd := Ad{
-		ID:        newID,
-		Message:   args,
-		CreatedAt: time.Now(),
-	}
-	ads = append(ads, newAd)
-
-	// Save ads
-	if err := p.saveAds(ctx, ads); err != nil {
+	ad, err := databases.Ads.Add(ctx, args)
+	if err != nil {
 		return p.sendMessage(bot, chatID, constants.MsgError)
 	}
 
-	return p.sendMessage(bot, chatID, fmt.Sprintf(constants.MsgAdsAdded, newID))
+	return p.sendMessage(bot, chatID, fmt.Sprintf(constants.MsgAdsAdded, ad.ID))
 }
 
 // handleDelAds menghapus ads
@@ -295,34 +406,20 @@ func (p *AdminPlugin) handleDelAds(ctx context.Context, bot *tgbotapi.BotAPI, ch
 		return p.sendMessage(bot, chatID, "❌ Format: /delads <id>")
 	}
 
-	ads, _ := p.getAds(ctx)
-
-	// Find and remove ad
-	found := false
-	newAds := make([]Ad, 0)
-	for _, ad := range ads {
-		if ad.ID == adID {
-			found = true
-			continue
-		}
-		newAds = append(newAds, ad)
+	found, err := databases.Ads.Delete(ctx, adID)
+	if err != nil {
+		return p.sendMessage(bot, chatID, constants.MsgError)
 	}
-
 	if !found {
 		return p.sendMessage(bot, chatID, fmt.Sprintf(constants.MsgAdsNotFound, adID))
 	}
 
-	// Save ads
-	if err := p.saveAds(ctx, newAds); err != nil {
-		return p.sendMessage(bot, chatID, constants.MsgError)
-	}
-
 	return p.sendMessage(bot, chatID, fmt.Sprintf(constants.MsgAdsDeleted, adID))
 }
 
 // handleListAds menampilkan daftar ads
 func (p *AdminPlugin) handleListAds(ctx context.Context, bot *tgbotapi.BotAPI, chatID int64) error {
-	ads, _ := p.getAds(ctx)
+	ads, _ := databases.Ads.List(ctx)
 
 	if len(ads) == 0 {
 		return p.sendMessage(bot, chatID, constants.MsgAdsEmpty)
@@ -338,11 +435,11 @@ func (p *AdminPlugin) handleListAds(ctx context.Context, bot *tgbotapi.BotAPI, c
 
 // handleToggleAds enable/disable ads
 func (p *AdminPlugin) handleToggleAds(ctx context.Context, bot *tgbotapi.BotAPI, chatID int64) error {
-	enabled, _ := databases.GetGlobalVarBool(ctx, constants.VarGlobalAdsEnabled)
+	enabled, _ := databases.Ads.Enabled(ctx)
 
 	// Toggle
 	newEnabled := !enabled
-	databases.SetGlobalVar(ctx, constants.VarGlobalAdsEnabled, newEnabled)
+	databases.Ads.SetEnabled(ctx, newEnabled)
 
 	status := "Disabled"
 	if newEnabled {
@@ -362,10 +459,40 @@ func (p *AdminPlugin) handleBan(ctx context.Context, bot *tgbotapi.BotAPI, chatI
 		return p.sendMessage(bot, chatID, constants.MsgInvalidUserID)
 	}
 
-	databases.BanUser(ctx, userID)
+	if err := databases.BanUser(ctx, userID); err != nil {
+		log.Printf("Error banning user %d: %v", userID, err)
+		return p.sendMessage(bot, chatID, constants.MsgError)
+	}
+	p.endBannedUser(ctx, userID)
 	return p.sendMessage(bot, chatID, fmt.Sprintf(constants.MsgUserBanned, userID))
 }
 
+// endBannedUser mengakhiri chat atau pencarian user yang baru di-ban
+// supaya matcher tidak lagi memasangkannya dan partner tidak menunggu
+func (p *AdminPlugin) endBannedUser(ctx context.Context, userID int64) {
+	status, _ := databases.GetUserStatus(ctx, userID)
+	switch status {
+	case constants.StatusChatting:
+		partnerID, _ := databases.GetUserPartner(ctx, userID)
+		if partnerID <= 0 {
+			databases.SetUserStatus(ctx, userID, constants.StatusIdle)
+			return
+		}
+		if err := databases.DisconnectUsers(ctx, userID, partnerID, 0, constants.EndReasonBanned); err != nil {
+			log.Printf("Error disconnecting banned user %d: %v", userID, err)
+			return
+		}
+		msg := tgbotapi.NewMessage(partnerID, constants.MsgPartnerLeft)
+		msg.ParseMode = "Markdown"
+		sender.SendAsync(msg, sender.PriorityHigh)
+	case constants.StatusSearching:
+		databases.SetUserStatus(ctx, userID, constants.StatusIdle)
+		if p.matcher != nil {
+			p.matcher.RemoveSearchingUser(ctx, userID)
+		}
+	}
+}
+
 // handleUnban unban user
 func (p *AdminPlugin) handleUnban(ctx context.Context, bot *tgbotapi.BotAPI, chatID int64, message *tgbotapi.Message) error {
 	args := strings.TrimPrefix(message.Text, "/"+constants.CmdUnban)
@@ -380,38 +507,14 @@ func (p *AdminPlugin) handleUnban(ctx context.Context, bot *tgbotapi.BotAPI, cha
 	return p.sendMessage(bot, chatID, fmt.Sprintf(constants.MsgUserUnbanned, userID))
 }
 
-// getAds mengambil semua ads dari database
-func (p *AdminPlugin) getAds(ctx context.Context) ([]Ad, error) {
-	adsJSON, err := databases.GetGlobalVar(ctx, constants.VarGlobalAds)
-	if err != nil || adsJSON == "" {
-		return []Ad{}, nil
-	}
-
-	var ads []Ad
-	if err := json.Unmarshal([]byte(adsJSON), &ads); err != nil {
-		return []Ad{}, nil
-	}
-
-	return ads, nil
-}
-
-// saveAds menyimpan ads ke database
-func (p *AdminPlugin) saveAds(ctx context.Context, ads []Ad) error {
-	adsJSON, err := json.Marshal(ads)
-	if err != nil {
-		return err
-	}
-	return databases.SetGlobalVar(ctx, constants.VarGlobalAds, string(adsJSON))
-}
-
 // GetRandomAd mengambil ads secara random
 func (p *AdminPlugin) GetRandomAd(ctx context.Context) (string, bool) {
-	enabled, _ := databases.GetGlobalVarBool(ctx, constants.VarGlobalAdsEnabled)
+	enabled, _ := databases.Ads.Enabled(ctx)
 	if !enabled {
 		return "", false
 	}
 
-	ads, _ := p.getAds(ctx)
+	ads, _ := databases.Ads.List(ctx)
 	if len(ads) == 0 {
 		return "", false
 	}
@@ -421,28 +524,160 @@ func (p *AdminPlugin) GetRandomAd(ctx context.Context) (string, bool) {
 	return constants.MsgAdsPrefix + ad.Message, true
 }
 
-// handleEnv menampilkan environment variables
+// handleEnv menampilkan konfigurasi aktif, nilai secret (token, URL database/Redis) disamarkan
 func (p *AdminPlugin) handleEnv(bot *tgbotapi.BotAPI, chatID int64) error {
-	// Format owner IDs
-	ownerIDsStr := make([]string, len(constants.OwnerIDs))
-	for i, id := range constants.OwnerIDs {
-		ownerIDsStr[i] = strconv.FormatInt(id, 10)
-	}
-	ownerIDsFormatted := strings.Join(ownerIDsStr, ", ")
-
-	msg := fmt.Sprintf(constants.MsgEnvInfo,
-		constants.BotOwnerURL,
-		constants.BotChannelURL,
-		constants.BotSupportURL,
-		constants.LogGroupID,
-		ownerIDsFormatted,
-		constants.MaxWarnings,
-		constants.AdsIntervalMessages,
-	)
-
+	msg := fmt.Sprintf(constants.MsgEnvInfo, config.C.String())
 	return p.sendMessage(bot, chatID, msg)
 }
 
+// handleConfig menampilkan dan mengubah setting runtime: /config list|get <key>|set <key> <value>
+func (p *AdminPlugin) handleConfig(ctx context.Context, bot *tgbotapi.BotAPI, chatID int64, message *tgbotapi.Message) error {
+	args := strings.Fields(message.CommandArguments())
+	if len(args) == 0 {
+		args = []string{"list"}
+	}
+
+	switch strings.ToLower(args[0]) {
+	case "list":
+		var sb strings.Builder
+		for _, s := range settings.All() {
+			marker := ""
+			if s.Overridden() {
+				marker = " ✏️"
+			}
+			sb.WriteString(fmt.Sprintf(constants.MsgConfigListItem, s.Key(), s.Value(), marker, s.Description()))
+		}
+		return p.sendMessage(bot, chatID, fmt.Sprintf(constants.MsgConfigList, sb.String()))
+
+	case "get":
+		if len(args) != 2 {
+			return p.sendMessage(bot, chatID, constants.MsgConfigUsage)
+		}
+		s, ok := settings.Lookup(args[1])
+		if !ok {
+			return p.sendMessage(bot, chatID, fmt.Sprintf(constants.MsgConfigUnknown, args[1]))
+		}
+		source := "default (environment)"
+		if s.Overridden() {
+			source = "/config"
+		}
+		return p.sendMessage(bot, chatID, fmt.Sprintf(constants.MsgConfigGet, s.Key(), s.Value(), s.Description(), s.Range(), s.Default(), source))
+
+	case "set":
+		if len(args) != 3 {
+			return p.sendMessage(bot, chatID, constants.MsgConfigUsage)
+		}
+		old := ""
+		if s, ok := settings.Lookup(args[1]); ok {
+			old = s.Value()
+		}
+		s, err := settings.Set(ctx, args[1], args[2])
+		switch {
+		case errors.Is(err, settings.ErrUnknown):
+			return p.sendMessage(bot, chatID, fmt.Sprintf(constants.MsgConfigUnknown, args[1]))
+		case errors.Is(err, settings.ErrInvalid):
+			return p.sendMessage(bot, chatID, fmt.Sprintf(constants.MsgConfigInvalid, err))
+		case err != nil:
+			return p.sendMessage(bot, chatID, fmt.Sprintf(constants.MsgConfigFailed, err))
+		}
+		return p.sendMessage(bot, chatID, fmt.Sprintf(constants.MsgConfigSet, s.Key(), old, s.Value()))
+	}
+
+	return p.sendMessage(bot, chatID, constants.MsgConfigUsage)
+}
+
+// handleMaintenance mengatur mode maintenance: /maintenance on [-end] [pesan] | off
+func (p *AdminPlugin) handleMaintenance(ctx context.Context, bot *tgbotapi.BotAPI, chatID int64, message *tgbotapi.Message) error {
+	mode, rest, _ := strings.Cut(strings.TrimSpace(message.CommandArguments()), " ")
+	rest = strings.TrimSpace(rest)
+
+	switch strings.ToLower(mode) {
+	case "on":
+		endSessions := false
+		if flag, after, _ := strings.Cut(rest, " "); flag == "-end" {
+			endSessions = true
+			rest = strings.TrimSpace(after)
+		}
+
+		if err := settings.SetMaintenance(ctx, true, rest); err != nil {
+			log.Printf("Error enabling maintenance: %v", err)
+			return p.sendMessage(bot, chatID, constants.MsgError)
+		}
+
+		text := maintenanceText(rest)
+		cancelled := p.cancelSearches(ctx, text)
+		ended := 0
+		if endSessions {
+			ended = p.endActiveSessions(ctx, text)
+		}
+		log.Printf("🛠️ Maintenance enabled: %d searches cancelled, %d sessions ended", cancelled, ended)
+		return p.sendMessage(bot, chatID, fmt.Sprintf(constants.MsgMaintenanceOn, cancelled, ended))
+
+	case "off":
+		if err := settings.SetMaintenance(ctx, false, ""); err != nil {
+			log.Printf("Error disabling maintenance: %v", err)
+			return p.sendMessage(bot, chatID, constants.MsgError)
+		}
+		return p.sendMessage(bot, chatID, constants.MsgMaintenanceOff)
+	}
+
+	status := "✅ Aktif (normal)"
+	if enabled, _ := settings.Maintenance(); enabled {
+		status = "🛠️ Maintenance"
+	}
+	return p.sendMessage(bot, chatID, fmt.Sprintf(constants.MsgMaintenanceUsage, status))
+}
+
+// cancelSearches membatalkan semua pencarian yang sedang berjalan dan mengirim pesan maintenance
+func (p *AdminPlugin) cancelSearches(ctx context.Context, text string) int {
+	userIDs, err := databases.Users.SearchingIDs(ctx, 0)
+	if err != nil {
+		log.Printf("Error getting searching users: %v", err)
+		return 0
+	}
+
+	for _, userID := range userIDs {
+		if err := databases.SetUserStatus(ctx, userID, constants.StatusIdle); err != nil {
+			log.Printf("Error cancelling search for user %d: %v", userID, err)
+			continue
+		}
+		if p.matcher != nil {
+			p.matcher.RemoveSearchingUser(ctx, userID)
+		}
+
+		msg := tgbotapi.NewMessage(userID, text)
+		msg.ParseMode = "Markdown"
+		sender.SendAsync(msg, sender.PriorityNormal)
+	}
+	return len(userIDs)
+}
+
+// endActiveSessions mengakhiri semua sesi chat aktif dan memberi tahu kedua user
+func (p *AdminPlugin) endActiveSessions(ctx context.Context, text string) int {
+	sessions, err := databases.GetActiveSessions(ctx)
+	if err != nil {
+		log.Printf("Error getting active sessions: %v", err)
+		return 0
+	}
+
+	notice := constants.MsgMaintenanceSessionEnded + "\n\n" + text
+	ended := 0
+	for _, session := range sessions {
+		if err := databases.DisconnectUsers(ctx, session.User1ID, session.User2ID, 0, constants.EndReasonMaintenance); err != nil {
+			log.Printf("Error ending session %d: %v", session.ID, err)
+			continue
+		}
+		ended++
+
+		for _, userID := range []int64{session.User1ID, session.User2ID} {
+			msg := tgbotapi.NewMessage(userID, notice)
+			msg.ParseMode = "Markdown"
+			sender.SendAsync(msg, sender.PriorityNormal)
+		}
+	}
+	return ended
+}
+
 // handleUpdate updates the bot by pulling latest code and rebuilding
 func (p *AdminPlugin) handleUpdate(ctx context.Context, bot *tgbotapi.BotAPI, chatID int64) error {
 	// Send start message
@@ -522,6 +757,75 @@ func (p *AdminPlugin) handleFsubInfo(ctx context.Context, bot *tgbotapi.BotAPI,
 func (p *AdminPlugin) sendMessage(bot *tgbotapi.BotAPI, chatID int64, text string) error {
 	msg := tgbotapi.NewMessage(chatID, text)
 	msg.ParseMode = "Markdown"
-	_, err := bot.Send(msg)
+	_, err := sender.Send(msg, sender.PriorityNormal)
 	return err
 }
+
+// endReasonLabels adalah label alasan sesi berakhir untuk laporan admin
+var endReasonLabels = map[string]string{
+	constants.EndReasonStop:        "⏹️ Stop",
+	constants.EndReasonNext:        "⏭️ Next",
+	constants.EndReasonIdle:        "⏰ Auto-close (idle)",
+	constants.EndReasonBanned:      "🚫 Ban",
+	constants.EndReasonBlocked:     "🔒 Blokir bot",
+	constants.EndReasonStale:       "🧹 Dibersihkan",
+	constants.EndReasonMaintenance: "🛠️ Maintenance",
+}
+
+// handleReport menampilkan laporan sesi chat untuk admin: /report [hari]
+func (p *AdminPlugin) handleReport(ctx context.Context, bot *tgbotapi.BotAPI, chatID int64, message *tgbotapi.Message) error {
+	days := 7
+	if args := strings.TrimSpace(message.CommandArguments()); args != "" {
+		if _, err := fmt.Sscanf(args, "%d", &days); err != nil || days <= 0 {
+			return p.sendMessage(bot, chatID, "❌ Format: /report [hari]")
+		}
+	}
+
+	since := time.Now().AddDate(0, 0, -days)
+	report, err := databases.GetSessionReport(ctx, since)
+	if errors.Is(err, databases.ErrNotSupported) {
+		return p.sendMessage(bot, chatID, constants.MsgNotSupported)
+	}
+	if err != nil {
+		log.Printf("Error getting session report: %v", err)
+		return p.sendMessage(bot, chatID, constants.MsgError)
+	}
+
+	msg := fmt.Sprintf(constants.MsgSessionReport,
+		days,
+		report.TotalSessions, report.ActiveSessions,
+		formatSeconds(report.AvgDuration),
+		formatSeconds(report.AvgWait),
+		report.AvgMessages,
+		report.SilentSessions,
+		report.RandomMatches,
+		report.NearbyMatches, report.AvgDistance,
+		formatEndReasons(report.EndReasons),
+	)
+	return p.sendMessage(bot, chatID, msg)
+}
+
+// formatEndReasons format breakdown alasan sesi berakhir, terbanyak dulu
+func formatEndReasons(reasons map[string]int64) string {
+	if len(reasons) == 0 {
+		return "-"
+	}
+
+	keys := make([]string, 0, len(reasons))
+	for reason := range reasons {
+		keys = append(keys, reason)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return reasons[keys[i]] > reasons[keys[j]]
+	})
+
+	var lines []string
+	for _, reason := range keys {
+		label, ok := endReasonLabels[reason]
+		if !ok {
+			label = "❔ " + reason
+		}
+		lines = append(lines, fmt.Sprintf("• %s: *%d*", label, reasons[reason]))
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,79 @@
+package plugins
+
+import (
+	"context"
+	"log"
+	"runtime/debug"
+
+	"tg-anon-go/constants"
+	"tg-anon-go/databases"
+	"tg-anon-go/sender"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// handleBlockedUser dipanggil saat user memblokir bot atau akunnya sudah dihapus.
+// Sesi chat diakhiri, partner diberi tahu, dan user ditandai tidak aktif
+// supaya dilewati oleh matcher dan broadcast.
+func (m *Manager) handleBlockedUser(userID int64) {
+	// Dijalankan di goroutine milik sender, jadi panic harus ditangkap di sini
+	defer func() {
+		if r := recover(); r != nil {
+			reporter.Panic("manager", "blocked", userID, r, debug.Stack())
+		}
+	}()
+
+	ctx := context.Background()
+
+	inactive, _ := databases.IsUserInactive(ctx, userID)
+	status, _ := databases.GetUserStatus(ctx, userID)
+	if inactive && status == constants.StatusIdle {
+		return // Sudah ditangani sebelumnya
+	}
+
+	if err := databases.SetUserInactive(ctx, userID, true); err != nil {
+		log.Printf("Error marking user %d inactive: %v", userID, err)
+	}
+
+	switch status {
+	case constants.StatusChatting:
+		partnerID, _ := databases.GetUserPartner(ctx, userID)
+		if partnerID > 0 {
+			databases.DisconnectUsers(ctx, userID, partnerID, userID, constants.EndReasonBlocked)
+
+			msg := tgbotapi.NewMessage(partnerID, constants.MsgPartnerGone)
+			msg.ParseMode = "Markdown"
+			sender.Send(msg, sender.PriorityHigh)
+		} else {
+			databases.SetUserStatus(ctx, userID, constants.StatusIdle)
+		}
+	case constants.StatusSearching:
+		databases.SetUserStatus(ctx, userID, constants.StatusIdle)
+		if m.matcher != nil {
+			m.matcher.RemoveSearchingUser(ctx, userID)
+		}
+	}
+
+	log.Printf("🚫 User %d blocked the bot or was deactivated, marked inactive", userID)
+}
+
+// handleMyChatMember menangani perubahan status bot di private chat user
+// (user memblokir atau membuka blokir bot)
+func (m *Manager) handleMyChatMember(update *tgbotapi.ChatMemberUpdated) {
+	if !update.Chat.IsPrivate() {
+		return
+	}
+
+	userID := update.From.ID
+
+	switch update.NewChatMember.Status {
+	case "kicked":
+		m.handleBlockedUser(userID)
+	case "member":
+		ctx := context.Background()
+		if inactive, _ := databases.IsUserInactive(ctx, userID); inactive {
+			databases.SetUserInactive(ctx, userID, false)
+			log.Printf("✅ User %d unblocked the bot, marked active", userID)
+		}
+	}
+}
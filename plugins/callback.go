@@ -0,0 +1,227 @@
+package plugins
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"sync"
+
+	"tg-anon-go/config"
+	"tg-anon-go/constants"
+	"tg-anon-go/sender"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// Batas panjang callback data dari Telegram (byte)
+const maxCallbackDataLength = 64
+
+// Panjang tanda tangan callback (byte HMAC sebelum base64)
+const callbackSignatureBytes = 6
+
+// Callback adalah callback query yang sudah diverifikasi dan di-parse oleh router
+type Callback struct {
+	*tgbotapi.CallbackQuery
+	Namespace string
+	Action    string
+	Args      []string
+
+	answered bool
+}
+
+// Answer menjawab callback dengan teks singkat (toast). Hanya jawaban pertama yang dikirim.
+func (c *Callback) Answer(text string) {
+	c.answer(tgbotapi.NewCallback(c.ID, text))
+}
+
+// Alert menjawab callback dengan popup alert
+func (c *Callback) Alert(text string) {
+	c.answer(tgbotapi.NewCallbackWithAlert(c.ID, text))
+}
+
+func (c *Callback) answer(config tgbotapi.CallbackConfig) {
+	if c.answered {
+		return
+	}
+	c.answered = true
+	sender.Request(config, sender.PriorityNormal)
+}
+
+// Arg mengembalikan argumen ke-i (string kosong jika tidak ada)
+func (c *Callback) Arg(i int) string {
+	if i < 0 || i >= len(c.Args) {
+		return ""
+	}
+	return c.Args[i]
+}
+
+// Int64 mengembalikan argumen ke-i sebagai int64
+func (c *Callback) Int64(i int) (int64, error) {
+	if i < 0 || i >= len(c.Args) {
+		return 0, fmt.Errorf("callback %s:%s: missing argument %d", c.Namespace, c.Action, i)
+	}
+	return strconv.ParseInt(c.Args[i], 10, 64)
+}
+
+// Int mengembalikan argumen ke-i sebagai int
+func (c *Callback) Int(i int) (int, error) {
+	v, err := c.Int64(i)
+	return int(v), err
+}
+
+// DeleteMessage menghapus pesan yang memuat tombol callback ini
+func (c *Callback) DeleteMessage() {
+	if c.Message == nil {
+		return
+	}
+	sender.Request(tgbotapi.NewDeleteMessage(c.Message.Chat.ID, c.Message.MessageID), sender.PriorityNormal)
+}
+
+// CallbackHandler menangani callback untuk satu namespace
+type CallbackHandler func(ctx context.Context, bot *tgbotapi.BotAPI, cb *Callback) error
+
+type callbackRoute struct {
+	handler   CallbackHandler
+	ownerOnly bool
+	plugin    string
+}
+
+// CallbackRouter meneruskan callback query ke handler berdasarkan namespace
+type CallbackRouter struct {
+	routes map[string]callbackRoute
+	owner  string // Plugin yang sedang mendaftarkan namespace
+}
+
+// NewCallbackRouter membuat instance CallbackRouter baru
+func NewCallbackRouter() *CallbackRouter {
+	return &CallbackRouter{routes: make(map[string]callbackRoute)}
+}
+
+// Handle mendaftarkan handler untuk namespace callback
+func (r *CallbackRouter) Handle(namespace string, handler CallbackHandler) {
+	r.register(namespace, callbackRoute{handler: handler})
+}
+
+// HandleOwner mendaftarkan handler yang hanya boleh dipicu oleh owner
+func (r *CallbackRouter) HandleOwner(namespace string, handler CallbackHandler) {
+	r.register(namespace, callbackRoute{handler: handler, ownerOnly: true})
+}
+
+func (r *CallbackRouter) register(namespace string, route callbackRoute) {
+	route.plugin = r.owner
+	if _, exists := r.routes[namespace]; exists {
+		log.Printf("⚠️ Callback namespace %q registered twice, overriding", namespace)
+	}
+	r.routes[namespace] = route
+	log.Printf("📦 Registered callback namespace: %s", namespace)
+}
+
+// Dispatch memverifikasi callback data lalu memanggil handler namespace-nya.
+// Callback selalu dijawab, termasuk ketika data tidak valid atau handler gagal.
+func (r *CallbackRouter) Dispatch(uc *UpdateContext) {
+	query := uc.Callback
+	cb := &Callback{CallbackQuery: query}
+	defer func() {
+		// Pastikan loading state di tombol selalu hilang
+		cb.Answer("")
+	}()
+
+	namespace, action, args, ok := parseCallbackData(query.Data)
+	if !ok {
+		log.Printf("🚫 Rejected callback with invalid signature from user %d: %q", query.From.ID, query.Data)
+		cb.Answer(constants.MsgCallbackInvalid)
+		return
+	}
+	cb.Namespace, cb.Action, cb.Args = namespace, action, args
+
+	route, exists := r.routes[namespace]
+	if !exists {
+		log.Printf("Unknown callback namespace: %s", namespace)
+		cb.Answer(constants.MsgCallbackInvalid)
+		return
+	}
+	uc.Plugin = route.plugin
+
+	if route.ownerOnly && !isOwnerID(query.From.ID) {
+		log.Printf("🚫 Rejected owner-only callback %s:%s from user %d", namespace, action, query.From.ID)
+		cb.Alert(constants.MsgCallbackOwnerOnly)
+		return
+	}
+
+	if err := route.handler(uc.Ctx, uc.Bot, cb); err != nil {
+		reporter.Error(route.plugin, uc.UpdateType(), query.From.ID, err)
+		cb.Answer(constants.MsgCallbackFailed)
+	}
+}
+
+// callbackLabel mengambil "namespace:action" dari callback data untuk log
+func callbackLabel(data string) string {
+	parts := strings.SplitN(data, ":", 3)
+	if len(parts) < 2 {
+		return "invalid"
+	}
+	return parts[0] + ":" + parts[1]
+}
+
+// CallbackData membuat callback data bertanda tangan untuk tombol inline keyboard
+func CallbackData(namespace, action string, args ...interface{}) string {
+	parts := []string{namespace, action}
+	for _, arg := range args {
+		parts = append(parts, fmt.Sprint(arg))
+	}
+	payload := strings.Join(parts, ":")
+	data := payload + ":" + signCallback(payload)
+
+	if len(data) > maxCallbackDataLength {
+		log.Printf("⚠️ Callback data %q exceeds %d bytes", data, maxCallbackDataLength)
+	}
+	return data
+}
+
+// parseCallbackData memverifikasi tanda tangan dan memecah callback data
+func parseCallbackData(data string) (namespace, action string, args []string, ok bool) {
+	i := strings.LastIndex(data, ":")
+	if i < 0 {
+		return "", "", nil, false
+	}
+	payload, signature := data[:i], data[i+1:]
+	if !hmac.Equal([]byte(signature), []byte(signCallback(payload))) {
+		return "", "", nil, false
+	}
+
+	parts := strings.Split(payload, ":")
+	if len(parts) < 2 {
+		return "", "", nil, false
+	}
+	return parts[0], parts[1], parts[2:], true
+}
+
+var (
+	callbackKeyOnce sync.Once
+	callbackKey     []byte
+)
+
+// signCallback menghitung HMAC-SHA256 (dipotong) dari payload callback
+func signCallback(payload string) string {
+	callbackKeyOnce.Do(func() {
+		secret := config.C.CallbackSecret
+		if secret == "" {
+			secret = "callback:" + config.C.BotToken
+		}
+		callbackKey = []byte(secret)
+	})
+
+	mac := hmac.New(sha256.New, callbackKey)
+	mac.Write([]byte(payload))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil)[:callbackSignatureBytes])
+}
+
+// isOwnerID mengecek apakah user termasuk OWNER_IDS
+func isOwnerID(userID int64) bool {
+	return config.C.IsOwner(userID)
+}
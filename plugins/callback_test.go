@@ -0,0 +1,93 @@
+package plugins
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"tg-anon-go/config"
+)
+
+func init() {
+	// Kunci tetap supaya tanda tangan tidak bergantung pada BOT_TOKEN di environment
+	config.C.CallbackSecret = "test-secret"
+}
+
+func TestCallbackDataRoundTrip(t *testing.T) {
+	tests := []struct {
+		name      string
+		namespace string
+		action    string
+		args      []interface{}
+		wantArgs  []string
+	}{
+		{"no args", "profile", "cancel", nil, []string{}},
+		{"string arg", "profile", "edit", []interface{}{"name"}, []string{"name"}},
+		{"mixed args", "warn", "media", []interface{}{int64(123456789), 42}, []string{"123456789", "42"}},
+		{"empty arg", "flow", "gender", []interface{}{""}, []string{""}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data := CallbackData(tt.namespace, tt.action, tt.args...)
+			if len(data) > maxCallbackDataLength {
+				t.Fatalf("callback data %q exceeds %d bytes", data, maxCallbackDataLength)
+			}
+
+			namespace, action, args, ok := parseCallbackData(data)
+			if !ok {
+				t.Fatalf("parseCallbackData(%q) rejected valid data", data)
+			}
+			if namespace != tt.namespace || action != tt.action {
+				t.Errorf("got %s:%s, want %s:%s", namespace, action, tt.namespace, tt.action)
+			}
+			if !reflect.DeepEqual(args, tt.wantArgs) {
+				t.Errorf("args = %q, want %q", args, tt.wantArgs)
+			}
+		})
+	}
+}
+
+func TestParseCallbackDataRejectsTampering(t *testing.T) {
+	valid := CallbackData("warn", "media", 1001)
+	i := strings.LastIndex(valid, ":")
+	payload, signature := valid[:i], valid[i+1:]
+
+	tests := []struct {
+		name string
+		data string
+	}{
+		{"empty", ""},
+		{"unsigned", "warn:media:1001"},
+		{"changed arg", strings.Replace(payload, "1001", "1002", 1) + ":" + signature},
+		{"changed namespace", "admin" + strings.TrimPrefix(payload, "warn") + ":" + signature},
+		{"wrong signature", payload + ":AAAAAAAA"},
+		{"missing action", "warn:" + signCallback("warn")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, _, _, ok := parseCallbackData(tt.data); ok {
+				t.Errorf("parseCallbackData(%q) accepted invalid data", tt.data)
+			}
+		})
+	}
+}
+
+func TestCallbackLabel(t *testing.T) {
+	tests := []struct {
+		data string
+		want string
+	}{
+		{"profile:edit:name:sig", "profile:edit"},
+		{"profile:cancel", "profile:cancel"},
+		{"profile", "invalid"},
+		{"", "invalid"},
+	}
+
+	for _, tt := range tests {
+		if got := callbackLabel(tt.data); got != tt.want {
+			t.Errorf("callbackLabel(%q) = %q, want %q", tt.data, got, tt.want)
+		}
+	}
+}
@@ -2,17 +2,17 @@ package plugins
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
 	"log"
 	"math/rand"
-	"strconv"
-	"strings"
 	"time"
 
+	"tg-anon-go/config"
 	"tg-anon-go/constants"
 	"tg-anon-go/databases"
 	"tg-anon-go/matcher"
+	"tg-anon-go/sender"
+	"tg-anon-go/settings"
 
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
 )
@@ -66,54 +66,26 @@ func (p *ChatPlugin) HandleCommand(bot *tgbotapi.BotAPI, message *tgbotapi.Messa
 
 // handleSearch menangani pencarian partner - langsung cari otomatis
 func (p *ChatPlugin) handleSearch(ctx context.Context, bot *tgbotapi.BotAPI, chatID, userID int64) error {
-	// Check if user is registered
-	isRegistered, _ := databases.GetVarBool(ctx, userID, constants.VarIsRegistered)
-	if !isRegistered {
-		return p.sendMessage(bot, chatID, constants.MsgNotRegistered)
-	}
-
-	// Check if user is banned
-	isBanned, _ := databases.IsUserBanned(ctx, userID)
-	if isBanned {
-		return p.sendMessage(bot, chatID, "❌ Kamu telah dibanned dari bot ini.")
-	}
+	// Registrasi dan ban sudah dicek oleh middleware manager
 
-	// Get current status using var system
-	status, err := databases.GetUserStatus(ctx, userID)
+	// Get current state
+	state, err := databases.GetUserState(ctx, userID)
 	if err != nil {
-		log.Printf("Error getting user status: %v", err)
+		log.Printf("Error getting user state: %v", err)
 		return p.sendMessage(bot, chatID, constants.MsgError)
 	}
 
-	// Check current status
-	switch status {
+	// Check current status. ConnectUsers/DisconnectUsers bersifat atomik, jadi status
+	// chatting selalu punya partner kecuali data lama dari sebelum transaksi dipakai.
+	switch state.Status {
 	case constants.StatusSearching:
 		return p.sendMessage(bot, chatID, constants.MsgAlreadySearching)
 	case constants.StatusChatting:
-		// Defensive check - verify partner exists and is valid
-		partnerID, err := databases.GetUserPartner(ctx, userID)
-		if err != nil || partnerID == 0 {
-			// No partner found - cleanup stuck state
-			log.Printf("⚠️ User %d stuck in chatting status with no partner, cleaning up...", userID)
-			databases.SetUserStatus(ctx, userID, constants.StatusIdle)
-			databases.ClearUserPartner(ctx, userID)
-			// Fall through to allow search below
-		} else {
-			// Verify partner is also chatting with us
-			partnerStatus, _ := databases.GetUserStatus(ctx, partnerID)
-			partnerPartnerID, _ := databases.GetUserPartner(ctx, partnerID)
-
-			if partnerStatus != constants.StatusChatting || partnerPartnerID != userID {
-				// Partner not properly connected - cleanup
-				log.Printf("⚠️ User %d stuck in chatting, partner %d status: %s, partner's partner: %d. Cleaning up...",
-					userID, partnerID, partnerStatus, partnerPartnerID)
-				databases.DisconnectUsers(ctx, userID, partnerID)
-				// Fall through to allow search below
-			} else {
-				// Valid connection exists
-				return p.sendMessage(bot, chatID, constants.MsgAlreadyChatting)
-			}
+		if state.PartnerID != 0 {
+			return p.sendMessage(bot, chatID, constants.MsgAlreadyChatting)
 		}
+		log.Printf("⚠️ User %d in chatting status without partner, resetting to idle", userID)
+		databases.SetUserStatus(ctx, userID, constants.StatusIdle)
 	}
 	// Update last active
 	databases.UpdateLastActive(ctx, userID)
@@ -139,16 +111,16 @@ func (p *ChatPlugin) doSearchAuto(ctx context.Context, bot *tgbotapi.BotAPI, cha
 
 	if databases.HasLocation(ctx, userID) {
 		searchMode = constants.SearchModeNearby
-		lat, _ = databases.GetVarFloat64(ctx, userID, constants.VarLatitude)
-		lon, _ = databases.GetVarFloat64(ctx, userID, constants.VarLongitude)
+		lat, lon, _ = databases.GetUserLocation(ctx, userID)
 		log.Printf("📍 User %d has location, using nearby mode", userID)
 	} else {
 		searchMode = constants.SearchModeRandom
 		log.Printf("🎲 User %d no location, using random mode", userID)
 	}
 
-	// Store search mode
-	databases.SetVar(ctx, userID, constants.VarSearchMode, searchMode)
+	// Store search mode & waktu mulai mencari
+	databases.SetSearchMode(ctx, userID, searchMode)
+	databases.SetSearchStartedAt(ctx, userID)
 
 	// Publish to Redis matcher
 	if p.matcher != nil {
@@ -162,21 +134,10 @@ func (p *ChatPlugin) doSearchAuto(ctx context.Context, bot *tgbotapi.BotAPI, cha
 	return p.sendMessage(bot, chatID, constants.MsgSearching)
 }
 
-// HandleCallbackQuery menangani callback dari inline keyboard
-func (p *ChatPlugin) HandleCallbackQuery(bot *tgbotapi.BotAPI, callback *tgbotapi.CallbackQuery) error {
-	ctx := context.Background()
-
-	// Handle warn callback (from log group)
-	if strings.HasPrefix(callback.Data, constants.CallbackWarnUser) {
-		return p.handleWarnCallback(ctx, bot, callback)
-	}
-
-	return nil
-}
-
-// CanHandleCallback mengecek apakah plugin bisa handle callback ini
-func (p *ChatPlugin) CanHandleCallback(data string) bool {
-	return strings.HasPrefix(data, constants.CallbackWarnUser)
+// RegisterCallbacks mendaftarkan callback warn (log group) dan rating
+func (p *ChatPlugin) RegisterCallbacks(router *CallbackRouter) {
+	router.HandleOwner(constants.CallbackWarn, p.handleWarnCallback)
+	router.Handle(constants.CallbackRate, p.handleRateCallback)
 }
 
 // handleNext skip partner dan cari baru
@@ -205,11 +166,17 @@ func (p *ChatPlugin) handleNext(ctx context.Context, bot *tgbotapi.BotAPI, chatI
 		// End current chat and search for new partner
 		partnerID, _ := databases.GetUserPartner(ctx, userID)
 		if partnerID > 0 {
+			sessionID, _ := databases.GetUserSessionID(ctx, userID)
+
 			// Notify partner
 			p.sendMessage(bot, partnerID, constants.MsgPartnerLeft)
 
 			// Disconnect users
-			databases.DisconnectUsers(ctx, userID, partnerID)
+			databases.DisconnectUsers(ctx, userID, partnerID, userID, constants.EndReasonNext)
+
+			// Minta kedua user menilai sesi ini
+			SendRatingPrompt(userID, sessionID)
+			SendRatingPrompt(partnerID, sessionID)
 		}
 
 		// Search for new partner
@@ -243,18 +210,24 @@ func (p *ChatPlugin) handleStop(ctx context.Context, bot *tgbotapi.BotAPI, chatI
 		return p.sendMessage(bot, chatID, constants.MsgSearchCancelled)
 	case constants.StatusChatting:
 		partnerID, _ := databases.GetUserPartner(ctx, userID)
+		var sessionID int64
 		if partnerID > 0 {
+			sessionID, _ = databases.GetUserSessionID(ctx, userID)
+
 			// Notify partner
 			p.sendMessage(bot, partnerID, constants.MsgPartnerLeft)
 
 			// Disconnect users
-			databases.DisconnectUsers(ctx, userID, partnerID)
+			databases.DisconnectUsers(ctx, userID, partnerID, userID, constants.EndReasonStop)
+			SendRatingPrompt(partnerID, sessionID)
 		} else {
 			// Reset user status only
 			databases.SetUserStatus(ctx, userID, constants.StatusIdle)
 		}
 
-		return p.sendMessage(bot, chatID, constants.MsgChatEnded)
+		err := p.sendMessage(bot, chatID, constants.MsgChatEnded)
+		SendRatingPrompt(userID, sessionID)
+		return err
 	}
 
 	return nil
@@ -279,14 +252,17 @@ func (p *ChatPlugin) HandleMessage(bot *tgbotapi.BotAPI, message *tgbotapi.Messa
 	databases.UpdateLastActive(ctx, userID)
 	databases.IncrementUserTotalMessages(ctx, userID)
 
+	// Catat aktivitas sesi supaya tidak ditutup karena idle
+	if sessionID, _ := databases.GetUserSessionID(ctx, userID); sessionID > 0 {
+		databases.TouchSession(ctx, sessionID, userID)
+	}
+
 	// Increment global message count for ads
-	currentCount, _ := databases.GetVarInt(ctx, userID, "msg_count_ads")
-	currentCount++
-	databases.SetVar(ctx, userID, "msg_count_ads", currentCount)
+	currentCount, _ := databases.IncrementAdsCounter(ctx, userID)
 
 	// Check if should send ads (every N messages)
-	if currentCount >= constants.AdsIntervalMessages {
-		databases.SetVar(ctx, userID, "msg_count_ads", 0)
+	if currentCount >= settings.AdsIntervalMessages.Get() {
+		databases.ResetAdsCounter(ctx, userID)
 		p.sendRandomAds(ctx, bot, userID)
 	}
 
@@ -330,7 +306,7 @@ func (p *ChatPlugin) CanHandleMessage(message *tgbotapi.Message) bool {
 // Forward functions
 func (p *ChatPlugin) forwardTextMessage(bot *tgbotapi.BotAPI, partnerID int64, text string) error {
 	msg := tgbotapi.NewMessage(partnerID, "💬 "+text)
-	_, err := bot.Send(msg)
+	_, err := sender.Send(msg, sender.PriorityHigh)
 	return err
 }
 
@@ -340,7 +316,7 @@ func (p *ChatPlugin) forwardPhoto(bot *tgbotapi.BotAPI, partnerID int64, message
 	if message.Caption != "" {
 		msg.Caption = "💬 " + message.Caption
 	}
-	sentMsg, err := bot.Send(msg)
+	sentMsg, err := sender.Send(msg, sender.PriorityHigh)
 	if err != nil {
 		return err
 	}
@@ -353,13 +329,13 @@ func (p *ChatPlugin) forwardPhoto(bot *tgbotapi.BotAPI, partnerID int64, message
 
 func (p *ChatPlugin) forwardSticker(bot *tgbotapi.BotAPI, partnerID int64, message *tgbotapi.Message) error {
 	msg := tgbotapi.NewSticker(partnerID, tgbotapi.FileID(message.Sticker.FileID))
-	_, err := bot.Send(msg)
+	_, err := sender.Send(msg, sender.PriorityHigh)
 	return err
 }
 
 func (p *ChatPlugin) forwardVoice(bot *tgbotapi.BotAPI, partnerID int64, message *tgbotapi.Message) error {
 	msg := tgbotapi.NewVoice(partnerID, tgbotapi.FileID(message.Voice.FileID))
-	_, err := bot.Send(msg)
+	_, err := sender.Send(msg, sender.PriorityHigh)
 	return err
 }
 
@@ -368,7 +344,7 @@ func (p *ChatPlugin) forwardVideo(bot *tgbotapi.BotAPI, partnerID int64, message
 	if message.Caption != "" {
 		msg.Caption = "💬 " + message.Caption
 	}
-	sentMsg, err := bot.Send(msg)
+	sentMsg, err := sender.Send(msg, sender.PriorityHigh)
 	if err != nil {
 		return err
 	}
@@ -384,7 +360,7 @@ func (p *ChatPlugin) forwardDocument(bot *tgbotapi.BotAPI, partnerID int64, mess
 	if message.Caption != "" {
 		msg.Caption = "💬 " + message.Caption
 	}
-	_, err := bot.Send(msg)
+	_, err := sender.Send(msg, sender.PriorityHigh)
 	return err
 }
 
@@ -393,14 +369,14 @@ func (p *ChatPlugin) forwardAnimation(bot *tgbotapi.BotAPI, partnerID int64, mes
 	if message.Caption != "" {
 		msg.Caption = "💬 " + message.Caption
 	}
-	_, err := bot.Send(msg)
+	_, err := sender.Send(msg, sender.PriorityHigh)
 	return err
 }
 
 func (p *ChatPlugin) sendMessage(bot *tgbotapi.BotAPI, chatID int64, text string) error {
 	msg := tgbotapi.NewMessage(chatID, text)
 	msg.ParseMode = "Markdown"
-	_, err := bot.Send(msg)
+	_, err := sender.Send(msg, sender.PriorityHigh)
 	return err
 }
 
@@ -446,23 +422,13 @@ func (p *ChatPlugin) handleShare(ctx context.Context, bot *tgbotapi.BotAPI, chat
 
 // sendRandomAds mengirim ads random ke user
 func (p *ChatPlugin) sendRandomAds(ctx context.Context, bot *tgbotapi.BotAPI, userID int64) {
-	// Get ads from global var
-	adsEnabled, _ := databases.GetGlobalVarBool(ctx, constants.VarGlobalAdsEnabled)
+	adsEnabled, _ := databases.Ads.Enabled(ctx)
 	if !adsEnabled {
 		return
 	}
 
-	adsJSON, _ := databases.GetGlobalVar(ctx, constants.VarGlobalAds)
-	if adsJSON == "" {
-		return
-	}
-
-	// Parse ads
-	var ads []struct {
-		ID      int    `json:"id"`
-		Message string `json:"message"`
-	}
-	if err := json.Unmarshal([]byte(adsJSON), &ads); err != nil || len(ads) == 0 {
+	ads, err := databases.Ads.List(ctx)
+	if err != nil || len(ads) == 0 {
 		return
 	}
 
@@ -473,12 +439,12 @@ func (p *ChatPlugin) sendRandomAds(ctx context.Context, bot *tgbotapi.BotAPI, us
 	// Send to user
 	msg := tgbotapi.NewMessage(userID, adsMsg)
 	msg.ParseMode = "Markdown"
-	bot.Send(msg)
+	sender.SendAsync(msg, sender.PriorityLow)
 }
 
 // logMediaToGroup mengirim media ke log group dengan tombol warn
 func (p *ChatPlugin) logMediaToGroup(bot *tgbotapi.BotAPI, senderID, partnerID int64, mediaType, fileID string, sentMessageID int) {
-	if constants.LogGroupID == 0 {
+	if config.C.LogGroupID == 0 {
 		return
 	}
 
@@ -486,8 +452,8 @@ func (p *ChatPlugin) logMediaToGroup(bot *tgbotapi.BotAPI, senderID, partnerID i
 	currentTime := time.Now().Format("2006-01-02 15:04:05")
 	logText := fmt.Sprintf(constants.MsgLogMedia, senderID, senderID, partnerID, partnerID, mediaType, currentTime)
 
-	// Create warn button callback data: warn_user_{senderID}_{partnerID}_{sentMessageID}
-	callbackData := fmt.Sprintf("%s%d_%d_%d", constants.CallbackWarnUser, senderID, partnerID, sentMessageID)
+	// Create warn button callback data: warn:user:{senderID}:{partnerID}:{sentMessageID}
+	callbackData := CallbackData(constants.CallbackWarn, "user", senderID, partnerID, sentMessageID)
 
 	keyboard := tgbotapi.NewInlineKeyboardMarkup(
 		tgbotapi.NewInlineKeyboardRow(
@@ -499,13 +465,13 @@ func (p *ChatPlugin) logMediaToGroup(bot *tgbotapi.BotAPI, senderID, partnerID i
 	var msg tgbotapi.Chattable
 	switch mediaType {
 	case "Photo":
-		photoMsg := tgbotapi.NewPhoto(constants.LogGroupID, tgbotapi.FileID(fileID))
+		photoMsg := tgbotapi.NewPhoto(config.C.LogGroupID, tgbotapi.FileID(fileID))
 		photoMsg.Caption = logText
 		photoMsg.ParseMode = "Markdown"
 		photoMsg.ReplyMarkup = keyboard
 		msg = photoMsg
 	case "Video":
-		videoMsg := tgbotapi.NewVideo(constants.LogGroupID, tgbotapi.FileID(fileID))
+		videoMsg := tgbotapi.NewVideo(config.C.LogGroupID, tgbotapi.FileID(fileID))
 		videoMsg.Caption = logText
 		videoMsg.ParseMode = "Markdown"
 		videoMsg.ReplyMarkup = keyboard
@@ -514,49 +480,45 @@ func (p *ChatPlugin) logMediaToGroup(bot *tgbotapi.BotAPI, senderID, partnerID i
 		return
 	}
 
-	_, err := bot.Send(msg)
+	_, err := sender.Send(msg, sender.PriorityNormal)
 	if err != nil {
 		log.Printf("Error logging media to group: %v", err)
 	}
 }
 
-// handleWarnCallback menangani callback warn dari log group
-func (p *ChatPlugin) handleWarnCallback(ctx context.Context, bot *tgbotapi.BotAPI, callback *tgbotapi.CallbackQuery) error {
-	// Parse callback data: warn_user_{senderID}_{partnerID}_{sentMessageID}
-	data := strings.TrimPrefix(callback.Data, constants.CallbackWarnUser)
-	parts := strings.Split(data, "_")
-	if len(parts) != 3 {
-		return fmt.Errorf("invalid warn callback data")
-	}
-
-	senderID, err := strconv.ParseInt(parts[0], 10, 64)
+// handleWarnCallback menangani callback warn dari log group (hanya owner)
+func (p *ChatPlugin) handleWarnCallback(ctx context.Context, bot *tgbotapi.BotAPI, callback *Callback) error {
+	// Parse callback args: {senderID}:{partnerID}:{sentMessageID}
+	senderID, err := callback.Int64(0)
 	if err != nil {
 		return err
 	}
 
-	partnerID, err := strconv.ParseInt(parts[1], 10, 64)
+	partnerID, err := callback.Int64(1)
 	if err != nil {
 		return err
 	}
 
-	sentMessageID, err := strconv.Atoi(parts[2])
+	sentMessageID, err := callback.Int(2)
 	if err != nil {
 		return err
 	}
 
 	// Increment warn count
-	currentWarns, _ := databases.GetVarInt(ctx, senderID, constants.VarWarnCount)
-	newWarns := currentWarns + 1
-	databases.SetVar(ctx, senderID, constants.VarWarnCount, newWarns)
+	newWarns, err := databases.IncrementWarnCount(ctx, senderID)
+	if err != nil {
+		return err
+	}
 
 	// Delete the media message from partner's chat
 	deleteMsg := tgbotapi.NewDeleteMessage(partnerID, sentMessageID)
-	bot.Send(deleteMsg)
+	sender.Request(deleteMsg, sender.PriorityNormal)
 
 	// Check if should auto-ban
-	if newWarns >= constants.MaxWarnings {
+	maxWarnings := settings.MaxWarnings.Get()
+	if newWarns >= maxWarnings {
 		// Ban user
-		databases.SetVar(ctx, senderID, constants.VarIsBanned, true)
+		databases.BanUser(ctx, senderID)
 
 		// Disconnect if chatting
 		status, _ := databases.GetUserStatus(ctx, senderID)
@@ -564,7 +526,7 @@ func (p *ChatPlugin) handleWarnCallback(ctx context.Context, bot *tgbotapi.BotAP
 			partnerIDCurrent, _ := databases.GetUserPartner(ctx, senderID)
 			if partnerIDCurrent > 0 {
 				p.sendMessage(bot, partnerIDCurrent, constants.MsgPartnerLeft)
-				databases.DisconnectUsers(ctx, senderID, partnerIDCurrent)
+				databases.DisconnectUsers(ctx, senderID, partnerIDCurrent, 0, constants.EndReasonBanned)
 			}
 		}
 
@@ -573,44 +535,42 @@ func (p *ChatPlugin) handleWarnCallback(ctx context.Context, bot *tgbotapi.BotAP
 		p.sendMessage(bot, senderID, bannedMsg)
 
 		// Update callback answer and log message
-		callbackResponse := tgbotapi.NewCallback(callback.ID, fmt.Sprintf("User %d telah dibanned!", senderID))
-		bot.Send(callbackResponse)
+		callback.Answer(fmt.Sprintf("User %d telah dibanned!", senderID))
 
 		// Update log group message to show banned status
-		editText := fmt.Sprintf("%s\n\n🚫 *USER BANNED!* (Warn: %d/%d)", callback.Message.Caption, newWarns, constants.MaxWarnings)
+		editText := fmt.Sprintf("%s\n\n🚫 *USER BANNED!* (Warn: %d/%d)", callback.Message.Caption, newWarns, maxWarnings)
 		if callback.Message.Photo != nil {
-			editCaption := tgbotapi.NewEditMessageCaption(constants.LogGroupID, callback.Message.MessageID, editText)
+			editCaption := tgbotapi.NewEditMessageCaption(config.C.LogGroupID, callback.Message.MessageID, editText)
 			editCaption.ParseMode = "Markdown"
-			bot.Send(editCaption)
+			sender.Send(editCaption, sender.PriorityNormal)
 		} else if callback.Message.Video != nil {
-			editCaption := tgbotapi.NewEditMessageCaption(constants.LogGroupID, callback.Message.MessageID, editText)
+			editCaption := tgbotapi.NewEditMessageCaption(config.C.LogGroupID, callback.Message.MessageID, editText)
 			editCaption.ParseMode = "Markdown"
-			bot.Send(editCaption)
+			sender.Send(editCaption, sender.PriorityNormal)
 		}
 
 		log.Printf("🚫 User %d auto-banned after %d warnings", senderID, newWarns)
 	} else {
 		// Notify user about warning
-		warnMsg := fmt.Sprintf(constants.MsgWarnedNotify, newWarns, constants.MaxWarnings, constants.MaxWarnings)
+		warnMsg := fmt.Sprintf(constants.MsgWarnedNotify, newWarns, maxWarnings, maxWarnings)
 		p.sendMessage(bot, senderID, warnMsg)
 
 		// Update callback answer
-		callbackResponse := tgbotapi.NewCallback(callback.ID, fmt.Sprintf("User %d diberi warning (%d/%d)", senderID, newWarns, constants.MaxWarnings))
-		bot.Send(callbackResponse)
+		callback.Answer(fmt.Sprintf("User %d diberi warning (%d/%d)", senderID, newWarns, maxWarnings))
 
 		// Update log group message to show warn count
-		editText := fmt.Sprintf("%s\n\n⚠️ *WARNED!* (Warn: %d/%d)", callback.Message.Caption, newWarns, constants.MaxWarnings)
+		editText := fmt.Sprintf("%s\n\n⚠️ *WARNED!* (Warn: %d/%d)", callback.Message.Caption, newWarns, maxWarnings)
 		if callback.Message.Photo != nil {
-			editCaption := tgbotapi.NewEditMessageCaption(constants.LogGroupID, callback.Message.MessageID, editText)
+			editCaption := tgbotapi.NewEditMessageCaption(config.C.LogGroupID, callback.Message.MessageID, editText)
 			editCaption.ParseMode = "Markdown"
-			bot.Send(editCaption)
+			sender.Send(editCaption, sender.PriorityNormal)
 		} else if callback.Message.Video != nil {
-			editCaption := tgbotapi.NewEditMessageCaption(constants.LogGroupID, callback.Message.MessageID, editText)
+			editCaption := tgbotapi.NewEditMessageCaption(config.C.LogGroupID, callback.Message.MessageID, editText)
 			editCaption.ParseMode = "Markdown"
-			bot.Send(editCaption)
+			sender.Send(editCaption, sender.PriorityNormal)
 		}
 
-		log.Printf("⚠️ User %d warned (%d/%d)", senderID, newWarns, constants.MaxWarnings)
+		log.Printf("⚠️ User %d warned (%d/%d)", senderID, newWarns, maxWarnings)
 	}
 
 	return nil
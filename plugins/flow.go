@@ -0,0 +1,380 @@
+package plugins
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"tg-anon-go/config"
+	"tg-anon-go/constants"
+	"tg-anon-go/databases"
+	"tg-anon-go/sender"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// FlowInput adalah input user untuk satu langkah flow (teks, pilihan tombol, atau lokasi)
+type FlowInput struct {
+	Text     string
+	Option   string // Value dari tombol inline yang dipilih
+	Location *tgbotapi.Location
+}
+
+// Value mengembalikan pilihan tombol jika ada, atau teks yang diketik user
+func (in FlowInput) Value() string {
+	if in.Option != "" {
+		return in.Option
+	}
+	return strings.TrimSpace(in.Text)
+}
+
+// FlowOption adalah satu tombol pilihan di langkah flow
+type FlowOption struct {
+	Label string
+	Value string
+}
+
+// FlowStep adalah satu langkah di sebuah flow
+type FlowStep struct {
+	Name string
+
+	// Prompt membuat teks pertanyaan (Markdown) untuk langkah ini
+	Prompt func(ctx context.Context, fc *FlowContext) string
+
+	// Options adalah baris tombol inline; value dikirim lewat callback
+	Options [][]FlowOption
+
+	// Keyboard membuat reply markup custom (misalnya tombol share lokasi)
+	Keyboard func(fc *FlowContext) interface{}
+
+	// Validate mengecek input; error yang dikembalikan ditampilkan ke user
+	Validate func(in FlowInput) error
+
+	// Save menyimpan input yang sudah valid
+	Save func(ctx context.Context, fc *FlowContext, in FlowInput) error
+}
+
+// Flow adalah kumpulan langkah multi-step (registrasi, edit profil, wizard lain)
+type Flow struct {
+	Name    string
+	Steps   []*FlowStep
+	Timeout time.Duration // 0 = memakai config.C.FlowTimeout
+
+	// OnComplete dipanggil setelah langkah terakhir tersimpan
+	OnComplete func(ctx context.Context, fc *FlowContext) error
+
+	// CancelMessage dikirim saat user /cancel (default MsgFlowCancelled)
+	CancelMessage string
+}
+
+// step mencari langkah berdasarkan nama
+func (f *Flow) step(name string) (*FlowStep, int) {
+	for i, s := range f.Steps {
+		if s.Name == name {
+			return s, i
+		}
+	}
+	return nil, -1
+}
+
+// timeout mengembalikan batas waktu flow
+func (f *Flow) timeout() time.Duration {
+	if f.Timeout > 0 {
+		return f.Timeout
+	}
+	return time.Duration(config.C.FlowTimeout) * time.Minute
+}
+
+// FlowContext berisi state flow yang sedang berjalan untuk satu user
+type FlowContext struct {
+	Flow   *Flow
+	UserID int64
+	ChatID int64
+	data   map[string]string
+}
+
+// Get mengambil data sementara flow
+func (fc *FlowContext) Get(key string) string {
+	return fc.data[key]
+}
+
+// Set menyimpan data sementara flow (dipersist bersama state flow)
+func (fc *FlowContext) Set(key, value string) {
+	fc.data[key] = value
+}
+
+// ErrFlowNotFound dikembalikan saat flow belum diregistrasi
+var ErrFlowNotFound = errors.New("flow not found")
+
+// FlowEngine menjalankan flow dan menyimpan state-nya di var system
+type FlowEngine struct {
+	mu    sync.RWMutex
+	flows map[string]*Flow
+}
+
+// NewFlowEngine membuat FlowEngine baru
+func NewFlowEngine() *FlowEngine {
+	return &FlowEngine{flows: make(map[string]*Flow)}
+}
+
+// Register mendaftarkan flow
+func (e *FlowEngine) Register(flow *Flow) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.flows[flow.Name] = flow
+}
+
+// get mengambil flow berdasarkan nama
+func (e *FlowEngine) get(name string) *Flow {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return e.flows[name]
+}
+
+// Start memulai flow dari langkah pertama (flow lain yang aktif akan diganti)
+func (e *FlowEngine) Start(ctx context.Context, name string, userID, chatID int64) error {
+	flow := e.get(name)
+	if flow == nil || len(flow.Steps) == 0 {
+		return fmt.Errorf("%w: %s", ErrFlowNotFound, name)
+	}
+
+	// Flow tidak berjalan selama chat supaya pesan chat tidak perlu dicek ke flow (lihat HandleMessage)
+	if status, _ := databases.GetUserStatus(ctx, userID); status == constants.StatusChatting {
+		return sendFlowMessage(chatID, constants.MsgAlreadyChatting, nil)
+	}
+
+	fc := &FlowContext{Flow: flow, UserID: userID, ChatID: chatID, data: make(map[string]string)}
+	first := flow.Steps[0]
+	if err := e.save(ctx, fc, first.Name); err != nil {
+		return err
+	}
+	return e.prompt(ctx, fc, first)
+}
+
+// Active mengembalikan flow dan langkah yang sedang berjalan untuk user
+func (e *FlowEngine) Active(ctx context.Context, userID, chatID int64) (*FlowContext, *FlowStep, bool) {
+	name, _ := databases.GetVar(ctx, userID, constants.VarFlowName)
+	if name == "" {
+		return nil, nil, false
+	}
+
+	flow := e.get(name)
+	stepName, _ := databases.GetVar(ctx, userID, constants.VarFlowStep)
+	if flow == nil {
+		e.clear(ctx, userID)
+		return nil, nil, false
+	}
+	step, _ := flow.step(stepName)
+	if step == nil {
+		e.clear(ctx, userID)
+		return nil, nil, false
+	}
+
+	fc := &FlowContext{Flow: flow, UserID: userID, ChatID: chatID, data: make(map[string]string)}
+	if raw, _ := databases.GetVar(ctx, userID, constants.VarFlowData); raw != "" {
+		json.Unmarshal([]byte(raw), &fc.data)
+	}
+	return fc, step, true
+}
+
+// HandleMessage memproses pesan user yang sedang berada di dalam flow.
+// Mengembalikan false jika user tidak punya flow aktif.
+func (e *FlowEngine) HandleMessage(ctx context.Context, message *tgbotapi.Message) (bool, error) {
+	// Pesan chat langsung diteruskan; status dibaca dari cache sehingga tidak perlu query flow
+	if status, _ := databases.GetUserStatus(ctx, message.From.ID); status == constants.StatusChatting {
+		return false, nil
+	}
+
+	fc, step, ok := e.Active(ctx, message.From.ID, message.Chat.ID)
+	if !ok {
+		return false, nil
+	}
+
+	if e.expired(ctx, fc) {
+		e.clear(ctx, fc.UserID)
+		return true, sendFlowMessage(fc.ChatID, constants.MsgFlowExpired, tgbotapi.NewRemoveKeyboard(true))
+	}
+
+	// Langkah dengan tombol pilihan hanya menerima callback
+	if len(step.Options) > 0 {
+		return true, e.reject(ctx, fc, step, errors.New(constants.MsgFlowUseButtons))
+	}
+
+	in := FlowInput{Text: message.Text, Location: message.Location}
+	return true, e.advance(ctx, fc, step, in)
+}
+
+// HandleCallback memproses tombol pilihan flow (format: flow:{step}:{value})
+func (e *FlowEngine) HandleCallback(ctx context.Context, bot *tgbotapi.BotAPI, callback *Callback) error {
+	if callback.Message == nil {
+		return nil
+	}
+	stepName, value := callback.Action, callback.Arg(0)
+
+	fc, step, ok := e.Active(ctx, callback.From.ID, callback.Message.Chat.ID)
+	if !ok || step.Name != stepName || e.expired(ctx, fc) {
+		if ok {
+			e.clear(ctx, fc.UserID)
+		}
+		callback.Answer(constants.MsgFlowStaleButton)
+		callback.DeleteMessage()
+		return nil
+	}
+
+	callback.Answer("✅")
+	callback.DeleteMessage()
+
+	return e.advance(ctx, fc, step, FlowInput{Option: value})
+}
+
+// Cancel membatalkan flow aktif user. Mengembalikan false jika tidak ada flow aktif.
+func (e *FlowEngine) Cancel(ctx context.Context, userID, chatID int64) (bool, error) {
+	fc, _, ok := e.Active(ctx, userID, chatID)
+	if !ok {
+		return false, nil
+	}
+	e.clear(ctx, userID)
+
+	text := fc.Flow.CancelMessage
+	if text == "" {
+		text = constants.MsgFlowCancelled
+	}
+	return true, sendFlowMessage(chatID, text, tgbotapi.NewRemoveKeyboard(true))
+}
+
+// advance memvalidasi input, menyimpan, lalu lanjut ke langkah berikutnya atau selesai
+func (e *FlowEngine) advance(ctx context.Context, fc *FlowContext, step *FlowStep, in FlowInput) error {
+	if step.Validate != nil {
+		if err := step.Validate(in); err != nil {
+			return e.reject(ctx, fc, step, err)
+		}
+	}
+
+	if step.Save != nil {
+		if err := step.Save(ctx, fc, in); err != nil {
+			log.Printf("Error saving flow %s step %s for user %d: %v", fc.Flow.Name, step.Name, fc.UserID, err)
+			return sendFlowMessage(fc.ChatID, constants.MsgError, nil)
+		}
+	}
+
+	_, index := fc.Flow.step(step.Name)
+	if index+1 < len(fc.Flow.Steps) {
+		next := fc.Flow.Steps[index+1]
+		if err := e.save(ctx, fc, next.Name); err != nil {
+			return err
+		}
+		return e.prompt(ctx, fc, next)
+	}
+
+	// Langkah terakhir selesai
+	e.clear(ctx, fc.UserID)
+	if fc.Flow.OnComplete != nil {
+		return fc.Flow.OnComplete(ctx, fc)
+	}
+	return nil
+}
+
+// reject mengirim pesan error validasi lalu mengulang prompt jika langkah memakai tombol
+func (e *FlowEngine) reject(ctx context.Context, fc *FlowContext, step *FlowStep, err error) error {
+	if err != nil {
+		if sendErr := sendFlowMessage(fc.ChatID, err.Error(), nil); sendErr != nil {
+			return sendErr
+		}
+	}
+	if len(step.Options) > 0 {
+		return e.prompt(ctx, fc, step)
+	}
+	return nil
+}
+
+// prompt mengirim pertanyaan untuk sebuah langkah beserta keyboard-nya
+func (e *FlowEngine) prompt(ctx context.Context, fc *FlowContext, step *FlowStep) error {
+	text := ""
+	if step.Prompt != nil {
+		text = step.Prompt(ctx, fc)
+	}
+	text += constants.MsgFlowCancelHint
+
+	var markup interface{}
+	switch {
+	case len(step.Options) > 0:
+		var rows [][]tgbotapi.InlineKeyboardButton
+		for _, optionRow := range step.Options {
+			var row []tgbotapi.InlineKeyboardButton
+			for _, option := range optionRow {
+				data := CallbackData(constants.CallbackFlow, step.Name, option.Value)
+				row = append(row, tgbotapi.NewInlineKeyboardButtonData(option.Label, data))
+			}
+			rows = append(rows, row)
+		}
+		markup = tgbotapi.NewInlineKeyboardMarkup(rows...)
+	case step.Keyboard != nil:
+		markup = step.Keyboard(fc)
+	}
+
+	return sendFlowMessage(fc.ChatID, text, markup)
+}
+
+// save menyimpan posisi flow user ke var system
+func (e *FlowEngine) save(ctx context.Context, fc *FlowContext, stepName string) error {
+	data, err := json.Marshal(fc.data)
+	if err != nil {
+		return err
+	}
+	if err := databases.SetVar(ctx, fc.UserID, constants.VarFlowName, fc.Flow.Name); err != nil {
+		return err
+	}
+	if err := databases.SetVar(ctx, fc.UserID, constants.VarFlowStep, stepName); err != nil {
+		return err
+	}
+	if err := databases.SetVar(ctx, fc.UserID, constants.VarFlowData, string(data)); err != nil {
+		return err
+	}
+	return databases.SetVar(ctx, fc.UserID, constants.VarFlowUpdatedAt, time.Now().Unix())
+}
+
+// clear menghapus state flow user
+func (e *FlowEngine) clear(ctx context.Context, userID int64) {
+	databases.DeleteVar(ctx, userID, constants.VarFlowName)
+	databases.DeleteVar(ctx, userID, constants.VarFlowStep)
+	databases.DeleteVar(ctx, userID, constants.VarFlowData)
+	databases.DeleteVar(ctx, userID, constants.VarFlowUpdatedAt)
+}
+
+// expired mengecek apakah langkah terakhir sudah melewati timeout flow
+func (e *FlowEngine) expired(ctx context.Context, fc *FlowContext) bool {
+	updatedAt, err := databases.GetVarInt64(ctx, fc.UserID, constants.VarFlowUpdatedAt)
+	if err != nil || updatedAt == 0 {
+		return false
+	}
+	return time.Since(time.Unix(updatedAt, 0)) > fc.Flow.timeout()
+}
+
+// sendFlowMessage mengirim pesan Markdown dengan reply markup opsional
+func sendFlowMessage(chatID int64, text string, markup interface{}) error {
+	msg := tgbotapi.NewMessage(chatID, text)
+	msg.ParseMode = "Markdown"
+	if markup != nil {
+		msg.ReplyMarkup = markup
+	}
+	_, err := sender.Send(msg, sender.PriorityNormal)
+	return err
+}
+
+// flows adalah flow engine default yang dipakai semua plugin
+var flows = NewFlowEngine()
+
+// RegisterFlow mendaftarkan flow ke engine default
+func RegisterFlow(flow *Flow) {
+	flows.Register(flow)
+}
+
+// StartFlow memulai flow di engine default
+func StartFlow(ctx context.Context, name string, userID, chatID int64) error {
+	return flows.Start(ctx, name, userID, chatID)
+}
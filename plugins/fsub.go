@@ -7,6 +7,7 @@ import (
 
 	"tg-anon-go/constants"
 	"tg-anon-go/databases"
+	"tg-anon-go/sender"
 
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
 )
@@ -71,19 +72,19 @@ func SendFsubPrompt(bot *tgbotapi.BotAPI, chatID int64, channelUsername string)
 				tgbotapi.NewInlineKeyboardButtonURL("📢 Join Channel", channelURL),
 			),
 			tgbotapi.NewInlineKeyboardRow(
-				tgbotapi.NewInlineKeyboardButtonData("✅ Sudah Join", "fsub_verify"),
+				tgbotapi.NewInlineKeyboardButtonData("✅ Sudah Join", CallbackData(constants.CallbackFsub, "verify")),
 			),
 		)
 	} else {
 		// For private channels, only show verify button
 		keyboard = tgbotapi.NewInlineKeyboardMarkup(
 			tgbotapi.NewInlineKeyboardRow(
-				tgbotapi.NewInlineKeyboardButtonData("✅ Sudah Join", "fsub_verify"),
+				tgbotapi.NewInlineKeyboardButtonData("✅ Sudah Join", CallbackData(constants.CallbackFsub, "verify")),
 			),
 		)
 	}
 	msg.ReplyMarkup = keyboard
 
-	_, err := bot.Send(msg)
+	_, err := sender.Send(msg, sender.PriorityNormal)
 	return err
 }
@@ -3,10 +3,11 @@ package plugins
 import (
 	"context"
 	"log"
-	"strings"
+	"runtime/debug"
 
 	"tg-anon-go/constants"
 	"tg-anon-go/matcher"
+	"tg-anon-go/sender"
 
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
 )
@@ -15,16 +16,35 @@ import (
 type Manager struct {
 	plugins     []Plugin
 	commands    map[string]Plugin
+	callbacks   *CallbackRouter
+	middlewares []Middleware
+	handler     HandlerFunc
 	adminPlugin *AdminPlugin
 	matcher     *matcher.Matcher
 }
 
 // NewManager membuat instance Manager baru
 func NewManager() *Manager {
-	return &Manager{
-		plugins:  make([]Plugin, 0),
-		commands: make(map[string]Plugin),
+	reporter = newConfiguredReporter()
+
+	m := &Manager{
+		plugins:   make([]Plugin, 0),
+		commands:  make(map[string]Plugin),
+		callbacks: NewCallbackRouter(),
 	}
+	m.callbacks.owner = "manager"
+
+	// Callback bawaan manager: verifikasi fsub dan tombol flow multi-langkah
+	m.callbacks.Handle(constants.CallbackFsub, m.handleFsubVerify)
+	m.callbacks.Handle(constants.CallbackFlow, flows.HandleCallback)
+
+	// Pipeline bawaan: recover, filter chat, auth, maintenance, ban, rate limit, fsub, registrasi
+	m.Use(defaultMiddlewares()...)
+
+	// Tangani user yang memblokir bot (403) secara terpusat
+	sender.OnBlocked(m.handleBlockedUser)
+
+	return m
 }
 
 // Register mendaftarkan plugin
@@ -37,6 +57,14 @@ func (m *Manager) Register(plugin Plugin) {
 		log.Printf("📦 Registered command: /%s from plugin: %s", cmd, plugin.Name())
 	}
 
+	// Daftarkan namespace callback dan middleware milik plugin
+	m.callbacks.owner = plugin.Name()
+	plugin.RegisterCallbacks(m.callbacks)
+	m.callbacks.owner = "manager"
+	if middlewares := plugin.Middlewares(); len(middlewares) > 0 {
+		m.Use(middlewares...)
+	}
+
 	log.Printf("✅ Plugin loaded: %s", plugin.Name())
 }
 
@@ -44,15 +72,14 @@ func (m *Manager) Register(plugin Plugin) {
 func (m *Manager) SetMatcher(mch *matcher.Matcher) {
 	m.matcher = mch
 
-	// Pass matcher to ChatPlugin
+	// Pass matcher to plugins that need it (ChatPlugin, AdminPlugin)
 	for _, plugin := range m.plugins {
-		if chatPlugin, ok := plugin.(*ChatPlugin); ok {
-			chatPlugin.SetMatcher(mch)
-			break
+		if p, ok := plugin.(interface{ SetMatcher(*matcher.Matcher) }); ok {
+			p.SetMatcher(mch)
 		}
 	}
 
-	log.Println("✅ Matcher instance set in plugin manager and ChatPlugin")
+	log.Println("✅ Matcher instance set in plugin manager and plugins")
 }
 
 // GetMatcher gets the matcher instance
@@ -62,105 +89,118 @@ func (m *Manager) GetMatcher() *matcher.Matcher {
 
 // HandleUpdate menangani update dari Telegram
 func (m *Manager) HandleUpdate(bot *tgbotapi.BotAPI, update tgbotapi.Update) {
-	// Handle callback query (inline keyboard)
-	if update.CallbackQuery != nil {
-		m.handleCallback(bot, update.CallbackQuery)
-		return
-	}
+	// Jaring terakhir untuk panic di luar middleware (misalnya my_chat_member)
+	defer func() {
+		if r := recover(); r != nil {
+			var userID int64
+			if user := update.SentFrom(); user != nil {
+				userID = user.ID
+			}
+			reporter.Panic("manager", updateKind(update), userID, r, debug.Stack())
+		}
+	}()
 
-	if update.Message == nil {
+	// Handle perubahan status bot (user block/unblock bot)
+	if update.MyChatMember != nil {
+		m.handleMyChatMember(update.MyChatMember)
 		return
 	}
 
-	message := update.Message
-
-	// Ignore messages from log group to prevent loop
-	if message.Chat.ID == constants.LogGroupID {
-		log.Printf("🚫 Ignoring message from log group: %d", message.Chat.ID)
+	uc := newUpdateContext(bot, update)
+	if uc == nil {
 		return
 	}
 
-	// Ignore group messages (only handle private chats)
-	if !message.Chat.IsPrivate() {
-		log.Printf("🚫 Ignoring group message from chat: %d", message.Chat.ID)
-		return
+	if err := m.handler(uc); err != nil {
+		reporter.Error("manager", uc.UpdateType(), uc.UserID, err)
 	}
+}
 
-	// Check if user is owner - owners bypass fsub
-	isOwner := false
-	for _, ownerID := range constants.OwnerIDs {
-		if message.From.ID == ownerID {
-			isOwner = true
-			break
-		}
+// updateKind mendeskripsikan jenis update mentah untuk laporan error
+func updateKind(update tgbotapi.Update) string {
+	switch {
+	case update.MyChatMember != nil:
+		return "my_chat_member"
+	case update.CallbackQuery != nil:
+		return "callback"
+	case update.Message != nil:
+		return "message"
+	default:
+		return "other"
 	}
+}
 
-	// Check fsub (force subscribe) - except for /start command and owners
-	if !isOwner {
-		if message.IsCommand() {
-			command := strings.ToLower(message.Command())
-			if command != "start" {
-				ctx := context.Background()
-				allowed, channel := CheckFsub(ctx, bot, message.From.ID)
-				if !allowed {
-					SendFsubPrompt(bot, message.Chat.ID, channel)
-					return
-				}
-			}
-		} else {
-			// Check fsub for regular messages too
-			ctx := context.Background()
-			allowed, channel := CheckFsub(ctx, bot, message.From.ID)
-			if !allowed {
-				SendFsubPrompt(bot, message.Chat.ID, channel)
-				return
-			}
-		}
+// Use menambahkan middleware ke pipeline. Middleware dijalankan sesuai urutan pendaftaran.
+func (m *Manager) Use(middlewares ...Middleware) {
+	m.middlewares = append(m.middlewares, middlewares...)
+	m.handler = Chain(m.dispatch, m.middlewares...)
+}
+
+// dispatch meneruskan update yang sudah lolos middleware ke command, flow, plugin atau callback router
+func (m *Manager) dispatch(uc *UpdateContext) error {
+	if uc.Callback != nil {
+		m.callbacks.Dispatch(uc)
+		return nil
 	}
 
+	message := uc.Message
+
 	// Handle command
-	if message.IsCommand() {
-		command := strings.ToLower(message.Command())
+	if uc.Command != "" {
+		// /cancel membatalkan flow multi-langkah yang sedang berjalan
+		if uc.Command == constants.CmdCancel {
+			uc.Plugin = "flow"
+			m.handleCancel(message)
+			return nil
+		}
 
-		if plugin, exists := m.commands[command]; exists {
-			if err := plugin.HandleCommand(bot, message, command); err != nil {
-				log.Printf("Error handling command /%s: %v", command, err)
+		if plugin, exists := m.commands[uc.Command]; exists {
+			uc.Plugin = plugin.Name()
+			if err := plugin.HandleCommand(uc.Bot, message, uc.Command); err != nil {
+				reporter.Error(uc.Plugin, uc.UpdateType(), uc.UserID, err)
 			}
-			return
+			return nil
 		}
 
 		// Unknown command
-		log.Printf("Unknown command: /%s", command)
-		return
+		log.Printf("Unknown command: /%s", uc.Command)
+		return nil
+	}
+
+	// Pesan untuk flow multi-langkah (registrasi, edit profil) diproses lebih dulu
+	uc.Plugin = "flow"
+	if handled, err := flows.HandleMessage(uc.Ctx, message); handled {
+		if err != nil {
+			reporter.Error(uc.Plugin, uc.UpdateType(), uc.UserID, err)
+		}
+		return nil
 	}
 
 	// Handle regular message
 	for _, plugin := range m.plugins {
+		uc.Plugin = plugin.Name()
 		if plugin.CanHandleMessage(message) {
-			if err := plugin.HandleMessage(bot, message); err != nil {
-				log.Printf("Error handling message in plugin %s: %v", plugin.Name(), err)
+			if err := plugin.HandleMessage(uc.Bot, message); err != nil {
+				reporter.Error(uc.Plugin, uc.UpdateType(), uc.UserID, err)
 			}
-			return
+			return nil
 		}
 	}
+	return nil
 }
 
-// handleCallback menangani callback query dari inline keyboard
-func (m *Manager) handleCallback(bot *tgbotapi.BotAPI, callback *tgbotapi.CallbackQuery) {
-	// Handle fsub verification callback first
-	if callback.Data == "fsub_verify" {
-		m.handleFsubVerify(bot, callback)
+// handleCancel membatalkan flow aktif user
+func (m *Manager) handleCancel(message *tgbotapi.Message) {
+	ctx := context.Background()
+	cancelled, err := flows.Cancel(ctx, message.From.ID, message.Chat.ID)
+	if err != nil {
+		log.Printf("Error cancelling flow for user %d: %v", message.From.ID, err)
 		return
 	}
-
-	// Delegate to plugins
-	for _, plugin := range m.plugins {
-		if plugin.CanHandleCallback(callback.Data) {
-			if err := plugin.HandleCallbackQuery(bot, callback); err != nil {
-				log.Printf("Error handling callback in plugin %s: %v", plugin.Name(), err)
-			}
-			return
-		}
+	if !cancelled {
+		msg := tgbotapi.NewMessage(message.Chat.ID, constants.MsgFlowNothingToCancel)
+		msg.ParseMode = "Markdown"
+		sender.Send(msg, sender.PriorityNormal)
 	}
 }
 
@@ -177,8 +217,10 @@ func (m *Manager) LoadDefaultPlugins() {
 }
 
 // handleFsubVerify handles fsub verification callback
-func (m *Manager) handleFsubVerify(bot *tgbotapi.BotAPI, callback *tgbotapi.CallbackQuery) {
-	ctx := context.Background()
+func (m *Manager) handleFsubVerify(ctx context.Context, bot *tgbotapi.BotAPI, callback *Callback) error {
+	if callback.Message == nil {
+		return nil
+	}
 	userID := callback.From.ID
 
 	// Check if user is now a member
@@ -192,12 +234,10 @@ func (m *Manager) handleFsubVerify(bot *tgbotapi.BotAPI, callback *tgbotapi.Call
 		callbackText = "❌ Belum join channel"
 	}
 
-	callbackResponse := tgbotapi.NewCallback(callback.ID, callbackText)
-	bot.Send(callbackResponse)
+	callback.Answer(callbackText)
 
 	// Delete the prompt message
-	deleteMsg := tgbotapi.NewDeleteMessage(callback.Message.Chat.ID, callback.Message.MessageID)
-	bot.Send(deleteMsg)
+	callback.DeleteMessage()
 
 	// Send result message
 	var msg tgbotapi.MessageConfig
@@ -209,7 +249,8 @@ func (m *Manager) handleFsubVerify(bot *tgbotapi.BotAPI, callback *tgbotapi.Call
 		SendFsubPrompt(bot, callback.Message.Chat.ID, channel)
 	}
 	msg.ParseMode = "Markdown"
-	bot.Send(msg)
+	_, err := sender.Send(msg, sender.PriorityNormal)
+	return err
 }
 
 // GetAdminPlugin mengembalikan admin plugin untuk akses ads
@@ -0,0 +1,343 @@
+package plugins
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"runtime/debug"
+	"strings"
+	"sync"
+	"time"
+
+	"tg-anon-go/config"
+	"tg-anon-go/constants"
+	"tg-anon-go/databases"
+	"tg-anon-go/sender"
+	"tg-anon-go/settings"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// UpdateContext berisi update yang sedang diproses oleh middleware chain
+type UpdateContext struct {
+	Ctx      context.Context
+	Bot      *tgbotapi.BotAPI
+	Message  *tgbotapi.Message       // Terisi untuk command & pesan biasa
+	Callback *tgbotapi.CallbackQuery // Terisi untuk callback query
+	UserID   int64
+	ChatID   int64
+	Command  string // Command lowercase tanpa "/" (kosong jika bukan command)
+	IsOwner  bool
+	Plugin   string // Plugin yang sedang menangani update (untuk laporan error)
+}
+
+// newUpdateContext membuat UpdateContext dari update Telegram (nil jika update tidak diproses)
+func newUpdateContext(bot *tgbotapi.BotAPI, update tgbotapi.Update) *UpdateContext {
+	uc := &UpdateContext{Ctx: context.Background(), Bot: bot}
+
+	switch {
+	case update.CallbackQuery != nil:
+		uc.Callback = update.CallbackQuery
+		uc.UserID = update.CallbackQuery.From.ID
+		uc.ChatID = update.CallbackQuery.From.ID
+		if update.CallbackQuery.Message != nil {
+			uc.ChatID = update.CallbackQuery.Message.Chat.ID
+		}
+	case update.Message != nil:
+		uc.Message = update.Message
+		uc.ChatID = update.Message.Chat.ID
+		if update.Message.From != nil {
+			uc.UserID = update.Message.From.ID
+		}
+		if update.Message.IsCommand() {
+			uc.Command = strings.ToLower(update.Message.Command())
+		}
+	default:
+		return nil
+	}
+
+	return uc
+}
+
+// UpdateType mendeskripsikan jenis update untuk log dan laporan error
+func (uc *UpdateContext) UpdateType() string {
+	switch {
+	case uc.Callback != nil:
+		return "callback " + callbackLabel(uc.Callback.Data)
+	case uc.Command != "":
+		return "command /" + uc.Command
+	default:
+		return "message"
+	}
+}
+
+// Reply membalas user: kirim pesan untuk message, atau alert untuk callback
+func (uc *UpdateContext) Reply(text string) {
+	if uc.Callback != nil {
+		sender.Request(tgbotapi.NewCallbackWithAlert(uc.Callback.ID, text), sender.PriorityNormal)
+		return
+	}
+
+	msg := tgbotapi.NewMessage(uc.ChatID, text)
+	msg.ParseMode = "Markdown"
+	sender.Send(msg, sender.PriorityNormal)
+}
+
+// HandlerFunc memproses satu update
+type HandlerFunc func(uc *UpdateContext) error
+
+// Middleware membungkus HandlerFunc. Middleware boleh menghentikan update dengan tidak memanggil next.
+type Middleware func(next HandlerFunc) HandlerFunc
+
+// Chain menyusun middleware di sekitar handler. Middleware pertama adalah yang paling luar.
+func Chain(handler HandlerFunc, middlewares ...Middleware) HandlerFunc {
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		handler = middlewares[i](handler)
+	}
+	return handler
+}
+
+// defaultMiddlewares adalah pipeline bawaan manager, dijalankan sebelum middleware plugin
+func defaultMiddlewares() []Middleware {
+	return []Middleware{
+		recoverMiddleware,
+		chatFilterMiddleware,
+		authMiddleware,
+		maintenanceMiddleware,
+		banMiddleware,
+		rateLimitMiddleware(newRateLimiter(config.C.RateLimitMessages, time.Duration(config.C.RateLimitWindow)*time.Second)),
+		fsubMiddleware,
+		registrationMiddleware,
+	}
+}
+
+// recoverMiddleware menangkap panic supaya satu update tidak mematikan bot, lalu melaporkannya ke log group
+func recoverMiddleware(next HandlerFunc) HandlerFunc {
+	return func(uc *UpdateContext) (err error) {
+		defer func() {
+			if r := recover(); r != nil {
+				plugin := uc.Plugin
+				if plugin == "" {
+					plugin = "middleware"
+				}
+				reporter.Panic(plugin, uc.UpdateType(), uc.UserID, r, debug.Stack())
+				uc.Reply(constants.MsgError)
+			}
+		}()
+		return next(uc)
+	}
+}
+
+// chatFilterMiddleware hanya meneruskan pesan dari private chat (bukan log group / grup lain)
+func chatFilterMiddleware(next HandlerFunc) HandlerFunc {
+	return func(uc *UpdateContext) error {
+		if uc.Message == nil {
+			// Callback dari log group (tombol warn) tetap diteruskan
+			return next(uc)
+		}
+
+		// Ignore messages from log group to prevent loop
+		if uc.ChatID == config.C.LogGroupID {
+			log.Printf("🚫 Ignoring message from log group: %d", uc.ChatID)
+			return nil
+		}
+
+		// Ignore group messages (only handle private chats)
+		if !uc.Message.Chat.IsPrivate() {
+			log.Printf("🚫 Ignoring group message from chat: %d", uc.ChatID)
+			return nil
+		}
+
+		return next(uc)
+	}
+}
+
+// authMiddleware menolak update tanpa pengirim dan menandai owner
+func authMiddleware(next HandlerFunc) HandlerFunc {
+	return func(uc *UpdateContext) error {
+		if uc.UserID == 0 {
+			return nil
+		}
+		uc.IsOwner = isOwnerID(uc.UserID)
+		return next(uc)
+	}
+}
+
+// maintenanceMiddleware menolak user biasa saat bot dalam mode maintenance (/maintenance).
+// User yang masih dalam sesi chat tetap bisa mengirim pesan ke partner dan /stop,
+// semua aksi lain (termasuk pencarian baru) dibalas dengan pesan maintenance.
+func maintenanceMiddleware(next HandlerFunc) HandlerFunc {
+	return func(uc *UpdateContext) error {
+		enabled, message := settings.Maintenance()
+		if !enabled || uc.IsOwner {
+			return next(uc)
+		}
+
+		if uc.Message != nil && (uc.Command == "" || uc.Command == constants.CmdStop) {
+			if status, _ := databases.GetUserStatus(uc.Ctx, uc.UserID); status == constants.StatusChatting {
+				return next(uc)
+			}
+		}
+
+		uc.Reply(maintenanceText(message))
+		return nil
+	}
+}
+
+// maintenanceText membuat pesan maintenance untuk user (pesan dari admin jika ada)
+func maintenanceText(message string) string {
+	if message == "" {
+		return constants.MsgMaintenance
+	}
+	return fmt.Sprintf(constants.MsgMaintenanceCustom, tgbotapi.EscapeText(tgbotapi.ModeMarkdown, message))
+}
+
+// banMiddleware menolak semua interaksi dari user yang dibanned
+func banMiddleware(next HandlerFunc) HandlerFunc {
+	return func(uc *UpdateContext) error {
+		if uc.IsOwner {
+			return next(uc)
+		}
+
+		banned, _ := databases.IsUserBanned(uc.Ctx, uc.UserID)
+		if !banned {
+			return next(uc)
+		}
+
+		// Pesan biasa diabaikan diam-diam supaya tidak spam balasan
+		if uc.Command != "" || uc.Callback != nil {
+			uc.Reply(constants.MsgBanned)
+		}
+		return nil
+	}
+}
+
+// rateLimitMiddleware membatasi jumlah update per user dalam satu window
+func rateLimitMiddleware(limiter *rateLimiter) Middleware {
+	return func(next HandlerFunc) HandlerFunc {
+		return func(uc *UpdateContext) error {
+			if uc.IsOwner {
+				return next(uc)
+			}
+
+			allowed, warn := limiter.Allow(uc.UserID)
+			if allowed {
+				return next(uc)
+			}
+
+			// Callback selalu dijawab, pesan hanya diperingatkan sekali per window
+			if uc.Callback != nil || warn {
+				uc.Reply(constants.MsgRateLimited)
+			}
+			return nil
+		}
+	}
+}
+
+// fsubMiddleware mewajibkan join channel sebelum memakai bot (kecuali /start dan owner)
+func fsubMiddleware(next HandlerFunc) HandlerFunc {
+	return func(uc *UpdateContext) error {
+		// Callback (termasuk tombol verifikasi fsub) tidak dicek
+		if uc.IsOwner || uc.Message == nil || uc.Command == constants.CmdStart {
+			return next(uc)
+		}
+
+		allowed, channel := CheckFsub(uc.Ctx, uc.Bot, uc.UserID)
+		if !allowed {
+			SendFsubPrompt(uc.Bot, uc.ChatID, channel)
+			return nil
+		}
+		return next(uc)
+	}
+}
+
+// publicCommands adalah command yang boleh dipakai sebelum registrasi selesai
+var publicCommands = map[string]bool{
+	constants.CmdStart:  true,
+	constants.CmdHelp:   true,
+	constants.CmdCancel: true,
+}
+
+// registrationMiddleware mewajibkan registrasi untuk semua command selain publicCommands.
+// Pesan biasa tetap diteruskan supaya flow registrasi bisa menerima jawaban.
+func registrationMiddleware(next HandlerFunc) HandlerFunc {
+	return func(uc *UpdateContext) error {
+		if uc.IsOwner || uc.Command == "" || publicCommands[uc.Command] {
+			return next(uc)
+		}
+
+		isRegistered, _ := databases.IsUserRegistered(uc.Ctx, uc.UserID)
+		if !isRegistered {
+			uc.Reply(constants.MsgNotRegistered)
+			return nil
+		}
+		return next(uc)
+	}
+}
+
+// rateLimiter menghitung update per user dengan fixed window di memory
+type rateLimiter struct {
+	mu        sync.Mutex
+	limit     int
+	window    time.Duration
+	buckets   map[int64]*rateBucket
+	lastPrune time.Time
+}
+
+type rateBucket struct {
+	start  time.Time
+	count  int
+	warned bool
+}
+
+// newRateLimiter membuat rate limiter; limit <= 0 berarti tidak dibatasi
+func newRateLimiter(limit int, window time.Duration) *rateLimiter {
+	return &rateLimiter{
+		limit:     limit,
+		window:    window,
+		buckets:   make(map[int64]*rateBucket),
+		lastPrune: time.Now(),
+	}
+}
+
+// Allow mencatat satu update dan mengembalikan apakah diizinkan.
+// warn bernilai true hanya pada penolakan pertama di window tersebut.
+func (l *rateLimiter) Allow(userID int64) (allowed, warn bool) {
+	if l.limit <= 0 || l.window <= 0 {
+		return true, false
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	l.prune(now)
+
+	bucket, ok := l.buckets[userID]
+	if !ok || now.Sub(bucket.start) >= l.window {
+		bucket = &rateBucket{start: now}
+		l.buckets[userID] = bucket
+	}
+
+	bucket.count++
+	if bucket.count <= l.limit {
+		return true, false
+	}
+
+	warn = !bucket.warned
+	bucket.warned = true
+	return false, warn
+}
+
+// prune membuang bucket yang window-nya sudah lewat (maksimal sekali per window)
+func (l *rateLimiter) prune(now time.Time) {
+	if now.Sub(l.lastPrune) < l.window {
+		return
+	}
+	for userID, bucket := range l.buckets {
+		if now.Sub(bucket.start) >= l.window {
+			delete(l.buckets, userID)
+		}
+	}
+	l.lastPrune = now
+}
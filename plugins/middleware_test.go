@@ -0,0 +1,65 @@
+package plugins
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRateLimiterAllow(t *testing.T) {
+	type call struct {
+		allowed, warn bool
+	}
+	tests := []struct {
+		name  string
+		limit int
+		calls []call
+	}{
+		{"within limit", 3, []call{{true, false}, {true, false}, {true, false}}},
+		{"warn once after limit", 2, []call{{true, false}, {true, false}, {false, true}, {false, false}, {false, false}}},
+		{"unlimited", 0, []call{{true, false}, {true, false}, {true, false}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			limiter := newRateLimiter(tt.limit, time.Minute)
+			for i, want := range tt.calls {
+				allowed, warn := limiter.Allow(1)
+				if allowed != want.allowed || warn != want.warn {
+					t.Errorf("call %d: Allow() = (%v, %v), want (%v, %v)", i+1, allowed, warn, want.allowed, want.warn)
+				}
+			}
+		})
+	}
+}
+
+func TestRateLimiterPerUser(t *testing.T) {
+	limiter := newRateLimiter(1, time.Minute)
+
+	if allowed, _ := limiter.Allow(1); !allowed {
+		t.Fatal("first update of user 1 rejected")
+	}
+	if allowed, _ := limiter.Allow(2); !allowed {
+		t.Error("user 2 limited by user 1's bucket")
+	}
+	if allowed, _ := limiter.Allow(1); allowed {
+		t.Error("second update of user 1 allowed")
+	}
+}
+
+func TestRateLimiterWindowReset(t *testing.T) {
+	window := 20 * time.Millisecond
+	limiter := newRateLimiter(1, window)
+
+	limiter.Allow(1)
+	if allowed, _ := limiter.Allow(1); allowed {
+		t.Fatal("second update in the same window allowed")
+	}
+
+	time.Sleep(window + 5*time.Millisecond)
+	if allowed, warn := limiter.Allow(1); !allowed || warn {
+		t.Errorf("Allow() after window = (%v, %v), want (true, false)", allowed, warn)
+	}
+	if len(limiter.buckets) != 1 {
+		t.Errorf("buckets = %d, want 1 after prune", len(limiter.buckets))
+	}
+}
@@ -0,0 +1,55 @@
+package plugins
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"tg-anon-go/constants"
+	"tg-anon-go/databases"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// handleMyStats menampilkan statistik sesi chat user
+func (p *StartPlugin) handleMyStats(ctx context.Context, bot *tgbotapi.BotAPI, chatID, userID int64) error {
+	stats, err := databases.GetUserSessionStats(ctx, userID)
+	if errors.Is(err, databases.ErrNotSupported) {
+		return p.sendMessage(bot, chatID, constants.MsgNotSupported)
+	}
+	if err != nil {
+		log.Printf("Error getting session stats for user %d: %v", userID, err)
+		return p.sendMessage(bot, chatID, constants.MsgError)
+	}
+
+	if stats.TotalSessions == 0 {
+		return p.sendMessage(bot, chatID, constants.MsgMyStatsEmpty)
+	}
+
+	msg := fmt.Sprintf(constants.MsgMyStats,
+		stats.TotalSessions,
+		formatSeconds(stats.AvgDuration),
+		formatSeconds(stats.LongestDuration),
+		formatSeconds(stats.AvgWait),
+		stats.MessagesSent,
+		stats.MessagesReceived,
+		stats.EndedByMe,
+		stats.EndedByPartner,
+	)
+	return p.sendMessage(bot, chatID, msg)
+}
+
+// formatSeconds format durasi dalam detik menjadi teks singkat (contoh: 1j 5m, 3m 20d)
+func formatSeconds(seconds float64) string {
+	d := time.Duration(seconds) * time.Second
+	switch {
+	case d >= time.Hour:
+		return fmt.Sprintf("%dj %dm", int(d.Hours()), int(d.Minutes())%60)
+	case d >= time.Minute:
+		return fmt.Sprintf("%dm %dd", int(d.Minutes()), int(d.Seconds())%60)
+	default:
+		return fmt.Sprintf("%dd", int(d.Seconds()))
+	}
+}
@@ -8,24 +8,24 @@ import (
 type Plugin interface {
 	// Name mengembalikan nama plugin
 	Name() string
-	
+
 	// Commands mengembalikan daftar command yang ditangani plugin
 	Commands() []string
-	
+
 	// HandleCommand menangani command
 	HandleCommand(bot *tgbotapi.BotAPI, message *tgbotapi.Message, command string) error
-	
+
 	// HandleMessage menangani pesan biasa (non-command)
 	HandleMessage(bot *tgbotapi.BotAPI, message *tgbotapi.Message) error
-	
+
 	// CanHandleMessage mengecek apakah plugin bisa handle message ini
 	CanHandleMessage(message *tgbotapi.Message) bool
 
-	// HandleCallbackQuery menangani callback dari inline keyboard
-	HandleCallbackQuery(bot *tgbotapi.BotAPI, callback *tgbotapi.CallbackQuery) error
+	// RegisterCallbacks mendaftarkan namespace callback inline keyboard milik plugin
+	RegisterCallbacks(router *CallbackRouter)
 
-	// CanHandleCallback mengecek apakah plugin bisa handle callback ini
-	CanHandleCallback(data string) bool
+	// Middlewares mengembalikan middleware tambahan milik plugin (dijalankan setelah middleware bawaan)
+	Middlewares() []Middleware
 }
 
 // BasePlugin struct dasar untuk plugin
@@ -41,12 +41,10 @@ func (b *BasePlugin) CanHandleMessage(message *tgbotapi.Message) bool {
 	return false
 }
 
-// HandleCallbackQuery implementasi default - tidak handle callback
-func (b *BasePlugin) HandleCallbackQuery(bot *tgbotapi.BotAPI, callback *tgbotapi.CallbackQuery) error {
-	return nil
-}
+// RegisterCallbacks implementasi default - tidak punya callback
+func (b *BasePlugin) RegisterCallbacks(router *CallbackRouter) {}
 
-// CanHandleCallback implementasi default - tidak handle callback
-func (b *BasePlugin) CanHandleCallback(data string) bool {
-	return false
+// Middlewares implementasi default - tidak menambah middleware
+func (b *BasePlugin) Middlewares() []Middleware {
+	return nil
 }
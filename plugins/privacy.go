@@ -0,0 +1,113 @@
+package plugins
+
+import (
+	"context"
+	"fmt"
+
+	"tg-anon-go/constants"
+	"tg-anon-go/databases"
+	"tg-anon-go/matcher"
+	"tg-anon-go/sender"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// privacyFieldLabels adalah label tombol untuk setiap field kartu profil
+var privacyFieldLabels = map[string]string{
+	constants.CardFieldAge:        "📅 Umur",
+	constants.CardFieldGender:     "👥 Gender",
+	constants.CardFieldCity:       "📍 Kota",
+	constants.CardFieldInterests:  "🎯 Minat",
+	constants.CardFieldName:       "🙋 Nama",
+	constants.CardFieldTelegramID: "🆔 ID Telegram",
+}
+
+// handlePrivacy menampilkan menu pengaturan kartu profil yang dilihat partner
+func (p *StartPlugin) handlePrivacy(ctx context.Context, bot *tgbotapi.BotAPI, chatID, userID int64) error {
+	fields := databases.GetCardFields(ctx, userID)
+	msg := tgbotapi.NewMessage(chatID, fmt.Sprintf(constants.MsgPrivacyMenu, matcher.BuildPartnerCard(ctx, userID)))
+	msg.ParseMode = "Markdown"
+	msg.ReplyMarkup = privacyKeyboard(fields)
+
+	_, err := sender.Send(msg, sender.PriorityNormal)
+	return err
+}
+
+// privacyKeyboard membuat tombol toggle untuk setiap field kartu profil
+func privacyKeyboard(fields map[string]bool) tgbotapi.InlineKeyboardMarkup {
+	var rows [][]tgbotapi.InlineKeyboardButton
+	var row []tgbotapi.InlineKeyboardButton
+
+	for _, field := range constants.CardFields {
+		mark := "❌"
+		if fields[field] {
+			mark = "✅"
+		}
+		label := fmt.Sprintf("%s %s", mark, privacyFieldLabels[field])
+		row = append(row, tgbotapi.NewInlineKeyboardButtonData(label, CallbackData(constants.CallbackPrivacy, "toggle", field)))
+		if len(row) == 2 {
+			rows = append(rows, row)
+			row = nil
+		}
+	}
+	if len(row) > 0 {
+		rows = append(rows, row)
+	}
+
+	rows = append(rows,
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("🕶️ Sembunyikan Semua", CallbackData(constants.CallbackPrivacy, "hide_all")),
+		),
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("✔️ Selesai", CallbackData(constants.CallbackPrivacy, "done")),
+		),
+	)
+	return tgbotapi.NewInlineKeyboardMarkup(rows...)
+}
+
+// handlePrivacyCallback menangani tombol di menu privasi (privacy:{toggle|hide_all|done}[:{field}])
+func (p *StartPlugin) handlePrivacyCallback(ctx context.Context, bot *tgbotapi.BotAPI, callback *Callback) error {
+	if callback.Message == nil {
+		return nil
+	}
+	chatID := callback.Message.Chat.ID
+	userID := callback.From.ID
+
+	var fields map[string]bool
+	switch callback.Action {
+	case "done":
+		callback.Answer(constants.MsgPrivacySaved)
+		callback.DeleteMessage()
+		return nil
+
+	case "hide_all":
+		fields = map[string]bool{}
+		if err := databases.SetCardFields(ctx, userID, fields); err != nil {
+			return err
+		}
+		callback.Answer(constants.MsgPrivacyHidden)
+
+	case "toggle":
+		field := callback.Arg(0)
+		if _, ok := privacyFieldLabels[field]; !ok {
+			return nil
+		}
+
+		var err error
+		fields, err = databases.ToggleCardField(ctx, userID, field)
+		if err != nil {
+			return err
+		}
+		callback.Answer("")
+
+	default:
+		return nil
+	}
+
+	// Refresh menu dengan preview kartu terbaru
+	text := fmt.Sprintf(constants.MsgPrivacyMenu, matcher.BuildPartnerCard(ctx, userID))
+	edit := tgbotapi.NewEditMessageTextAndMarkup(chatID, callback.Message.MessageID, text, privacyKeyboard(fields))
+	edit.ParseMode = "Markdown"
+	_, err := sender.Send(edit, sender.PriorityNormal)
+	return err
+}
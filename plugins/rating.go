@@ -0,0 +1,92 @@
+package plugins
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"tg-anon-go/config"
+	"tg-anon-go/constants"
+	"tg-anon-go/databases"
+	"tg-anon-go/sender"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// SendRatingPrompt mengirim tombol 👍/👎 untuk menilai partner di sesi yang baru berakhir
+func SendRatingPrompt(userID, sessionID int64) {
+	if sessionID == 0 {
+		return
+	}
+
+	msg := tgbotapi.NewMessage(userID, constants.MsgRatePrompt)
+	msg.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("👍", CallbackData(constants.CallbackRate, "up", sessionID)),
+			tgbotapi.NewInlineKeyboardButtonData("👎", CallbackData(constants.CallbackRate, "down", sessionID)),
+		),
+	)
+	sender.SendAsync(msg, sender.PriorityNormal)
+}
+
+// handleRateCallback menyimpan rating dari tombol 👍/👎 (rate:{up|down}:{sessionID})
+func (p *ChatPlugin) handleRateCallback(ctx context.Context, bot *tgbotapi.BotAPI, callback *Callback) error {
+	score := databases.RatingUp
+	if callback.Action == "down" {
+		score = databases.RatingDown
+	}
+
+	sessionID, err := callback.Int64(0)
+	if err != nil {
+		callback.Answer(constants.MsgRateInvalid)
+		return err
+	}
+
+	raterID := callback.From.ID
+	rateeID, saved, err := databases.SaveRating(ctx, sessionID, raterID, score)
+	if err != nil {
+		callback.Answer(constants.MsgRateInvalid)
+		return err
+	}
+
+	// Hapus tombol rating dari pesan prompt
+	if callback.Message != nil {
+		edit := tgbotapi.NewEditMessageText(callback.Message.Chat.ID, callback.Message.MessageID, constants.MsgRateThanks)
+		sender.Send(edit, sender.PriorityNormal)
+	}
+
+	if !saved {
+		callback.Answer(constants.MsgRateAlreadyRated)
+		return nil
+	}
+	callback.Answer(constants.MsgRateThanks)
+
+	p.updateReputation(ctx, rateeID)
+	return nil
+}
+
+// updateReputation menghitung ulang reputasi dan mem-flag user reputasi rendah ke admin
+func (p *ChatPlugin) updateReputation(ctx context.Context, userID int64) {
+	rep, err := databases.RefreshReputation(ctx, userID)
+	if err != nil {
+		log.Printf("Error refreshing reputation for user %d: %v", userID, err)
+		return
+	}
+
+	flagged := databases.IsLowReputationFlagged(ctx, userID)
+	switch {
+	case rep.IsLow() && !flagged:
+		databases.SetLowReputationFlag(ctx, userID, true)
+		log.Printf("⚠️ User %d flagged for low reputation (%.0f/100)", userID, rep.Score)
+
+		if config.C.LogGroupID != 0 {
+			text := fmt.Sprintf(constants.MsgLowReputationLog, userID, userID, rep.Score, rep.Up, rep.Down)
+			msg := tgbotapi.NewMessage(config.C.LogGroupID, text)
+			msg.ParseMode = "Markdown"
+			sender.SendAsync(msg, sender.PriorityNormal)
+		}
+	case !rep.IsLow() && flagged:
+		databases.SetLowReputationFlag(ctx, userID, false)
+		log.Printf("✅ User %d low reputation flag cleared (%.0f/100)", userID, rep.Score)
+	}
+}
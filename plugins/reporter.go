@@ -0,0 +1,143 @@
+package plugins
+
+import (
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"tg-anon-go/config"
+	"tg-anon-go/constants"
+	"tg-anon-go/sender"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// Batas laporan ke log group supaya grup tidak banjir saat ada insiden
+const (
+	maxReportsPerMinute = 10
+	maxReportStackBytes = 3000 // Pesan Telegram maksimal 4096 karakter
+)
+
+// errorReporter melaporkan panic dan error berulang ke log group dengan rate limit
+type errorReporter struct {
+	mu          sync.Mutex
+	errors      map[string]*errorWindow
+	sentAt      []time.Time
+	suppressed  int
+	threshold   int
+	window      time.Duration
+	lastCleanup time.Time
+}
+
+type errorWindow struct {
+	start    time.Time
+	count    int
+	reported bool
+}
+
+// reporter adalah errorReporter yang dipakai manager, middleware dan callback router.
+// Dibuat ulang oleh NewManager setelah konfigurasi dimuat.
+var reporter = newConfiguredReporter()
+
+// newConfiguredReporter membuat errorReporter dari ERROR_REPORT_THRESHOLD dan ERROR_REPORT_WINDOW
+func newConfiguredReporter() *errorReporter {
+	return newErrorReporter(config.C.ErrorReportThreshold, time.Duration(config.C.ErrorReportWindow)*time.Minute)
+}
+
+func newErrorReporter(threshold int, window time.Duration) *errorReporter {
+	if threshold < 1 {
+		threshold = 1
+	}
+	return &errorReporter{
+		errors:      make(map[string]*errorWindow),
+		threshold:   threshold,
+		window:      window,
+		lastCleanup: time.Now(),
+	}
+}
+
+// Panic melaporkan panic yang berhasil di-recover (selalu dilaporkan, tetap kena rate limit global)
+func (r *errorReporter) Panic(plugin, updateType string, userID int64, recovered interface{}, stack []byte) {
+	log.Printf("🔥 Panic in %s (%s) from user %d: %v\n%s", plugin, updateType, userID, recovered, stack)
+
+	if len(stack) > maxReportStackBytes {
+		stack = append(stack[:maxReportStackBytes:maxReportStackBytes], []byte("\n...")...)
+	}
+	text := fmt.Sprintf(constants.MsgReportPanic, plugin, updateType, userID, recovered, stack)
+	r.send(text)
+}
+
+// Error mencatat error handler dan melaporkannya jika terjadi berulang kali dalam satu window
+func (r *errorReporter) Error(plugin, updateType string, userID int64, err error) {
+	log.Printf("Error in %s (%s) from user %d: %v", plugin, updateType, userID, err)
+
+	key := plugin + "|" + updateType
+	now := time.Now()
+
+	r.mu.Lock()
+	r.cleanup(now)
+	w, ok := r.errors[key]
+	if !ok || now.Sub(w.start) >= r.window {
+		w = &errorWindow{start: now}
+		r.errors[key] = w
+	}
+	w.count++
+	report := w.count >= r.threshold && !w.reported
+	if report {
+		w.reported = true
+	}
+	count := w.count
+	r.mu.Unlock()
+
+	if report {
+		text := fmt.Sprintf(constants.MsgReportError, plugin, updateType, userID, count, int(r.window.Minutes()), err)
+		r.send(text)
+	}
+}
+
+// send mengirim laporan ke log group dengan batas global per menit
+func (r *errorReporter) send(text string) {
+	if config.C.LogGroupID == 0 {
+		return
+	}
+
+	r.mu.Lock()
+	now := time.Now()
+	recent := r.sentAt[:0]
+	for _, t := range r.sentAt {
+		if now.Sub(t) < time.Minute {
+			recent = append(recent, t)
+		}
+	}
+	r.sentAt = recent
+
+	if len(r.sentAt) >= maxReportsPerMinute {
+		r.suppressed++
+		r.mu.Unlock()
+		return
+	}
+	r.sentAt = append(r.sentAt, now)
+	if r.suppressed > 0 {
+		text += fmt.Sprintf(constants.MsgReportSuppressed, r.suppressed)
+		r.suppressed = 0
+	}
+	r.mu.Unlock()
+
+	// Tanpa ParseMode: stack trace bisa berisi karakter Markdown
+	msg := tgbotapi.NewMessage(config.C.LogGroupID, text)
+	sender.SendAsync(msg, sender.PriorityNormal)
+}
+
+// cleanup membuang window error yang sudah lewat (dipanggil dengan lock)
+func (r *errorReporter) cleanup(now time.Time) {
+	if now.Sub(r.lastCleanup) < r.window {
+		return
+	}
+	for key, w := range r.errors {
+		if now.Sub(w.start) >= r.window {
+			delete(r.errors, key)
+		}
+	}
+	r.lastCleanup = now
+}
@@ -2,12 +2,15 @@ package plugins
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
 	"strconv"
 
+	"tg-anon-go/config"
 	"tg-anon-go/constants"
 	"tg-anon-go/databases"
+	"tg-anon-go/sender"
 
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
 )
@@ -17,9 +20,11 @@ type StartPlugin struct {
 	BasePlugin
 }
 
-// NewStartPlugin membuat instance StartPlugin baru
+// NewStartPlugin membuat instance StartPlugin baru dan mendaftarkan flow registrasi & edit profil
 func NewStartPlugin() *StartPlugin {
-	return &StartPlugin{}
+	p := &StartPlugin{}
+	p.registerFlows()
+	return p
 }
 
 // Name mengembalikan nama plugin
@@ -29,7 +34,7 @@ func (p *StartPlugin) Name() string {
 
 // Commands mengembalikan daftar command yang ditangani
 func (p *StartPlugin) Commands() []string {
-	return []string{constants.CmdStart, constants.CmdHelp, constants.CmdProfile, constants.CmdEditProfile}
+	return []string{constants.CmdStart, constants.CmdHelp, constants.CmdProfile, constants.CmdEditProfile, constants.CmdPrivacy, constants.CmdMyStats}
 }
 
 // HandleCommand menangani command /start dan /help
@@ -46,6 +51,10 @@ func (p *StartPlugin) HandleCommand(bot *tgbotapi.BotAPI, message *tgbotapi.Mess
 		return p.handleProfile(ctx, bot, chatID, userID)
 	case constants.CmdEditProfile:
 		return p.handleEditProfile(ctx, bot, chatID, userID)
+	case constants.CmdPrivacy:
+		return p.handlePrivacy(ctx, bot, chatID, userID)
+	case constants.CmdMyStats:
+		return p.handleMyStats(ctx, bot, chatID, userID)
 	}
 
 	return nil
@@ -63,16 +72,20 @@ func (p *StartPlugin) handleStart(ctx context.Context, bot *tgbotapi.BotAPI, cha
 		return p.sendMessage(bot, chatID, constants.MsgError)
 	}
 
+	// User yang /start lagi berarti sudah tidak memblokir bot
+	if inactive, _ := databases.IsUserInactive(ctx, userID); inactive {
+		databases.SetUserInactive(ctx, userID, false)
+	}
+
 	// Check if user is already registered
-	isRegistered, _ := databases.GetVarBool(ctx, userID, constants.VarIsRegistered)
+	isRegistered, _ := databases.IsUserRegistered(ctx, userID)
 	if isRegistered {
 		// User sudah terdaftar, tampilkan welcome dengan buttons
 		return p.sendWelcomeWithButtons(bot, chatID)
 	}
 
 	// Mulai proses registrasi
-	databases.SetVar(ctx, userID, constants.VarRegState, constants.RegStateAskName)
-	return p.sendMessage(bot, chatID, constants.MsgRegWelcome)
+	return StartFlow(ctx, constants.FlowRegistration, userID, chatID)
 }
 
 // sendWelcomeWithButtons mengirim pesan welcome dengan inline keyboard
@@ -83,28 +96,34 @@ func (p *StartPlugin) sendWelcomeWithButtons(bot *tgbotapi.BotAPI, chatID int64)
 	// Create inline keyboard
 	keyboard := tgbotapi.NewInlineKeyboardMarkup(
 		tgbotapi.NewInlineKeyboardRow(
-			tgbotapi.NewInlineKeyboardButtonURL("👤 Owner", constants.BotOwnerURL),
-			tgbotapi.NewInlineKeyboardButtonURL("📢 Channel", constants.BotChannelURL),
+			tgbotapi.NewInlineKeyboardButtonURL("👤 Owner", config.C.BotOwnerURL),
+			tgbotapi.NewInlineKeyboardButtonURL("📢 Channel", config.C.BotChannelURL),
 		),
 		tgbotapi.NewInlineKeyboardRow(
-			tgbotapi.NewInlineKeyboardButtonURL("💬 Support", constants.BotSupportURL),
-			tgbotapi.NewInlineKeyboardButtonData("❌ Close", "close_welcome"),
+			tgbotapi.NewInlineKeyboardButtonURL("💬 Support", config.C.BotSupportURL),
+			tgbotapi.NewInlineKeyboardButtonData("❌ Close", CallbackData(constants.CallbackProfile, "close")),
 		),
 	)
 	msg.ReplyMarkup = keyboard
 
-	_, err := bot.Send(msg)
+	_, err := sender.Send(msg, sender.PriorityNormal)
 	return err
 }
 
 // handleProfile menampilkan profil user
 func (p *StartPlugin) handleProfile(ctx context.Context, bot *tgbotapi.BotAPI, chatID, userID int64) error {
-	name, _ := databases.GetVar(ctx, userID, constants.VarName)
-	age, _ := databases.GetVar(ctx, userID, constants.VarAge)
-	gender, _ := databases.GetVar(ctx, userID, constants.VarGender)
-	location, _ := databases.GetVar(ctx, userID, constants.VarLocation)
-	totalChats, _ := databases.GetVarInt(ctx, userID, constants.VarTotalChats)
-	totalMessages, _ := databases.GetVarInt(ctx, userID, constants.VarTotalMessages)
+	profile, err := databases.GetUserProfile(ctx, userID)
+	if err != nil {
+		return err
+	}
+	stats, err := databases.GetUserStatsByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	name, age, gender := profile.Name, profile.AgeString(), profile.Gender
+	location, interests := profile.Location, profile.Interests
+	totalChats, totalMessages := stats.TotalChats, stats.TotalMessages
 
 	if name == "" {
 		name = "Belum diisi"
@@ -118,434 +137,324 @@ func (p *StartPlugin) handleProfile(ctx context.Context, bot *tgbotapi.BotAPI, c
 	if location == "" {
 		location = "Belum diisi"
 	}
-
-	msg := fmt.Sprintf(constants.MsgProfileInfo, name, age, gender, location, totalChats, totalMessages)
-	return p.sendMessage(bot, chatID, msg)
-}
-
-// HandleMessage menangani pesan saat registrasi
-func (p *StartPlugin) HandleMessage(bot *tgbotapi.BotAPI, message *tgbotapi.Message) error {
-	ctx := context.Background()
-	chatID := message.Chat.ID
-	userID := message.From.ID
-	text := message.Text
-
-	// Check edit state first
-	editState, _ := databases.GetVar(ctx, userID, constants.VarEditState)
-	if editState != "" && editState != constants.EditStateNone {
-		switch editState {
-		case constants.EditStateName:
-			return p.handleEditNameInput(ctx, bot, chatID, userID, text)
-		case constants.EditStateAge:
-			return p.handleEditAgeInput(ctx, bot, chatID, userID, text)
-		case constants.EditStateLocation:
-			if message.Location != nil {
-				return p.handleEditLocationInput(ctx, bot, chatID, userID, message.Location)
-			}
-			return p.sendMessage(bot, chatID, constants.MsgRegInvalidLocation)
-		}
+	if interests == "" {
+		interests = "Belum diisi"
 	}
 
-	// Get registration state
-	regState, _ := databases.GetVar(ctx, userID, constants.VarRegState)
-	switch regState {
-	case constants.RegStateAskName:
-		return p.handleNameInput(ctx, bot, chatID, userID, text)
-	case constants.RegStateAskAge:
-		return p.handleAgeInput(ctx, bot, chatID, userID, text)
-	case constants.RegStateAskGender:
-		// Gender will be handled by callback
-		return p.sendMessage(bot, chatID, constants.MsgRegInvalidGender)
-	case constants.RegStateAskLocation:
-		// Check if user sent location
-		if message.Location != nil {
-			return p.handleLocationShare(ctx, bot, chatID, userID, message.Location)
-		}
-		return p.sendMessage(bot, chatID, constants.MsgRegInvalidLocation)
-	}
+	// Nama, lokasi, dan minat adalah input bebas user; escape supaya tidak merusak Markdown
+	name = tgbotapi.EscapeText(tgbotapi.ModeMarkdown, name)
+	location = tgbotapi.EscapeText(tgbotapi.ModeMarkdown, location)
+	interests = tgbotapi.EscapeText(tgbotapi.ModeMarkdown, interests)
 
-	return nil
-}
-
-// handleNameInput menangani input nama
-func (p *StartPlugin) handleNameInput(ctx context.Context, bot *tgbotapi.BotAPI, chatID, userID int64, name string) error {
-	if name == "" || len(name) < 2 {
-		return p.sendMessage(bot, chatID, "⚠️ Nama terlalu pendek. Silakan masukkan nama yang valid.")
-	}
-
-	if len(name) > 50 {
-		return p.sendMessage(bot, chatID, "⚠️ Nama terlalu panjang. Maksimal 50 karakter.")
-	}
+	rep, _ := databases.GetReputation(ctx, userID)
 
-	// Save name
-	databases.SetVar(ctx, userID, constants.VarName, name)
-	databases.SetVar(ctx, userID, constants.VarRegState, constants.RegStateAskAge)
-
-	msg := fmt.Sprintf(constants.MsgRegAskAge, name)
+	msg := fmt.Sprintf(constants.MsgProfileInfo, name, age, gender, location, interests, totalChats, totalMessages, rep.Score, rep.Count())
 	return p.sendMessage(bot, chatID, msg)
 }
 
-// handleAgeInput menangani input umur
-func (p *StartPlugin) handleAgeInput(ctx context.Context, bot *tgbotapi.BotAPI, chatID, userID int64, ageStr string) error {
-	age, err := strconv.Atoi(ageStr)
-	if err != nil || age < 13 || age > 100 {
-		return p.sendMessage(bot, chatID, constants.MsgRegInvalidAge)
-	}
-
-	// Save age
-	databases.SetVar(ctx, userID, constants.VarAge, ageStr)
-	databases.SetVar(ctx, userID, constants.VarRegState, constants.RegStateAskGender)
-
-	msg := fmt.Sprintf(constants.MsgRegAskGender, ageStr)
-	return p.sendMessageWithGenderButtons(bot, chatID, msg)
-}
-
-// sendMessageWithGenderButtons mengirim pesan dengan tombol pilihan gender
-func (p *StartPlugin) sendMessageWithGenderButtons(bot *tgbotapi.BotAPI, chatID int64, text string) error {
+// sendMessage mengirim pesan dengan Markdown
+func (p *StartPlugin) sendMessage(bot *tgbotapi.BotAPI, chatID int64, text string) error {
 	msg := tgbotapi.NewMessage(chatID, text)
 	msg.ParseMode = "Markdown"
-
-	// Create inline keyboard with gender options
-	keyboard := tgbotapi.NewInlineKeyboardMarkup(
-		tgbotapi.NewInlineKeyboardRow(
-			tgbotapi.NewInlineKeyboardButtonData("👨 "+constants.GenderMale, "gender_male"),
-			tgbotapi.NewInlineKeyboardButtonData("👩 "+constants.GenderFemale, "gender_female"),
-		),
-		tgbotapi.NewInlineKeyboardRow(
-			tgbotapi.NewInlineKeyboardButtonData("🧑 "+constants.GenderOther, "gender_other"),
-		),
-	)
-	msg.ReplyMarkup = keyboard
-
-	_, err := bot.Send(msg)
+	_, err := sender.Send(msg, sender.PriorityNormal)
 	return err
 }
 
-// handleGenderSelection menangani pilihan gender
-func (p *StartPlugin) handleGenderSelection(ctx context.Context, bot *tgbotapi.BotAPI, chatID, userID int64, gender string) error {
-	// Save gender
-	databases.SetVar(ctx, userID, constants.VarGender, gender)
-	databases.SetVar(ctx, userID, constants.VarRegState, constants.RegStateAskLocation)
-
-	return p.sendMessageWithLocationButton(bot, chatID, constants.MsgRegAskLocation)
+// editProfileFlows memetakan field di menu edit profil ke flow-nya
+var editProfileFlows = map[string]string{
+	"name":      constants.FlowEditName,
+	"age":       constants.FlowEditAge,
+	"gender":    constants.FlowEditGender,
+	"location":  constants.FlowEditLocation,
+	"interests": constants.FlowEditInterests,
 }
 
-// handleLocationShare menangani share lokasi
-func (p *StartPlugin) handleLocationShare(ctx context.Context, bot *tgbotapi.BotAPI, chatID, userID int64, location *tgbotapi.Location) error {
-	// Save coordinates
-	databases.SetVar(ctx, userID, constants.VarLatitude, location.Latitude)
-	databases.SetVar(ctx, userID, constants.VarLongitude, location.Longitude)
-
-	// Get city name from coordinates using reverse geocoding
-	cityName := databases.GetCityNameFromCoordinates(location.Latitude, location.Longitude)
-	locationName := fmt.Sprintf("📍 %s", cityName)
-	databases.SetVar(ctx, userID, constants.VarLocation, locationName)
-
-	// Complete registration
-	databases.SetVar(ctx, userID, constants.VarRegState, constants.RegStateDone)
-	databases.SetVar(ctx, userID, constants.VarIsRegistered, true)
-
-	// Get saved data for confirmation
-	name, _ := databases.GetVar(ctx, userID, constants.VarName)
-	age, _ := databases.GetVar(ctx, userID, constants.VarAge)
-	gender, _ := databases.GetVar(ctx, userID, constants.VarGender)
-
-	msg := fmt.Sprintf(constants.MsgRegComplete, name, age, gender, locationName)
-	return p.sendMessageRemoveKeyboard(bot, chatID, msg)
+// RegisterCallbacks mendaftarkan callback menu profil dan privasi
+func (p *StartPlugin) RegisterCallbacks(router *CallbackRouter) {
+	router.Handle(constants.CallbackProfile, p.handleProfileCallback)
+	router.Handle(constants.CallbackPrivacy, p.handlePrivacyCallback)
 }
 
-// sendMessageWithLocationButton mengirim pesan dengan tombol request location
-func (p *StartPlugin) sendMessageWithLocationButton(bot *tgbotapi.BotAPI, chatID int64, text string) error {
-	msg := tgbotapi.NewMessage(chatID, text)
-	msg.ParseMode = "Markdown"
-
-	// Create keyboard with location request button
-	keyboard := tgbotapi.NewReplyKeyboard(
-		tgbotapi.NewKeyboardButtonRow(
-			tgbotapi.NewKeyboardButtonLocation("📍 Bagikan Lokasi"),
-		),
-	)
-	keyboard.OneTimeKeyboard = true
-	keyboard.ResizeKeyboard = true
-	msg.ReplyMarkup = keyboard
-
-	_, err := bot.Send(msg)
-	return err
-}
-
-// sendMessageRemoveKeyboard mengirim pesan dan menghapus keyboard
-func (p *StartPlugin) sendMessageRemoveKeyboard(bot *tgbotapi.BotAPI, chatID int64, text string) error {
-	msg := tgbotapi.NewMessage(chatID, text)
-	msg.ParseMode = "Markdown"
-	msg.ReplyMarkup = tgbotapi.NewRemoveKeyboard(true)
-	_, err := bot.Send(msg)
-	return err
-}
-
-// CanHandleMessage mengecek apakah plugin bisa handle message
-func (p *StartPlugin) CanHandleMessage(message *tgbotapi.Message) bool {
-	if message.IsCommand() {
-		return false
-	}
-
-	ctx := context.Background()
-	userID := message.From.ID
-
-	// Check edit state
-	editState, _ := databases.GetVar(ctx, userID, constants.VarEditState)
-	if editState != "" && editState != constants.EditStateNone {
-		return true
+// handleProfileCallback menangani tombol welcome dan menu edit profil (profile:{edit|cancel|close}[:{field}])
+func (p *StartPlugin) handleProfileCallback(ctx context.Context, bot *tgbotapi.BotAPI, callback *Callback) error {
+	if callback.Message == nil {
+		return nil
 	}
-
-	// Check registration state
-	regState, _ := databases.GetVar(ctx, userID, constants.VarRegState)
-
-	// Handle message jika user dalam proses registrasi
-	return regState == constants.RegStateAskName ||
-		regState == constants.RegStateAskAge ||
-		regState == constants.RegStateAskGender ||
-		regState == constants.RegStateAskLocation
-}
-
-// sendMessage mengirim pesan dengan Markdown
-func (p *StartPlugin) sendMessage(bot *tgbotapi.BotAPI, chatID int64, text string) error {
-	msg := tgbotapi.NewMessage(chatID, text)
-	msg.ParseMode = "Markdown"
-	_, err := bot.Send(msg)
-	return err
-}
-
-// HandleCallbackQuery menangani callback dari inline keyboard
-func (p *StartPlugin) HandleCallbackQuery(bot *tgbotapi.BotAPI, callback *tgbotapi.CallbackQuery) error {
-	ctx := context.Background()
 	chatID := callback.Message.Chat.ID
 	userID := callback.From.ID
 
-	// Handle edit profile callbacks
-	if callback.Data == "edit_name" || callback.Data == "edit_age" ||
-		callback.Data == "edit_gender" || callback.Data == "edit_location" ||
-		callback.Data == "edit_cancel" || callback.Data == "edit_gender_male" ||
-		callback.Data == "edit_gender_female" || callback.Data == "edit_gender_other" {
-		return p.handleEditCallback(ctx, bot, callback)
-	}
-
-	// Handle close welcome button
-	if callback.Data == "close_welcome" {
-		// Delete the message
-		deleteMsg := tgbotapi.NewDeleteMessage(chatID, callback.Message.MessageID)
-		bot.Send(deleteMsg)
+	// Delete the welcome / edit menu message
+	callback.Answer("")
+	callback.DeleteMessage()
 
-		// Answer callback to remove loading state
-		callbackResponse := tgbotapi.NewCallback(callback.ID, "")
-		bot.Send(callbackResponse)
-		return nil
-	}
-
-	// Handle gender selection
-	var gender string
-	switch callback.Data {
-	case "gender_male":
-		gender = constants.GenderMale
-	case "gender_female":
-		gender = constants.GenderFemale
-	case "gender_other":
-		gender = constants.GenderOther
-	default:
-		return nil
+	switch callback.Action {
+	case "cancel":
+		return p.sendMessage(bot, chatID, constants.MsgEditCancelled)
+	case "edit":
+		if flowName, ok := editProfileFlows[callback.Arg(0)]; ok {
+			return StartFlow(ctx, flowName, userID, chatID)
+		}
 	}
 
-	// Answer callback
-	callbackResponse := tgbotapi.NewCallback(callback.ID, "✅ Gender dipilih!")
-	bot.Send(callbackResponse)
-
-	// Delete the gender selection message
-	deleteMsg := tgbotapi.NewDeleteMessage(chatID, callback.Message.MessageID)
-	bot.Send(deleteMsg)
-
-	// Continue to location
-	return p.handleGenderSelection(ctx, bot, chatID, userID, gender)
-}
-
-// CanHandleCallback mengecek apakah plugin bisa handle callback
-func (p *StartPlugin) CanHandleCallback(data string) bool {
-	return data == "close_welcome" ||
-		data == "gender_male" ||
-		data == "gender_female" ||
-		data == "gender_other" ||
-		data == "edit_name" ||
-		data == "edit_age" ||
-		data == "edit_gender" ||
-		data == "edit_location" ||
-		data == "edit_cancel" ||
-		data == "edit_gender_male" ||
-		data == "edit_gender_female" ||
-		data == "edit_gender_other"
+	return nil
 }
 
 // handleEditProfile menampilkan menu edit profil
 func (p *StartPlugin) handleEditProfile(ctx context.Context, bot *tgbotapi.BotAPI, chatID, userID int64) error {
-	// Check if user is registered
-	isRegistered, _ := databases.GetVarBool(ctx, userID, constants.VarIsRegistered)
-	if !isRegistered {
-		return p.sendMessage(bot, chatID, constants.MsgNotRegistered)
-	}
-
 	msg := tgbotapi.NewMessage(chatID, constants.MsgEditProfile)
 	msg.ParseMode = "Markdown"
 
 	keyboard := tgbotapi.NewInlineKeyboardMarkup(
 		tgbotapi.NewInlineKeyboardRow(
-			tgbotapi.NewInlineKeyboardButtonData("👤 Edit Nama", "edit_name"),
-			tgbotapi.NewInlineKeyboardButtonData("📅 Edit Umur", "edit_age"),
+			tgbotapi.NewInlineKeyboardButtonData("👤 Edit Nama", CallbackData(constants.CallbackProfile, "edit", "name")),
+			tgbotapi.NewInlineKeyboardButtonData("📅 Edit Umur", CallbackData(constants.CallbackProfile, "edit", "age")),
 		),
 		tgbotapi.NewInlineKeyboardRow(
-			tgbotapi.NewInlineKeyboardButtonData("👥 Edit Gender", "edit_gender"),
-			tgbotapi.NewInlineKeyboardButtonData("📍 Edit Lokasi", "edit_location"),
+			tgbotapi.NewInlineKeyboardButtonData("👥 Edit Gender", CallbackData(constants.CallbackProfile, "edit", "gender")),
+			tgbotapi.NewInlineKeyboardButtonData("📍 Edit Lokasi", CallbackData(constants.CallbackProfile, "edit", "location")),
 		),
 		tgbotapi.NewInlineKeyboardRow(
-			tgbotapi.NewInlineKeyboardButtonData("❌ Batal", "edit_cancel"),
+			tgbotapi.NewInlineKeyboardButtonData("🎯 Edit Minat", CallbackData(constants.CallbackProfile, "edit", "interests")),
+			tgbotapi.NewInlineKeyboardButtonData("❌ Batal", CallbackData(constants.CallbackProfile, "cancel")),
 		),
 	)
 	msg.ReplyMarkup = keyboard
 
-	_, err := bot.Send(msg)
+	_, err := sender.Send(msg, sender.PriorityNormal)
 	return err
 }
 
-// handleEditNameInput menangani input nama baru
-func (p *StartPlugin) handleEditNameInput(ctx context.Context, bot *tgbotapi.BotAPI, chatID, userID int64, name string) error {
-	if name == "" || len(name) < 2 {
-		return p.sendMessage(bot, chatID, "⚠️ Nama terlalu pendek. Silakan masukkan nama yang valid.")
-	}
+// ============================================================
+// FLOW REGISTRASI & EDIT PROFIL
+// ============================================================
 
-	if len(name) > 50 {
-		return p.sendMessage(bot, chatID, "⚠️ Nama terlalu panjang. Maksimal 50 karakter.")
-	}
+// genderOptions adalah tombol pilihan gender untuk flow
+var genderOptions = [][]FlowOption{
+	{{Label: "👨 " + constants.GenderMale, Value: "male"}, {Label: "👩 " + constants.GenderFemale, Value: "female"}},
+	{{Label: "🧑 " + constants.GenderOther, Value: "other"}},
+}
 
-	// Update name
-	databases.SetVar(ctx, userID, constants.VarName, name)
-	databases.SetVar(ctx, userID, constants.VarEditState, constants.EditStateNone)
+// genderValues memetakan value tombol ke gender yang disimpan
+var genderValues = map[string]string{
+	"male":   constants.GenderMale,
+	"female": constants.GenderFemale,
+	"other":  constants.GenderOther,
+}
 
-	return p.sendMessage(bot, chatID, constants.MsgProfileUpdated)
+// registerFlows mendaftarkan flow registrasi dan edit profil
+func (p *StartPlugin) registerFlows() {
+	RegisterFlow(&Flow{
+		Name: constants.FlowRegistration,
+		Steps: []*FlowStep{
+			p.nameStep(func(ctx context.Context, fc *FlowContext) string {
+				return constants.MsgRegWelcome
+			}),
+			p.ageStep(func(ctx context.Context, fc *FlowContext) string {
+				profile, _ := databases.GetUserProfile(ctx, fc.UserID)
+				return fmt.Sprintf(constants.MsgRegAskAge, tgbotapi.EscapeText(tgbotapi.ModeMarkdown, profile.Name))
+			}),
+			p.genderStep(func(ctx context.Context, fc *FlowContext) string {
+				profile, _ := databases.GetUserProfile(ctx, fc.UserID)
+				return fmt.Sprintf(constants.MsgRegAskGender, profile.AgeString())
+			}),
+			p.locationStep(func(ctx context.Context, fc *FlowContext) string {
+				return constants.MsgRegAskLocation
+			}),
+		},
+		OnComplete:    p.completeRegistration,
+		CancelMessage: constants.MsgRegCancelled,
+	})
+
+	RegisterFlow(&Flow{
+		Name: constants.FlowEditName,
+		Steps: []*FlowStep{p.nameStep(func(ctx context.Context, fc *FlowContext) string {
+			return fmt.Sprintf(constants.MsgEditName, currentProfile(ctx, fc.UserID, func(p *databases.UserProfile) string { return p.Name }))
+		})},
+		OnComplete:    p.completeEdit,
+		CancelMessage: constants.MsgEditCancelled,
+	})
+
+	RegisterFlow(&Flow{
+		Name: constants.FlowEditAge,
+		Steps: []*FlowStep{p.ageStep(func(ctx context.Context, fc *FlowContext) string {
+			return fmt.Sprintf(constants.MsgEditAge, currentProfile(ctx, fc.UserID, func(p *databases.UserProfile) string { return p.AgeString() }))
+		})},
+		OnComplete:    p.completeEdit,
+		CancelMessage: constants.MsgEditCancelled,
+	})
+
+	RegisterFlow(&Flow{
+		Name: constants.FlowEditGender,
+		Steps: []*FlowStep{p.genderStep(func(ctx context.Context, fc *FlowContext) string {
+			return fmt.Sprintf(constants.MsgEditGender, currentProfile(ctx, fc.UserID, func(p *databases.UserProfile) string { return p.Gender }))
+		})},
+		OnComplete:    p.completeEdit,
+		CancelMessage: constants.MsgEditCancelled,
+	})
+
+	RegisterFlow(&Flow{
+		Name: constants.FlowEditLocation,
+		Steps: []*FlowStep{p.locationStep(func(ctx context.Context, fc *FlowContext) string {
+			return fmt.Sprintf(constants.MsgEditLocation, currentProfile(ctx, fc.UserID, func(p *databases.UserProfile) string { return p.Location }))
+		})},
+		OnComplete:    p.completeEdit,
+		CancelMessage: constants.MsgEditCancelled,
+	})
+
+	RegisterFlow(&Flow{
+		Name:          constants.FlowEditInterests,
+		Steps:         []*FlowStep{p.interestsStep()},
+		OnComplete:    p.completeEdit,
+		CancelMessage: constants.MsgEditCancelled,
+	})
 }
 
-// handleEditAgeInput menangani input umur baru
-func (p *StartPlugin) handleEditAgeInput(ctx context.Context, bot *tgbotapi.BotAPI, chatID, userID int64, ageStr string) error {
-	age, err := strconv.Atoi(ageStr)
-	if err != nil || age < 13 || age > 100 {
-		return p.sendMessage(bot, chatID, constants.MsgRegInvalidAge)
+// currentProfile mengambil nilai field profil (sudah di-escape untuk Markdown) atau "Belum diisi"
+func currentProfile(ctx context.Context, userID int64, field func(p *databases.UserProfile) string) string {
+	profile, _ := databases.GetUserProfile(ctx, userID)
+	value := field(profile)
+	if value == "" {
+		return "Belum diisi"
 	}
-
-	// Update age
-	databases.SetVar(ctx, userID, constants.VarAge, ageStr)
-	databases.SetVar(ctx, userID, constants.VarEditState, constants.EditStateNone)
-
-	return p.sendMessage(bot, chatID, constants.MsgProfileUpdated)
+	return tgbotapi.EscapeText(tgbotapi.ModeMarkdown, value)
 }
 
-// handleEditLocationInput menangani input lokasi baru
-func (p *StartPlugin) handleEditLocationInput(ctx context.Context, bot *tgbotapi.BotAPI, chatID, userID int64, location *tgbotapi.Location) error {
-	// Save new coordinates
-	databases.SetVar(ctx, userID, constants.VarLatitude, location.Latitude)
-	databases.SetVar(ctx, userID, constants.VarLongitude, location.Longitude)
-
-	// Get city name from coordinates using reverse geocoding
-	cityName := databases.GetCityNameFromCoordinates(location.Latitude, location.Longitude)
-	locationName := fmt.Sprintf("📍 %s", cityName)
-	databases.SetVar(ctx, userID, constants.VarLocation, locationName)
-
-	// Clear edit state
-	databases.SetVar(ctx, userID, constants.VarEditState, constants.EditStateNone)
-
-	return p.sendMessage(bot, chatID, constants.MsgProfileUpdated)
+// nameStep adalah langkah input nama
+func (p *StartPlugin) nameStep(prompt func(ctx context.Context, fc *FlowContext) string) *FlowStep {
+	return &FlowStep{
+		Name:   "name",
+		Prompt: prompt,
+		Validate: func(in FlowInput) error {
+			name := in.Value()
+			if len(name) < 2 {
+				return errors.New(constants.MsgNameTooShort)
+			}
+			if len(name) > 50 {
+				return errors.New(constants.MsgNameTooLong)
+			}
+			return nil
+		},
+		Save: func(ctx context.Context, fc *FlowContext, in FlowInput) error {
+			return databases.SetProfileName(ctx, fc.UserID, in.Value())
+		},
+	}
 }
 
-// handleEditCallback menangani callback dari edit profile
-func (p *StartPlugin) handleEditCallback(ctx context.Context, bot *tgbotapi.BotAPI, callback *tgbotapi.CallbackQuery) error {
-	chatID := callback.Message.Chat.ID
-	userID := callback.From.ID
-
-	// Answer callback
-	callbackResponse := tgbotapi.NewCallback(callback.ID, "")
-	bot.Send(callbackResponse)
-
-	// Delete the edit menu message
-	deleteMsg := tgbotapi.NewDeleteMessage(chatID, callback.Message.MessageID)
-	bot.Send(deleteMsg)
-
-	switch callback.Data {
-	case "edit_name":
-		name, _ := databases.GetVar(ctx, userID, constants.VarName)
-		databases.SetVar(ctx, userID, constants.VarEditState, constants.EditStateName)
-		msg := fmt.Sprintf(constants.MsgEditName, name)
-		return p.sendMessage(bot, chatID, msg)
-
-	case "edit_age":
-		age, _ := databases.GetVar(ctx, userID, constants.VarAge)
-		databases.SetVar(ctx, userID, constants.VarEditState, constants.EditStateAge)
-		msg := fmt.Sprintf(constants.MsgEditAge, age)
-		return p.sendMessage(bot, chatID, msg)
-
-	case "edit_gender":
-		gender, _ := databases.GetVar(ctx, userID, constants.VarGender)
-		databases.SetVar(ctx, userID, constants.VarEditState, constants.EditStateGender)
-		msg := fmt.Sprintf(constants.MsgEditGender, gender)
-		return p.sendEditGenderButtons(bot, chatID, msg)
-
-	case "edit_location":
-		location, _ := databases.GetVar(ctx, userID, constants.VarLocation)
-		databases.SetVar(ctx, userID, constants.VarEditState, constants.EditStateLocation)
-		msg := fmt.Sprintf(constants.MsgEditLocation, location)
-		return p.sendMessageWithLocationButton(bot, chatID, msg)
-
-	case "edit_cancel":
-		databases.SetVar(ctx, userID, constants.VarEditState, constants.EditStateNone)
-		return p.sendMessage(bot, chatID, constants.MsgEditCancelled)
+// ageStep adalah langkah input umur
+func (p *StartPlugin) ageStep(prompt func(ctx context.Context, fc *FlowContext) string) *FlowStep {
+	return &FlowStep{
+		Name:   "age",
+		Prompt: prompt,
+		Validate: func(in FlowInput) error {
+			age, err := strconv.Atoi(in.Value())
+			if err != nil || age < 13 || age > 100 {
+				return errors.New(constants.MsgRegInvalidAge)
+			}
+			return nil
+		},
+		Save: func(ctx context.Context, fc *FlowContext, in FlowInput) error {
+			age, err := strconv.Atoi(in.Value())
+			if err != nil {
+				return err
+			}
+			return databases.SetProfileAge(ctx, fc.UserID, age)
+		},
+	}
+}
 
-	case "edit_gender_male":
-		return p.handleEditGenderSelection(ctx, bot, chatID, userID, constants.GenderMale, callback)
-	case "edit_gender_female":
-		return p.handleEditGenderSelection(ctx, bot, chatID, userID, constants.GenderFemale, callback)
-	case "edit_gender_other":
-		return p.handleEditGenderSelection(ctx, bot, chatID, userID, constants.GenderOther, callback)
+// genderStep adalah langkah pilih gender lewat tombol
+func (p *StartPlugin) genderStep(prompt func(ctx context.Context, fc *FlowContext) string) *FlowStep {
+	return &FlowStep{
+		Name:    "gender",
+		Prompt:  prompt,
+		Options: genderOptions,
+		Validate: func(in FlowInput) error {
+			if _, ok := genderValues[in.Option]; !ok {
+				return errors.New(constants.MsgRegInvalidGender)
+			}
+			return nil
+		},
+		Save: func(ctx context.Context, fc *FlowContext, in FlowInput) error {
+			return databases.SetProfileGender(ctx, fc.UserID, genderValues[in.Option])
+		},
 	}
+}
 
-	return nil
+// locationStep adalah langkah share lokasi dengan reverse geocoding
+func (p *StartPlugin) locationStep(prompt func(ctx context.Context, fc *FlowContext) string) *FlowStep {
+	return &FlowStep{
+		Name:     "location",
+		Prompt:   prompt,
+		Keyboard: locationKeyboard,
+		Validate: func(in FlowInput) error {
+			if in.Location == nil {
+				return errors.New(constants.MsgRegInvalidLocation)
+			}
+			return nil
+		},
+		Save: func(ctx context.Context, fc *FlowContext, in FlowInput) error {
+			// Get city name from coordinates using reverse geocoding
+			cityName := databases.GetCityNameFromCoordinates(in.Location.Latitude, in.Location.Longitude)
+			return databases.SetProfileLocation(ctx, fc.UserID, fmt.Sprintf("📍 %s", cityName),
+				in.Location.Latitude, in.Location.Longitude)
+		},
+	}
 }
 
-// sendEditGenderButtons mengirim tombol pilihan gender untuk edit
-func (p *StartPlugin) sendEditGenderButtons(bot *tgbotapi.BotAPI, chatID int64, text string) error {
-	msg := tgbotapi.NewMessage(chatID, text)
-	msg.ParseMode = "Markdown"
+// interestsStep adalah langkah input minat
+func (p *StartPlugin) interestsStep() *FlowStep {
+	return &FlowStep{
+		Name: "interests",
+		Prompt: func(ctx context.Context, fc *FlowContext) string {
+			return fmt.Sprintf(constants.MsgEditInterests, currentProfile(ctx, fc.UserID, func(p *databases.UserProfile) string { return p.Interests }))
+		},
+		Validate: func(in FlowInput) error {
+			interests := in.Value()
+			if interests == "" {
+				return errors.New(constants.MsgInterestsEmpty)
+			}
+			if len([]rune(interests)) > constants.MaxInterestsLength {
+				return errors.New(constants.MsgInterestsTooLong)
+			}
+			return nil
+		},
+		Save: func(ctx context.Context, fc *FlowContext, in FlowInput) error {
+			return databases.SetProfileInterests(ctx, fc.UserID, in.Value())
+		},
+	}
+}
 
-	keyboard := tgbotapi.NewInlineKeyboardMarkup(
-		tgbotapi.NewInlineKeyboardRow(
-			tgbotapi.NewInlineKeyboardButtonData("👨 "+constants.GenderMale, "edit_gender_male"),
-			tgbotapi.NewInlineKeyboardButtonData("👩 "+constants.GenderFemale, "edit_gender_female"),
-		),
-		tgbotapi.NewInlineKeyboardRow(
-			tgbotapi.NewInlineKeyboardButtonData("🧑 "+constants.GenderOther, "edit_gender_other"),
+// locationKeyboard membuat keyboard dengan tombol request location
+func locationKeyboard(fc *FlowContext) interface{} {
+	keyboard := tgbotapi.NewReplyKeyboard(
+		tgbotapi.NewKeyboardButtonRow(
+			tgbotapi.NewKeyboardButtonLocation("📍 Bagikan Lokasi"),
 		),
 	)
-	msg.ReplyMarkup = keyboard
-
-	_, err := bot.Send(msg)
-	return err
+	keyboard.OneTimeKeyboard = true
+	keyboard.ResizeKeyboard = true
+	return keyboard
 }
 
-// handleEditGenderSelection menangani pilihan gender saat edit
-func (p *StartPlugin) handleEditGenderSelection(ctx context.Context, bot *tgbotapi.BotAPI, chatID, userID int64, gender string, callback *tgbotapi.CallbackQuery) error {
-	// Update gender
-	databases.SetVar(ctx, userID, constants.VarGender, gender)
-	databases.SetVar(ctx, userID, constants.VarEditState, constants.EditStateNone)
+// completeRegistration menandai user sudah terdaftar dan mengirim ringkasan profil
+func (p *StartPlugin) completeRegistration(ctx context.Context, fc *FlowContext) error {
+	if err := databases.MarkRegistered(ctx, fc.UserID); err != nil {
+		return err
+	}
 
-	// Answer callback
-	callbackResponse := tgbotapi.NewCallback(callback.ID, "✅ Gender diupdate!")
-	bot.Send(callbackResponse)
+	// Get saved data for confirmation
+	profile, err := databases.GetUserProfile(ctx, fc.UserID)
+	if err != nil {
+		return err
+	}
 
-	// Delete the gender selection message
-	deleteMsg := tgbotapi.NewDeleteMessage(chatID, callback.Message.MessageID)
-	bot.Send(deleteMsg)
+	name := tgbotapi.EscapeText(tgbotapi.ModeMarkdown, profile.Name)
+	location := tgbotapi.EscapeText(tgbotapi.ModeMarkdown, profile.Location)
+	msg := fmt.Sprintf(constants.MsgRegComplete, name, profile.AgeString(), profile.Gender, location)
+	return sendFlowMessage(fc.ChatID, msg, tgbotapi.NewRemoveKeyboard(true))
+}
 
-	return p.sendMessage(bot, chatID, constants.MsgProfileUpdated)
+// completeEdit mengirim konfirmasi setelah edit profil selesai
+func (p *StartPlugin) completeEdit(ctx context.Context, fc *FlowContext) error {
+	return sendFlowMessage(fc.ChatID, constants.MsgProfileUpdated, tgbotapi.NewRemoveKeyboard(true))
 }
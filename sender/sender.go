@@ -0,0 +1,515 @@
+package sender
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// Priority menentukan urutan pengiriman pesan di antrian
+type Priority int
+
+const (
+	PriorityLow    Priority = iota // Broadcast dan ads
+	PriorityNormal                 // Notifikasi bot biasa
+	PriorityHigh                   // Relay pesan chat antar user
+)
+
+const (
+	GlobalRatePerSecond = 30.0             // Limit global Telegram (pesan per detik)
+	GlobalBurst         = 30.0             // Burst global
+	PrivateChatRate     = 1.0              // Pesan per detik ke satu private chat
+	PrivateChatBurst    = 3.0              // Burst untuk satu private chat
+	GroupChatRate       = 20.0 / 60.0      // 20 pesan per menit ke satu grup
+	GroupChatBurst      = 3.0              // Burst untuk satu grup
+	MaxRetries          = 3                // Maksimal retry saat kena 429
+	QueueSize           = 1000             // Kapasitas antrian per prioritas
+	WorkerCount         = 8                // Jumlah worker pengirim
+	ChatLimiterIdleTTL  = 10 * time.Minute // Limiter chat yang idle akan dibuang
+)
+
+// ErrStopped dikembalikan jika dispatcher sudah dihentikan
+var ErrStopped = errors.New("sender: dispatcher stopped")
+
+// Result adalah hasil pengiriman satu request
+type Result struct {
+	Message  tgbotapi.Message
+	Response *tgbotapi.APIResponse
+	Err      error
+}
+
+type job struct {
+	chattable tgbotapi.Chattable
+	request   bool // true = Request (tanpa parse Message), false = Send
+	result    chan Result
+}
+
+// BlockedHandler dipanggil saat user memblokir bot atau akunnya sudah dihapus
+type BlockedHandler func(userID int64)
+
+// Dispatcher mengirim semua request keluar ke Telegram dengan rate limit
+// global dan per chat, serta retry otomatis saat mendapat 429 retry_after.
+type Dispatcher struct {
+	bot       *tgbotapi.BotAPI
+	queues    [3]chan *job
+	global    *limiter
+	chats     map[int64]*limiter
+	limitMu   sync.Mutex
+	onBlocked []BlockedHandler
+	stopChan  chan struct{}
+	wg        sync.WaitGroup
+	running   bool
+	mu        sync.RWMutex
+	pending   atomic.Int64 // Job di antrian + yang sedang dikirim
+}
+
+// NewDispatcher membuat Dispatcher baru untuk bot
+func NewDispatcher(bot *tgbotapi.BotAPI) *Dispatcher {
+	d := &Dispatcher{
+		bot:      bot,
+		global:   newLimiter(GlobalRatePerSecond, GlobalBurst),
+		chats:    make(map[int64]*limiter),
+		stopChan: make(chan struct{}),
+	}
+	for i := range d.queues {
+		d.queues[i] = make(chan *job, QueueSize)
+	}
+	return d
+}
+
+// Start memulai worker pengirim
+func (d *Dispatcher) Start() {
+	d.mu.Lock()
+	if d.running {
+		d.mu.Unlock()
+		return
+	}
+	d.running = true
+	d.mu.Unlock()
+
+	for i := 0; i < WorkerCount; i++ {
+		d.wg.Add(1)
+		go d.worker()
+	}
+
+	d.wg.Add(1)
+	go d.janitor()
+
+	log.Printf("📤 Outbound sender started (%d workers, %.0f msg/s global)", WorkerCount, GlobalRatePerSecond)
+}
+
+// Stop menghentikan worker. Request yang belum terkirim akan gagal dengan ErrStopped.
+func (d *Dispatcher) Stop() {
+	d.mu.Lock()
+	if !d.running {
+		d.mu.Unlock()
+		return
+	}
+	d.running = false
+	d.mu.Unlock()
+
+	close(d.stopChan)
+	d.wg.Wait()
+
+	// Fail semua job yang masih ada di antrian
+	for _, q := range d.queues {
+	drain:
+		for {
+			select {
+			case j := <-q:
+				d.pending.Add(-1)
+				j.result <- Result{Err: ErrStopped}
+			default:
+				break drain
+			}
+		}
+	}
+
+	log.Println("⏹️ Outbound sender stopped")
+}
+
+// Shutdown menunggu antrian kosong (atau ctx habis) lalu menghentikan worker.
+// Job yang belum terkirim saat deadline akan gagal dengan ErrStopped.
+func (d *Dispatcher) Shutdown(ctx context.Context) error {
+	ticker := time.NewTicker(50 * time.Millisecond)
+	defer ticker.Stop()
+
+	var err error
+wait:
+	for d.pending.Load() > 0 {
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			err = fmt.Errorf("sender: %d request belum terkirim: %w", d.pending.Load(), ctx.Err())
+			break wait
+		}
+	}
+
+	d.Stop()
+	return err
+}
+
+// Pending mengembalikan jumlah request yang masih di antrian atau sedang dikirim
+func (d *Dispatcher) Pending() int64 {
+	return d.pending.Load()
+}
+
+// OnBlocked mendaftarkan handler yang dipanggil saat pengiriman ke user gagal
+// karena user memblokir bot atau akunnya sudah dihapus
+func (d *Dispatcher) OnBlocked(handler BlockedHandler) {
+	d.mu.Lock()
+	d.onBlocked = append(d.onBlocked, handler)
+	d.mu.Unlock()
+}
+
+// Send mengirim Chattable dan menunggu Message hasilnya
+func (d *Dispatcher) Send(c tgbotapi.Chattable, prio Priority) (tgbotapi.Message, error) {
+	res := <-d.enqueue(c, prio, false)
+	return res.Message, res.Err
+}
+
+// Request mengirim Chattable yang tidak menghasilkan Message
+// (answerCallbackQuery, deleteMessage, dll)
+func (d *Dispatcher) Request(c tgbotapi.Chattable, prio Priority) (*tgbotapi.APIResponse, error) {
+	res := <-d.enqueue(c, prio, true)
+	return res.Response, res.Err
+}
+
+// SendAsync memasukkan Chattable ke antrian tanpa menunggu hasilnya.
+// Hasil pengiriman bisa dibaca dari channel yang dikembalikan.
+func (d *Dispatcher) SendAsync(c tgbotapi.Chattable, prio Priority) <-chan Result {
+	return d.enqueue(c, prio, false)
+}
+
+// enqueue memasukkan job ke antrian sesuai prioritas
+func (d *Dispatcher) enqueue(c tgbotapi.Chattable, prio Priority, request bool) <-chan Result {
+	j := &job{chattable: c, request: request, result: make(chan Result, 1)}
+
+	if prio < PriorityLow || prio > PriorityHigh {
+		prio = PriorityNormal
+	}
+
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	if !d.running {
+		j.result <- Result{Err: ErrStopped}
+		return j.result
+	}
+
+	d.pending.Add(1)
+	select {
+	case d.queues[prio] <- j:
+	case <-d.stopChan:
+		d.pending.Add(-1)
+		j.result <- Result{Err: ErrStopped}
+	}
+	return j.result
+}
+
+// worker mengambil job dari antrian (prioritas tertinggi dulu) dan mengirimnya
+func (d *Dispatcher) worker() {
+	defer d.wg.Done()
+
+	for {
+		j, ok := d.next()
+		if !ok {
+			return
+		}
+		j.result <- d.process(j)
+		d.pending.Add(-1)
+	}
+}
+
+// next mengambil job berikutnya, mendahulukan antrian prioritas tinggi
+func (d *Dispatcher) next() (*job, bool) {
+	for p := PriorityHigh; p >= PriorityLow; p-- {
+		select {
+		case j := <-d.queues[p]:
+			return j, true
+		default:
+		}
+	}
+
+	select {
+	case j := <-d.queues[PriorityHigh]:
+		return j, true
+	case j := <-d.queues[PriorityNormal]:
+		return j, true
+	case j := <-d.queues[PriorityLow]:
+		return j, true
+	case <-d.stopChan:
+		return nil, false
+	}
+}
+
+// process mengirim satu job dengan rate limit dan retry 429
+func (d *Dispatcher) process(j *job) Result {
+	chatID := chatIDOf(j.chattable)
+
+	for attempt := 0; ; attempt++ {
+		d.wait(chatID)
+
+		resp, err := d.bot.Request(j.chattable)
+		if err == nil {
+			res := Result{Response: resp}
+			if !j.request {
+				res.Err = json.Unmarshal(resp.Result, &res.Message)
+			}
+			return res
+		}
+
+		if IsBlocked(err) && chatID > 0 {
+			d.notifyBlocked(chatID)
+		}
+
+		retryAfter := RetryAfter(err)
+		if retryAfter <= 0 || attempt >= MaxRetries {
+			return Result{Response: resp, Err: err}
+		}
+
+		log.Printf("⏳ Rate limited by Telegram (chat %d), retrying in %s (attempt %d/%d)",
+			chatID, retryAfter, attempt+1, MaxRetries)
+		d.pause(chatID, retryAfter)
+	}
+}
+
+// notifyBlocked menjalankan semua BlockedHandler di goroutine terpisah
+// supaya handler bebas mengirim pesan lewat dispatcher tanpa deadlock
+func (d *Dispatcher) notifyBlocked(userID int64) {
+	d.mu.RLock()
+	handlers := d.onBlocked
+	d.mu.RUnlock()
+
+	for _, handler := range handlers {
+		go handler(userID)
+	}
+}
+
+// wait menunggu sampai limiter global dan limiter chat mengizinkan pengiriman
+func (d *Dispatcher) wait(chatID int64) {
+	now := time.Now()
+
+	d.limitMu.Lock()
+	delay := d.global.reserve(now)
+	if chatID != 0 {
+		if chatDelay := d.chatLimiter(chatID).reserve(now); chatDelay > delay {
+			delay = chatDelay
+		}
+	}
+	d.limitMu.Unlock()
+
+	if delay > 0 {
+		time.Sleep(delay)
+	}
+}
+
+// pause menahan pengiriman ke chat (atau global jika chatID 0) selama durasi tertentu
+func (d *Dispatcher) pause(chatID int64, duration time.Duration) {
+	until := time.Now().Add(duration)
+
+	d.limitMu.Lock()
+	defer d.limitMu.Unlock()
+
+	if chatID == 0 {
+		d.global.pauseUntil(until)
+		return
+	}
+	d.chatLimiter(chatID).pauseUntil(until)
+}
+
+// chatLimiter mengambil limiter untuk chat (harus dipanggil dengan limitMu terkunci)
+func (d *Dispatcher) chatLimiter(chatID int64) *limiter {
+	l, exists := d.chats[chatID]
+	if !exists {
+		if chatID < 0 {
+			l = newLimiter(GroupChatRate, GroupChatBurst)
+		} else {
+			l = newLimiter(PrivateChatRate, PrivateChatBurst)
+		}
+		d.chats[chatID] = l
+	}
+	return l
+}
+
+// janitor membuang limiter chat yang sudah lama idle
+func (d *Dispatcher) janitor() {
+	defer d.wg.Done()
+
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			cutoff := time.Now().Add(-ChatLimiterIdleTTL)
+			d.limitMu.Lock()
+			for chatID, l := range d.chats {
+				if l.last.Before(cutoff) && l.paused.Before(cutoff) {
+					delete(d.chats, chatID)
+				}
+			}
+			d.limitMu.Unlock()
+		case <-d.stopChan:
+			return
+		}
+	}
+}
+
+// RetryAfter mengembalikan durasi retry_after dari error 429 Telegram (0 jika bukan 429)
+func RetryAfter(err error) time.Duration {
+	var tgErr *tgbotapi.Error
+	if errors.As(err, &tgErr) && tgErr.RetryAfter > 0 {
+		return time.Duration(tgErr.RetryAfter) * time.Second
+	}
+	return 0
+}
+
+// IsBlocked mengecek apakah error berarti user memblokir bot atau akunnya sudah dihapus
+func IsBlocked(err error) bool {
+	var tgErr *tgbotapi.Error
+	if !errors.As(err, &tgErr) || tgErr.Code != 403 {
+		return false
+	}
+	msg := strings.ToLower(tgErr.Message)
+	return strings.Contains(msg, "bot was blocked by the user") ||
+		strings.Contains(msg, "user is deactivated")
+}
+
+// chatIDOf mengambil chat ID tujuan dari Chattable (0 jika tidak diketahui)
+func chatIDOf(c tgbotapi.Chattable) int64 {
+	switch v := c.(type) {
+	case tgbotapi.MessageConfig:
+		return v.ChatID
+	case tgbotapi.PhotoConfig:
+		return v.ChatID
+	case tgbotapi.VideoConfig:
+		return v.ChatID
+	case tgbotapi.StickerConfig:
+		return v.ChatID
+	case tgbotapi.VoiceConfig:
+		return v.ChatID
+	case tgbotapi.DocumentConfig:
+		return v.ChatID
+	case tgbotapi.AnimationConfig:
+		return v.ChatID
+	case tgbotapi.EditMessageTextConfig:
+		return v.ChatID
+	case tgbotapi.EditMessageCaptionConfig:
+		return v.ChatID
+	case tgbotapi.DeleteMessageConfig:
+		return v.ChatID
+	}
+	return 0
+}
+
+// limiter adalah token bucket sederhana
+type limiter struct {
+	rate   float64 // token per detik
+	burst  float64
+	tokens float64
+	last   time.Time
+	paused time.Time
+}
+
+func newLimiter(rate, burst float64) *limiter {
+	return &limiter{rate: rate, burst: burst, tokens: burst, last: time.Now()}
+}
+
+// reserve mengambil satu token dan mengembalikan durasi yang harus ditunggu
+func (l *limiter) reserve(now time.Time) time.Duration {
+	elapsed := now.Sub(l.last).Seconds()
+	if elapsed > 0 {
+		l.tokens += elapsed * l.rate
+		if l.tokens > l.burst {
+			l.tokens = l.burst
+		}
+		l.last = now
+	}
+
+	l.tokens--
+
+	var delay time.Duration
+	if l.tokens < 0 {
+		delay = time.Duration(-l.tokens / l.rate * float64(time.Second))
+	}
+	if pausedDelay := l.paused.Sub(now); pausedDelay > delay {
+		delay = pausedDelay
+	}
+	return delay
+}
+
+// pauseUntil menahan limiter sampai waktu tertentu
+func (l *limiter) pauseUntil(until time.Time) {
+	if until.After(l.paused) {
+		l.paused = until
+	}
+}
+
+// ============================================================
+// DEFAULT DISPATCHER
+// ============================================================
+
+var defaultDispatcher *Dispatcher
+
+// Init membuat dan menjalankan dispatcher default
+func Init(bot *tgbotapi.BotAPI) {
+	defaultDispatcher = NewDispatcher(bot)
+	defaultDispatcher.Start()
+}
+
+// Stop menghentikan dispatcher default
+func Stop() {
+	if defaultDispatcher != nil {
+		defaultDispatcher.Stop()
+	}
+}
+
+// Shutdown menunggu antrian dispatcher default kosong lalu menghentikannya
+func Shutdown(ctx context.Context) error {
+	if defaultDispatcher == nil {
+		return nil
+	}
+	return defaultDispatcher.Shutdown(ctx)
+}
+
+// OnBlocked mendaftarkan BlockedHandler di dispatcher default
+func OnBlocked(handler BlockedHandler) {
+	if defaultDispatcher != nil {
+		defaultDispatcher.OnBlocked(handler)
+	}
+}
+
+// Send mengirim Chattable lewat dispatcher default
+func Send(c tgbotapi.Chattable, prio Priority) (tgbotapi.Message, error) {
+	if defaultDispatcher == nil {
+		return tgbotapi.Message{}, ErrStopped
+	}
+	return defaultDispatcher.Send(c, prio)
+}
+
+// Request mengirim Chattable tanpa Message lewat dispatcher default
+func Request(c tgbotapi.Chattable, prio Priority) (*tgbotapi.APIResponse, error) {
+	if defaultDispatcher == nil {
+		return nil, ErrStopped
+	}
+	return defaultDispatcher.Request(c, prio)
+}
+
+// SendAsync memasukkan Chattable ke antrian dispatcher default tanpa menunggu
+func SendAsync(c tgbotapi.Chattable, prio Priority) <-chan Result {
+	if defaultDispatcher == nil {
+		ch := make(chan Result, 1)
+		ch <- Result{Err: ErrStopped}
+		return ch
+	}
+	return defaultDispatcher.SendAsync(c, prio)
+}
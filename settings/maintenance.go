@@ -0,0 +1,82 @@
+package settings
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync/atomic"
+
+	"tg-anon-go/cache"
+	"tg-anon-go/constants"
+	"tg-anon-go/databases"
+)
+
+// keyMaintenance adalah payload notifikasi di ChannelChanged saat mode maintenance berubah
+const keyMaintenance = "maintenance"
+
+// maintenanceState adalah status maintenance yang sedang berlaku di instance ini
+type maintenanceState struct {
+	enabled bool
+	message string
+}
+
+var maintenance atomic.Pointer[maintenanceState]
+
+// Maintenance mengembalikan apakah bot sedang maintenance beserta pesan dari admin ("" = pesan default)
+func Maintenance() (enabled bool, message string) {
+	if state := maintenance.Load(); state != nil {
+		return state.enabled, state.message
+	}
+	return false, ""
+}
+
+// SetMaintenance menyimpan status bot (VarGlobalBotStatus) dan pesan maintenance,
+// lalu memberi tahu instance lain lewat Redis
+func SetMaintenance(ctx context.Context, enabled bool, message string) error {
+	status := constants.BotStatusActive
+	if enabled {
+		status = constants.BotStatusMaintenance
+	} else {
+		message = ""
+	}
+
+	if err := databases.SetGlobalVar(ctx, constants.VarGlobalBotStatus, status); err != nil {
+		return fmt.Errorf("save bot status: %w", err)
+	}
+	if err := databases.SetGlobalVar(ctx, constants.VarGlobalMaintenanceMessage, message); err != nil {
+		return fmt.Errorf("save maintenance message: %w", err)
+	}
+	maintenance.Store(&maintenanceState{enabled: enabled, message: message})
+
+	if cache.Redis != nil {
+		if err := cache.Redis.Publish(ctx, ChannelChanged, keyMaintenance).Err(); err != nil {
+			log.Printf("⚠️ Error publishing maintenance change: %v", err)
+		}
+	}
+	log.Printf("🛠️ Bot status set to %s", status)
+	return nil
+}
+
+// applyMaintenance memasang status maintenance dari nilai variabel global
+func applyMaintenance(status, message string) {
+	maintenance.Store(&maintenanceState{
+		enabled: status == constants.BotStatusMaintenance,
+		message: message,
+	})
+}
+
+// reloadMaintenance membaca ulang status maintenance (dipanggil saat ada notifikasi)
+func reloadMaintenance(ctx context.Context) {
+	status, err := databases.GetGlobalVar(ctx, constants.VarGlobalBotStatus)
+	if err != nil {
+		log.Printf("Error reloading bot status: %v", err)
+		return
+	}
+	message, err := databases.GetGlobalVar(ctx, constants.VarGlobalMaintenanceMessage)
+	if err != nil {
+		log.Printf("Error reloading maintenance message: %v", err)
+		return
+	}
+	applyMaintenance(status, message)
+	log.Printf("🛠️ Bot status reloaded: %s", status)
+}
@@ -0,0 +1,240 @@
+package settings
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"sync/atomic"
+
+	"tg-anon-go/cache"
+	"tg-anon-go/config"
+	"tg-anon-go/constants"
+	"tg-anon-go/databases"
+)
+
+// ChannelChanged adalah channel Redis Pub/Sub untuk memberi tahu instance lain
+// bahwa sebuah setting berubah (payload: key setting)
+const ChannelChanged = "settings:changed"
+
+// ErrUnknown dikembalikan jika key setting tidak terdaftar
+var ErrUnknown = errors.New("unknown setting")
+
+// ErrInvalid dikembalikan jika nilai tidak sesuai tipe atau rentang setting
+var ErrInvalid = errors.New("invalid value")
+
+// Setting adalah setting runtime yang bisa dibaca dan diubah admin lewat /config.
+// Nilai default diambil dari config (environment), override disimpan di variabel global.
+type Setting interface {
+	Key() string
+	Description() string
+	// Value mengembalikan nilai aktif dalam bentuk teks
+	Value() string
+	Default() string
+	// Range menjelaskan nilai yang diterima, misalnya "1-100"
+	Range() string
+	// Overridden true jika nilai aktif berasal dari variabel global, bukan default
+	Overridden() bool
+
+	// parse memvalidasi teks dan mengembalikan fungsi yang menerapkan nilainya
+	parse(value string) (apply func(), err error)
+	reset()
+}
+
+// IntSetting adalah setting bilangan bulat dengan batas minimal dan maksimal
+type IntSetting struct {
+	key         string
+	description string
+	min, max    int
+	def         func() int
+
+	value      atomic.Int64
+	overridden atomic.Bool
+}
+
+// NewInt membuat IntSetting dan mendaftarkannya ke registry
+func NewInt(key, description string, min, max int, def func() int) *IntSetting {
+	s := &IntSetting{key: key, description: description, min: min, max: max, def: def}
+	registry = append(registry, s)
+	return s
+}
+
+// Get mengembalikan nilai aktif (override jika ada, selain itu default dari config)
+func (s *IntSetting) Get() int {
+	if s.overridden.Load() {
+		return int(s.value.Load())
+	}
+	return s.def()
+}
+
+func (s *IntSetting) Key() string         { return s.key }
+func (s *IntSetting) Description() string { return s.description }
+func (s *IntSetting) Value() string       { return strconv.Itoa(s.Get()) }
+func (s *IntSetting) Default() string     { return strconv.Itoa(s.def()) }
+func (s *IntSetting) Range() string       { return fmt.Sprintf("%d-%d", s.min, s.max) }
+func (s *IntSetting) Overridden() bool    { return s.overridden.Load() }
+
+func (s *IntSetting) parse(value string) (func(), error) {
+	n, err := strconv.Atoi(strings.TrimSpace(value))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s must be an integer, got %q", ErrInvalid, s.key, value)
+	}
+	if n < s.min || n > s.max {
+		return nil, fmt.Errorf("%w: %s must be between %d and %d, got %d", ErrInvalid, s.key, s.min, s.max, n)
+	}
+	return func() {
+		s.value.Store(int64(n))
+		s.overridden.Store(true)
+	}, nil
+}
+
+func (s *IntSetting) reset() {
+	s.overridden.Store(false)
+}
+
+// registry berisi semua setting runtime dengan urutan deklarasi
+var registry []Setting
+
+// Setting runtime. Default-nya mengikuti environment variable dengan nama yang sama.
+var (
+	MaxWarnings = NewInt("max_warnings", "Jumlah warning sebelum auto-ban", 1, 100,
+		func() int { return config.C.MaxWarnings })
+	AdsIntervalMessages = NewInt("ads_interval_messages", "Kirim ads setiap N pesan", 1, 100000,
+		func() int { return config.C.AdsIntervalMessages })
+	NearbyRadius = NewInt("nearby_radius", "Jarak maksimal (km) pencarian nearby", 1, 20000,
+		func() int { return config.C.NearbyRadius })
+	SessionIdleTimeout = NewInt("session_idle_timeout", "Menit tanpa pesan sebelum sesi ditutup otomatis (0 = nonaktif)", 0, 10080,
+		func() int { return config.C.SessionIdleTimeout })
+	SessionIdleWarning = NewInt("session_idle_warning", "Menit sebelum ditutup peringatan idle dikirim (0 = tanpa peringatan)", 0, 10080,
+		func() int { return config.C.SessionIdleWarning })
+)
+
+// All mengembalikan semua setting yang terdaftar
+func All() []Setting {
+	return registry
+}
+
+// Lookup mencari setting berdasarkan key (tidak case-sensitive)
+func Lookup(key string) (Setting, bool) {
+	key = strings.ToLower(strings.TrimSpace(key))
+	for _, s := range registry {
+		if s.Key() == key {
+			return s, true
+		}
+	}
+	return nil, false
+}
+
+// varKey adalah nama variabel global tempat override setting disimpan
+func varKey(key string) string {
+	return constants.VarGlobalSettingPrefix + key
+}
+
+// Load membaca semua override dan status maintenance dari variabel global. Nilai tersimpan
+// yang tidak valid dilewati (dicatat di log) sehingga setting tersebut kembali ke default.
+func Load(ctx context.Context) error {
+	vars, err := databases.GetAllVars(ctx, 0)
+	if err != nil {
+		return fmt.Errorf("load settings: %w", err)
+	}
+
+	for _, s := range registry {
+		value, ok := vars[varKey(s.Key())]
+		if !ok || value == "" {
+			s.reset()
+			continue
+		}
+		apply, err := s.parse(value)
+		if err != nil {
+			log.Printf("⚠️ Ignoring stored setting: %v", err)
+			s.reset()
+			continue
+		}
+		apply()
+	}
+
+	applyMaintenance(vars[constants.VarGlobalBotStatus], vars[constants.VarGlobalMaintenanceMessage])
+	return nil
+}
+
+// Set memvalidasi dan menyimpan nilai baru, lalu memberi tahu instance lain lewat Redis
+func Set(ctx context.Context, key, value string) (Setting, error) {
+	s, ok := Lookup(key)
+	if !ok {
+		return nil, fmt.Errorf("%w %q", ErrUnknown, key)
+	}
+
+	apply, err := s.parse(value)
+	if err != nil {
+		return s, err
+	}
+	if err := databases.SetGlobalVar(ctx, varKey(s.Key()), strings.TrimSpace(value)); err != nil {
+		return s, fmt.Errorf("save setting %s: %w", s.Key(), err)
+	}
+	apply()
+
+	if cache.Redis != nil {
+		if err := cache.Redis.Publish(ctx, ChannelChanged, s.Key()).Err(); err != nil {
+			log.Printf("⚠️ Error publishing setting change %s: %v", s.Key(), err)
+		}
+	}
+	log.Printf("⚙️ Setting %s set to %s", s.Key(), s.Value())
+	return s, nil
+}
+
+// reload membaca ulang satu setting dari variabel global (dipanggil saat ada notifikasi)
+func reload(ctx context.Context, key string) {
+	if key == keyMaintenance {
+		reloadMaintenance(ctx)
+		return
+	}
+
+	s, ok := Lookup(key)
+	if !ok {
+		return
+	}
+
+	value, err := databases.GetGlobalVar(ctx, varKey(s.Key()))
+	if err != nil {
+		log.Printf("Error reloading setting %s: %v", s.Key(), err)
+		return
+	}
+	if value == "" {
+		s.reset()
+		return
+	}
+	apply, err := s.parse(value)
+	if err != nil {
+		log.Printf("⚠️ Ignoring stored setting: %v", err)
+		return
+	}
+	apply()
+	log.Printf("⚙️ Setting %s reloaded: %s", s.Key(), s.Value())
+}
+
+// Listen menerima notifikasi perubahan setting dari instance lain hingga ctx dibatalkan
+func Listen(ctx context.Context) {
+	if cache.Redis == nil {
+		return
+	}
+
+	pubsub := cache.Redis.Subscribe(ctx, ChannelChanged)
+	defer pubsub.Close()
+
+	log.Println("👂 Listening on channel:", ChannelChanged)
+
+	ch := pubsub.Channel()
+	for {
+		select {
+		case msg, ok := <-ch:
+			if !ok {
+				return
+			}
+			reload(ctx, msg.Payload)
+		case <-ctx.Done():
+			return
+		}
+	}
+}
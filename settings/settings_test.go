@@ -0,0 +1,135 @@
+package settings
+
+import (
+	"errors"
+	"testing"
+
+	"tg-anon-go/constants"
+)
+
+func TestIntSettingParse(t *testing.T) {
+	s := &IntSetting{key: "test", min: 1, max: 100, def: func() int { return 10 }}
+
+	tests := []struct {
+		value   string
+		want    int
+		wantErr bool
+	}{
+		{"1", 1, false},
+		{"100", 100, false},
+		{" 42 ", 42, false},
+		{"0", 0, true},
+		{"101", 0, true},
+		{"-5", 0, true},
+		{"abc", 0, true},
+		{"4.5", 0, true},
+		{"", 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			s.reset()
+			apply, err := s.parse(tt.value)
+			if tt.wantErr {
+				if !errors.Is(err, ErrInvalid) {
+					t.Fatalf("parse(%q) error = %v, want ErrInvalid", tt.value, err)
+				}
+				if s.Overridden() || s.Get() != 10 {
+					t.Errorf("parse(%q) changed the value to %d before apply", tt.value, s.Get())
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parse(%q) = %v", tt.value, err)
+			}
+
+			apply()
+			if got := s.Get(); got != tt.want {
+				t.Errorf("Get() = %d, want %d", got, tt.want)
+			}
+			if !s.Overridden() {
+				t.Error("Overridden() = false after apply")
+			}
+		})
+	}
+}
+
+func TestIntSettingResetFollowsDefault(t *testing.T) {
+	def := 10
+	s := &IntSetting{key: "test", min: 0, max: 100, def: func() int { return def }}
+
+	apply, err := s.parse("50")
+	if err != nil {
+		t.Fatal(err)
+	}
+	apply()
+	def = 20
+	if got := s.Get(); got != 50 {
+		t.Errorf("Get() = %d with override, want 50", got)
+	}
+
+	s.reset()
+	if got, text := s.Get(), s.Default(); got != 20 || text != "20" {
+		t.Errorf("after reset Get() = %d, Default() = %q, want 20", got, text)
+	}
+	if s.Overridden() {
+		t.Error("Overridden() = true after reset")
+	}
+}
+
+func TestRegistryRanges(t *testing.T) {
+	seen := make(map[string]bool)
+	for _, s := range All() {
+		if seen[s.Key()] {
+			t.Errorf("duplicate setting key %q", s.Key())
+		}
+		seen[s.Key()] = true
+
+		// Nilai default dari config harus lolos validasi setting itu sendiri
+		if _, err := s.parse(s.Default()); err != nil {
+			t.Errorf("default of %s rejected: %v", s.Key(), err)
+		}
+	}
+}
+
+func TestLookup(t *testing.T) {
+	tests := []struct {
+		key    string
+		want   Setting
+		wantOK bool
+	}{
+		{"max_warnings", MaxWarnings, true},
+		{" MAX_WARNINGS ", MaxWarnings, true},
+		{"Nearby_Radius", NearbyRadius, true},
+		{"unknown", nil, false},
+		{"", nil, false},
+	}
+
+	for _, tt := range tests {
+		got, ok := Lookup(tt.key)
+		if ok != tt.wantOK || got != tt.want {
+			t.Errorf("Lookup(%q) = (%v, %v), want (%v, %v)", tt.key, got, ok, tt.want, tt.wantOK)
+		}
+	}
+}
+
+func TestApplyMaintenance(t *testing.T) {
+	tests := []struct {
+		status, message string
+		wantEnabled     bool
+		wantMessage     string
+	}{
+		{constants.BotStatusMaintenance, "Back soon", true, "Back soon"},
+		{constants.BotStatusActive, "", false, ""},
+		{"", "", false, ""},
+	}
+
+	for _, tt := range tests {
+		applyMaintenance(tt.status, tt.message)
+		enabled, message := Maintenance()
+		if enabled != tt.wantEnabled || message != tt.wantMessage {
+			t.Errorf("status %q: Maintenance() = (%v, %q), want (%v, %q)",
+				tt.status, enabled, message, tt.wantEnabled, tt.wantMessage)
+		}
+	}
+}
@@ -0,0 +1,281 @@
+package updates
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"log"
+	"runtime/debug"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// Handler memproses satu update dari Telegram
+type Handler func(update tgbotapi.Update)
+
+type item struct {
+	update   tgbotapi.Update
+	queuedAt time.Time
+}
+
+// Dispatcher membagi update ke sejumlah worker tetap berdasarkan user ID.
+// Update dari user yang sama selalu masuk ke shard yang sama sehingga diproses berurutan,
+// sementara user berbeda diproses paralel di shard lain.
+type Dispatcher struct {
+	handle    Handler
+	shards    []chan item
+	queueSize int
+	wg        sync.WaitGroup
+	running   bool
+	mu        sync.RWMutex
+
+	// Pelacakan offset: update_id yang belum selesai dan update_id tertinggi yang masuk
+	offsetMu     sync.Mutex
+	inFlight     map[int]struct{}
+	maxSubmitted int
+
+	processed   atomic.Int64
+	waitNanos   atomic.Int64
+	handleNanos atomic.Int64
+	maxWait     atomic.Int64
+}
+
+// Stats berisi ringkasan kondisi dispatcher untuk monitoring
+type Stats struct {
+	Workers       int
+	QueueCapacity int           // Kapasitas antrian per worker
+	QueueDepth    []int         // Jumlah update yang mengantri per worker
+	Queued        int           // Total update yang mengantri
+	Processed     int64         // Total update yang selesai diproses
+	AvgWait       time.Duration // Rata-rata waktu tunggu di antrian
+	AvgHandle     time.Duration // Rata-rata waktu proses handler
+	MaxWait       time.Duration // Waktu tunggu terlama sejak start
+}
+
+// NewDispatcher membuat Dispatcher dengan jumlah worker dan kapasitas antrian per worker
+func NewDispatcher(handle Handler, workers, queueSize int) *Dispatcher {
+	if workers < 1 {
+		workers = 1
+	}
+	if queueSize < 1 {
+		queueSize = 1
+	}
+
+	d := &Dispatcher{
+		handle:    handle,
+		shards:    make([]chan item, workers),
+		queueSize: queueSize,
+		inFlight:  make(map[int]struct{}),
+	}
+	for i := range d.shards {
+		d.shards[i] = make(chan item, queueSize)
+	}
+	return d
+}
+
+// Start memulai worker
+func (d *Dispatcher) Start() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.running {
+		return
+	}
+	d.running = true
+
+	for _, shard := range d.shards {
+		d.wg.Add(1)
+		go d.worker(shard)
+	}
+
+	log.Printf("📥 Update dispatcher started (%d workers, queue %d per worker)", len(d.shards), d.queueSize)
+}
+
+// Stop berhenti menerima update baru lalu menunggu antrian yang tersisa selesai diproses
+func (d *Dispatcher) Stop() {
+	d.Shutdown(context.Background())
+}
+
+// Shutdown berhenti menerima update baru lalu menunggu antrian selesai diproses atau ctx habis
+func (d *Dispatcher) Shutdown(ctx context.Context) error {
+	d.mu.Lock()
+	if !d.running {
+		d.mu.Unlock()
+		return nil
+	}
+	d.running = false
+	for _, shard := range d.shards {
+		close(shard)
+	}
+	d.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		d.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		log.Println("⏹️ Update dispatcher stopped")
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("updates: %d update belum selesai diproses: %w", d.Stats().Queued, ctx.Err())
+	}
+}
+
+// Submit memasukkan update ke antrian shard milik user-nya.
+// Jika antrian penuh, Submit menunggu (backpressure ke polling/webhook).
+// Mengembalikan false jika dispatcher sudah dihentikan.
+func (d *Dispatcher) Submit(update tgbotapi.Update) bool {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	if !d.running {
+		return false
+	}
+
+	d.offsetMu.Lock()
+	d.inFlight[update.UpdateID] = struct{}{}
+	if update.UpdateID > d.maxSubmitted {
+		d.maxSubmitted = update.UpdateID
+	}
+	d.offsetMu.Unlock()
+
+	d.shards[d.shardOf(update)] <- item{update: update, queuedAt: time.Now()}
+	return true
+}
+
+// Offset mengembalikan update_id tertinggi yang semua update sebelumnya sudah selesai diproses.
+// Aman dipakai sebagai offset polling: update setelahnya belum tentu selesai.
+func (d *Dispatcher) Offset() int {
+	d.offsetMu.Lock()
+	defer d.offsetMu.Unlock()
+
+	offset := d.maxSubmitted
+	for id := range d.inFlight {
+		if id-1 < offset {
+			offset = id - 1
+		}
+	}
+	return offset
+}
+
+// worker memproses update satu per satu dari satu shard
+func (d *Dispatcher) worker(shard chan item) {
+	defer d.wg.Done()
+
+	for it := range shard {
+		start := time.Now()
+		wait := start.Sub(it.queuedAt)
+
+		d.safeHandle(it.update)
+
+		d.offsetMu.Lock()
+		delete(d.inFlight, it.update.UpdateID)
+		d.offsetMu.Unlock()
+
+		d.processed.Add(1)
+		d.waitNanos.Add(int64(wait))
+		d.handleNanos.Add(int64(time.Since(start)))
+		for {
+			current := d.maxWait.Load()
+			if int64(wait) <= current || d.maxWait.CompareAndSwap(current, int64(wait)) {
+				break
+			}
+		}
+	}
+}
+
+// safeHandle menjalankan handler dan menangkap panic supaya satu update yang gagal
+// tidak menghentikan worker (dan semua user di shard-nya)
+func (d *Dispatcher) safeHandle(update tgbotapi.Update) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("🔥 Panic handling update %d: %v\n%s", update.UpdateID, r, debug.Stack())
+		}
+	}()
+	d.handle(update)
+}
+
+// shardOf memilih shard berdasarkan user ID (fallback ke chat ID)
+func (d *Dispatcher) shardOf(update tgbotapi.Update) int {
+	key := keyOf(update)
+	h := fnv.New32a()
+	h.Write([]byte(strconv.FormatInt(key, 10)))
+	return int(h.Sum32() % uint32(len(d.shards)))
+}
+
+// keyOf mengambil ID user pengirim update
+func keyOf(update tgbotapi.Update) int64 {
+	if user := update.SentFrom(); user != nil {
+		return user.ID
+	}
+	if update.MyChatMember != nil {
+		return update.MyChatMember.From.ID
+	}
+	if chat := update.FromChat(); chat != nil {
+		return chat.ID
+	}
+	return 0
+}
+
+// Stats mengembalikan kondisi antrian dan latency saat ini
+func (d *Dispatcher) Stats() Stats {
+	stats := Stats{
+		Workers:       len(d.shards),
+		QueueCapacity: d.queueSize,
+		QueueDepth:    make([]int, len(d.shards)),
+		Processed:     d.processed.Load(),
+		MaxWait:       time.Duration(d.maxWait.Load()),
+	}
+	for i, shard := range d.shards {
+		stats.QueueDepth[i] = len(shard)
+		stats.Queued += stats.QueueDepth[i]
+	}
+	if stats.Processed > 0 {
+		stats.AvgWait = time.Duration(d.waitNanos.Load() / stats.Processed)
+		stats.AvgHandle = time.Duration(d.handleNanos.Load() / stats.Processed)
+	}
+	return stats
+}
+
+// WriteMetrics menulis metrik dispatcher dalam format teks Prometheus
+func (d *Dispatcher) WriteMetrics(w io.Writer) {
+	stats := d.Stats()
+
+	fmt.Fprintf(w, "# HELP bot_update_workers Number of update workers.\n")
+	fmt.Fprintf(w, "# TYPE bot_update_workers gauge\n")
+	fmt.Fprintf(w, "bot_update_workers %d\n", stats.Workers)
+
+	fmt.Fprintf(w, "# HELP bot_update_queue_depth Updates waiting per worker.\n")
+	fmt.Fprintf(w, "# TYPE bot_update_queue_depth gauge\n")
+	for i, depth := range stats.QueueDepth {
+		fmt.Fprintf(w, "bot_update_queue_depth{worker=\"%d\"} %d\n", i, depth)
+	}
+
+	fmt.Fprintf(w, "# HELP bot_update_queue_capacity Queue capacity per worker.\n")
+	fmt.Fprintf(w, "# TYPE bot_update_queue_capacity gauge\n")
+	fmt.Fprintf(w, "bot_update_queue_capacity %d\n", stats.QueueCapacity)
+
+	fmt.Fprintf(w, "# HELP bot_updates_processed_total Updates fully processed.\n")
+	fmt.Fprintf(w, "# TYPE bot_updates_processed_total counter\n")
+	fmt.Fprintf(w, "bot_updates_processed_total %d\n", stats.Processed)
+
+	fmt.Fprintf(w, "# HELP bot_update_wait_seconds Time updates spent queued.\n")
+	fmt.Fprintf(w, "# TYPE bot_update_wait_seconds summary\n")
+	fmt.Fprintf(w, "bot_update_wait_seconds_sum %f\n", time.Duration(d.waitNanos.Load()).Seconds())
+	fmt.Fprintf(w, "bot_update_wait_seconds_count %d\n", stats.Processed)
+
+	fmt.Fprintf(w, "# HELP bot_update_handle_seconds Time spent handling updates.\n")
+	fmt.Fprintf(w, "# TYPE bot_update_handle_seconds summary\n")
+	fmt.Fprintf(w, "bot_update_handle_seconds_sum %f\n", time.Duration(d.handleNanos.Load()).Seconds())
+	fmt.Fprintf(w, "bot_update_handle_seconds_count %d\n", stats.Processed)
+
+	fmt.Fprintf(w, "# HELP bot_update_wait_max_seconds Longest queue wait since start.\n")
+	fmt.Fprintf(w, "# TYPE bot_update_wait_max_seconds gauge\n")
+	fmt.Fprintf(w, "bot_update_wait_max_seconds %f\n", stats.MaxWait.Seconds())
+}
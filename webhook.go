@@ -0,0 +1,166 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"sync/atomic"
+
+	"tg-anon-go/config"
+	"tg-anon-go/updates"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// Header yang dikirim Telegram berisi secret_token dari setWebhook
+const webhookSecretHeader = "X-Telegram-Bot-Api-Secret-Token"
+
+// Alasan webhook request ditolak (label metrik)
+const (
+	rejectMethod   = "method"
+	rejectSecret   = "secret"
+	rejectTooLarge = "too_large"
+	rejectBody     = "bad_body"
+)
+
+// webhookHandler menerima update dari Telegram dengan verifikasi secret token dan batas ukuran body
+type webhookHandler struct {
+	secret   string
+	maxBytes int64
+	updates  chan tgbotapi.Update
+
+	accepted atomic.Int64
+	rejected map[string]*atomic.Int64
+}
+
+func newWebhookHandler(secret string, maxBytes int64, buffer int) *webhookHandler {
+	h := &webhookHandler{
+		secret:   secret,
+		maxBytes: maxBytes,
+		updates:  make(chan tgbotapi.Update, buffer),
+		rejected: make(map[string]*atomic.Int64),
+	}
+	for _, reason := range []string{rejectMethod, rejectSecret, rejectTooLarge, rejectBody} {
+		h.rejected[reason] = new(atomic.Int64)
+	}
+	return h
+}
+
+// ServeHTTP memvalidasi request lalu meneruskan update ke channel
+func (h *webhookHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		h.reject(w, r, rejectMethod, http.StatusMethodNotAllowed)
+		return
+	}
+
+	token := r.Header.Get(webhookSecretHeader)
+	if subtle.ConstantTimeCompare([]byte(token), []byte(h.secret)) != 1 {
+		h.reject(w, r, rejectSecret, http.StatusUnauthorized)
+		return
+	}
+
+	var update tgbotapi.Update
+	body := http.MaxBytesReader(w, r.Body, h.maxBytes)
+	if err := json.NewDecoder(body).Decode(&update); err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			h.reject(w, r, rejectTooLarge, http.StatusRequestEntityTooLarge)
+			return
+		}
+		h.reject(w, r, rejectBody, http.StatusBadRequest)
+		return
+	}
+
+	select {
+	case h.updates <- update:
+		h.accepted.Add(1)
+		w.WriteHeader(http.StatusOK)
+	case <-r.Context().Done():
+		// Telegram akan mengirim ulang update ini
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+}
+
+// reject mencatat request yang ditolak
+func (h *webhookHandler) reject(w http.ResponseWriter, r *http.Request, reason string, status int) {
+	h.rejected[reason].Add(1)
+	log.Printf("🚫 Webhook request rejected (%s) from %s", reason, r.RemoteAddr)
+	http.Error(w, http.StatusText(status), status)
+}
+
+// WriteMetrics menulis jumlah request webhook dalam format teks Prometheus
+func (h *webhookHandler) WriteMetrics(w io.Writer) {
+	fmt.Fprintf(w, "# HELP bot_webhook_accepted_total Webhook updates accepted.\n")
+	fmt.Fprintf(w, "# TYPE bot_webhook_accepted_total counter\n")
+	fmt.Fprintf(w, "bot_webhook_accepted_total %d\n", h.accepted.Load())
+
+	fmt.Fprintf(w, "# HELP bot_webhook_rejected_total Webhook requests rejected.\n")
+	fmt.Fprintf(w, "# TYPE bot_webhook_rejected_total counter\n")
+	for _, reason := range []string{rejectMethod, rejectSecret, rejectTooLarge, rejectBody} {
+		fmt.Fprintf(w, "bot_webhook_rejected_total{reason=%q} %d\n", reason, h.rejected[reason].Load())
+	}
+}
+
+// setWebhook mendaftarkan webhook beserta secret_token (belum didukung WebhookConfig tgbotapi v5.5)
+func setWebhook(bot *tgbotapi.BotAPI, webhookURL, secret string) error {
+	params := tgbotapi.Params{}
+	params["url"] = webhookURL
+	params["secret_token"] = secret
+	_, err := bot.MakeRequest("setWebhook", params)
+	return err
+}
+
+// webhookSecret mengembalikan WEBHOOK_SECRET atau turunan dari bot token.
+// Telegram hanya menerima karakter A-Z, a-z, 0-9, _ dan - (maksimal 256).
+func webhookSecret(botToken string) string {
+	if config.C.WebhookSecret != "" {
+		return config.C.WebhookSecret
+	}
+	mac := hmac.New(sha256.New, []byte(botToken))
+	mac.Write([]byte("webhook-secret"))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// webhookPath memastikan path webhook diawali "/"
+func webhookPath() string {
+	path := config.C.WebhookPath
+	if !strings.HasPrefix(path, "/") {
+		path = "/" + path
+	}
+	return path
+}
+
+// newServeMux membuat mux khusus bot untuk health check, metrics, dan (opsional) webhook
+func newServeMux(dispatcher *updates.Dispatcher, webhook *webhookHandler) *http.ServeMux {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("Bot is running!"))
+	})
+	mux.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("OK"))
+	})
+
+	// Expose queue depth & latency di /metrics
+	mux.HandleFunc("/metrics", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+		dispatcher.WriteMetrics(w)
+		if webhook != nil {
+			webhook.WriteMetrics(w)
+		}
+	})
+
+	if webhook != nil {
+		mux.Handle(webhookPath(), webhook)
+	}
+	return mux
+}